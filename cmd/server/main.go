@@ -1,38 +1,267 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/MchlAlex/fc-lab02/config"
+	"github.com/MchlAlex/fc-lab02/internal/infra/rpc"
 	"github.com/MchlAlex/fc-lab02/internal/infra/web"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+	"github.com/MchlAlex/fc-lab02/internal/tlsconfig"
+
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
+// releaseTimeout limita a liberação dos recursos depois que os servidores param.
+const releaseTimeout = 5 * time.Second
+
 func main() {
 	// Carrega a configuração
-	cfg, err := config.LoadConfig(".") // "." indica o diretório atual
+	cfg, loader := loadConfig(os.Args[1:])
+
+	// Cria os serviços, compartilhados pelas APIs HTTP e gRPC
+	deps, err := web.NewDependencies(cfg)
+	if err != nil {
+		fatal(slog.Default(), "could not set up server", err)
+	}
+	logger := deps.Logger
+	slog.SetDefault(logger) // Logs de bibliotecas que usam o pacote log também saem no formato configurado
+
+	// SIGTERM (deploys no Cloud Run e no Kubernetes) e SIGINT iniciam o desligamento
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// Configura o servidor web; os streams terminam assim que o desligamento começa
+	router := web.NewRouter(cfg, deps)
+	srv := web.NewHTTPServer(cfg, router, logger)
+	srv.RegisterOnShutdown(deps.Poller.Close)
+
+	// HTTPS e TLS no gRPC, com o certificado relido quando os arquivos mudam
+	var grpcOpts []grpc.ServerOption
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" || cfg.TLSClientCAFile != "" {
+		certs, err := tlsconfig.New(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile)
+		if err != nil {
+			fatal(logger, "could not set up TLS", err)
+		}
+		certs.Logger = logger
+		go certs.Watch(ctx, cfg.TLSReloadInterval)
+		srv.TLSConfig = certs.ServerConfig()
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(certs.ServerConfig())))
+		logger.Info("TLS enabled", "cert_file", cfg.TLSCertFile, "mtls", cfg.TLSClientCAFile != "", "not_after", certs.Certificate().Leaf.NotAfter)
+	}
+	ln, err := net.Listen("tcp", srv.Addr)
 	if err != nil {
-		log.Fatalf("Could not load config: %v", err)
+		fatal(logger, "could not listen on "+srv.Addr, err)
 	}
 
-	// Configura o servidor web
-	router := web.SetupServer(cfg)
+	// Inicia o servidor gRPC ao lado do HTTP
+	grpcAddr := fmt.Sprintf(":%s", cfg.GRPCPort)
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		fatal(logger, "could not listen on "+grpcAddr, err)
+	}
+	weatherServer := rpc.NewWeatherServer(deps.LocationService, deps.WeatherService, deps.Converter, cfg.BatchConcurrency, cfg.BatchMaxSize, cfg.BatchTimeout)
+	weatherServer.Logger = logger
+	weatherServer.Authenticator = deps.Authenticator // Mesma chave de API e mesmo limite das rotas HTTP
+	weatherServer.RateLimiter = deps.RateLimiter
+	weatherServer.ClientCert = cfg.TLSClientCAFile != ""
+	grpcServer := rpc.NewServer(weatherServer, grpcOpts...)
+	go func() {
+		logger.Info("starting gRPC server", "port", cfg.GRPCPort)
+		if err := grpcServer.Serve(lis); err != nil {
+			fatal(logger, "gRPC server failed", err)
+		}
+	}()
 
-	// Define a porta do servidor
-	port := cfg.WebServerPort
-	if port == "" {
-		port = "8080" // Porta padrão se não definida
+	// Listener de administração, separado da porta pública
+	adminDone := make(chan struct{})
+	if cfg.AdminAddr != "" {
+		adminSrv := web.NewAdminServer(cfg, web.NewAdminRouter(cfg, deps), logger)
+		adminLn, err := net.Listen("tcp", adminSrv.Addr)
+		if err != nil {
+			fatal(logger, "could not listen on "+adminSrv.Addr, err)
+		}
+		go func() {
+			defer close(adminDone)
+			logger.Info("starting admin server", "addr", adminSrv.Addr)
+			if err := web.Serve(ctx, adminSrv, adminLn, cfg.ShutdownTimeout); err != nil {
+				logger.Error("admin server failed", "error", err)
+			}
+		}()
+	} else {
+		close(adminDone)
 	}
-	addr := fmt.Sprintf(":%s", port)
 
-	log.Printf("Starting server on port %s", port)
+	// Verifica as assinaturas (webhooks) em segundo plano até o desligamento
+	webhookTimeout := service.NewTimeoutTransport(service.NewWebhookTransport(), cfg.WebhookTimeout)
+	webhookClient := &http.Client{Transport: webhookTimeout, CheckRedirect: service.RefuseRedirects}
+	notifier := service.NewWebhookNotifier(webhookClient, deps.Subscriptions, cfg.WebhookMaxAttempts, cfg.WebhookBackoff)
+	notifier.Logger = logger
+	lookup := service.NewBatchLookup(deps.LocationService, deps.WeatherService, cfg.BatchConcurrency)
+	scheduler := service.NewSubscriptionScheduler(deps.Subscriptions, lookup, deps.Converter, notifier, cfg.SubscriptionCheckInterval)
+	scheduler.Concurrency = cfg.WebhookConcurrency
+	scheduler.Logger = logger
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		scheduler.Run(ctx)
+	}()
+
+	// Recarga da configuração (SIGHUP ou mudança nos arquivos), sem reiniciar
+	// deps.Reload é o único passo que pode falhar e não aplica nada nesse caso,
+	// então o prazo dos webhooks só muda depois dele: a recarga é tudo ou nada
+	go watchConfig(ctx, loader, cfg, logger, func(cfg *config.Config) error {
+		if err := deps.Reload(cfg); err != nil {
+			return err
+		}
+		webhookTimeout.SetTimeout(cfg.WebhookTimeout)
+		return nil
+	})
 
-	// Inicia o servidor HTTP
-	err = http.ListenAndServe(addr, router)
-	if err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Could not listen on %s: %v\n", addr, err)
+	// Depois do sinal, HTTP, gRPC, administração e scheduler terminam em paralelo dentro de ShutdownTimeout
+	drainCtx, cancelDrain := context.WithCancel(context.Background())
+	defer cancelDrain()
+	context.AfterFunc(ctx, func() {
+		logger.Info("shutting down", "timeout", cfg.ShutdownTimeout.String())
+		time.AfterFunc(cfg.ShutdownTimeout, cancelDrain)
+	})
+	grpcStopped := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+
+	// Atende até o sinal e então drena as requisições em andamento
+	logger.Info("starting HTTP server", "addr", srv.Addr, "tls", srv.TLSConfig != nil)
+	if err := web.Serve(ctx, srv, ln, cfg.ShutdownTimeout); err != nil {
+		if ctx.Err() == nil {
+			fatal(logger, "HTTP server failed", err)
+		}
+		logger.Error("HTTP server did not drain in time", "error", err)
+	}
+	stop() // Um segundo sinal volta a encerrar o processo na hora
+
+	select {
+	case <-grpcStopped:
+	case <-drainCtx.Done():
+		logger.Error("gRPC server did not drain in time")
+		grpcServer.Stop()
+	}
+	select {
+	case <-schedulerDone:
+	case <-drainCtx.Done():
+		logger.Error("subscription scheduler did not stop in time")
 	}
+	<-adminDone // Serve fecha as conexões que passam do prazo
+	webhookClient.CloseIdleConnections()
+
+	// Envia os spans pendentes e libera o banco e as conexões com os provedores
+	releaseCtx, cancelRelease := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancelRelease()
+	if err := deps.Close(releaseCtx); err != nil {
+		logger.Error("could not release resources", "error", err)
+	}
+	logger.Info("server stopped")
+}
+
+// loadConfig lê a configuração das flags, do ambiente e dos arquivos e retorna
+// também o Loader, usado nas recargas. Com --print-config, imprime a
+// configuração efetiva, sem os segredos, e encerra. Com problemas de
+// validação, registra todos e encerra com código 1.
+func loadConfig(args []string) (*config.Config, *config.Loader) {
+	loader := config.NewLoader(".") // O .env é lido do diretório atual
+	flags := pflag.NewFlagSet("server", pflag.ExitOnError)
+	loader.RegisterFlags(flags)
+	printConfig := flags.Bool("print-config", false, "print the effective configuration, with secrets redacted, and exit")
+	flags.Parse(args)
+
+	cfg, err := loader.Load()
+	var invalid *config.ValidationError
+	if err != nil && !errors.As(err, &invalid) {
+		fatal(slog.Default(), "could not load config", err)
+	}
+	if *printConfig {
+		out, _ := json.MarshalIndent(cfg.Redacted(), "", "  ")
+		fmt.Println(string(out))
+	}
+	if invalid != nil {
+		// Um problema por linha, para corrigir todos de uma vez
+		for _, p := range invalid.Problems {
+			slog.Error("invalid configuration", "key", p.Key, "error", p.Message)
+		}
+		os.Exit(1)
+	}
+	if *printConfig {
+		os.Exit(0)
+	}
+	return cfg, loader
+}
+
+// watchConfig relê a configuração a cada SIGHUP e quando o arquivo de
+// configuração ou o .env mudam, até ctx ser cancelado. Os campos recarregáveis
+// que mudaram vão para apply; os demais mantêm o valor em uso, com um aviso.
+// Uma leitura inválida é descartada por inteiro.
+func watchConfig(ctx context.Context, loader *config.Loader, cfg *config.Config, logger *slog.Logger, apply func(*config.Config) error) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	changes := loader.Watch(ctx, cfg.ConfigReloadInterval)
+
+	for {
+		var trigger string
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			trigger = "sighup"
+		case <-changes:
+			trigger = "file"
+		}
+
+		next, err := loader.Load()
+		var invalid *config.ValidationError
+		if errors.As(err, &invalid) {
+			for _, p := range invalid.Problems {
+				logger.Error("invalid configuration, keeping the current one", "key", p.Key, "error", p.Message)
+			}
+			continue
+		}
+		if err != nil {
+			logger.Error("could not reload config, keeping the current one", "error", err)
+			continue
+		}
+		merged, changed, restart := cfg.Reload(next)
+		for _, key := range restart {
+			logger.Warn("config change requires a restart, keeping the current value", "key", key)
+		}
+		if len(changed) == 0 {
+			logger.Info("config reloaded, nothing to apply", "trigger", trigger)
+			continue
+		}
+		if err := apply(merged); err != nil {
+			logger.Error("could not apply config, keeping the current one", "error", err)
+			continue
+		}
+		cfg = merged
+		logger.Info("config reloaded", "trigger", trigger, "changed", changed)
+	}
+}
 
-	log.Println("Server stopped")
+// fatal registra o erro e encerra o processo, como log.Fatalf.
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -1,24 +1,327 @@
 package config
+
 import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
+
 type Config struct {
-	WeatherAPIKey string `mapstructure:"WEATHER_API_KEY"`
+	WeatherAPIKey string `mapstructure:"WEATHER_API_KEY" reload:"true" secret:"true"`
 	WebServerPort string `mapstructure:"WEB_SERVER_PORT"`
+	GRPCPort      string `mapstructure:"GRPC_PORT"` // Porta do servidor gRPC (WeatherService)
+
+	// Limites do servidor HTTP: leitura dos cabeçalhos, da requisição inteira, escrita da resposta, conexões
+	// ociosas e tamanho máximo dos cabeçalhos. Streams (SSE e WebSocket) não ficam sujeitos ao limite de escrita
+	HTTPReadHeaderTimeout time.Duration `mapstructure:"HTTP_READ_HEADER_TIMEOUT"`
+	HTTPReadTimeout       time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout      time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout       time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	HTTPMaxHeaderBytes    int           `mapstructure:"HTTP_MAX_HEADER_BYTES"`
+
+	// HTTPS (e TLS no gRPC) a partir de certificado e chave em PEM, relidos quando os arquivos mudam. Com
+	// TLS_CLIENT_CA_FILE, só clientes com certificado assinado por essas CAs conectam (mTLS)
+	TLSCertFile       string        `mapstructure:"TLS_CERT_FILE"` // Vazio (com TLS_KEY_FILE) serve HTTP sem TLS
+	TLSKeyFile        string        `mapstructure:"TLS_KEY_FILE"`
+	TLSClientCAFile   string        `mapstructure:"TLS_CLIENT_CA_FILE"`
+	TLSReloadInterval time.Duration `mapstructure:"TLS_RELOAD_INTERVAL"`
+
+	// Listener de administração (pprof, configuração, caches, circuit breakers e versão). Vazio o desliga;
+	// o padrão só aceita conexões locais, e as rotas não existem na porta pública. Fora do loopback,
+	// exige ADMIN_TOKEN, que passa a proteger todas as rotas do listener
+	AdminAddr string `mapstructure:"ADMIN_ADDR"`
+
+	// Prazo para terminar as requisições em andamento depois de um SIGTERM ou SIGINT
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+
+	// Intervalo entre as verificações do arquivo de configuração e do .env, relidos quando mudam (0 deixa só o
+	// SIGHUP). Só os campos com a tag reload:"true" mudam sem reiniciar
+	ConfigReloadInterval time.Duration `mapstructure:"CONFIG_RELOAD_INTERVAL"`
+
+	// Logs (log/slog): formato "json" ou "text" e nível mínimo ("debug", "info", "warn" ou "error")
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+	LogLevel  string `mapstructure:"LOG_LEVEL" reload:"true"`
+
+	// Traces (OpenTelemetry): URL do coletor OTLP/HTTP, vazia desliga a exportação, e nome do serviço nos spans
+	OTLPEndpoint string `mapstructure:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName  string `mapstructure:"OTEL_SERVICE_NAME"`
+
+	// Proxies (IPs ou faixas CIDR, separados por vírgula) cujo X-Forwarded-For é aceito para descobrir o IP do cliente
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	// Limite de requisições por cliente: tiers "nome=limite/janela[:rajada]". O tier "anonymous" vale para
+	// quem não envia uma chave de API (identificado pelo IP) e o "authenticated" para as chaves sem cota própria
+	RateLimitTiers []string `mapstructure:"RATE_LIMIT_TIERS" reload:"true"`
+
+	// Chaves de API: exigência nas rotas da API (desligada por padrão), armazenamento (file ou sqlite) e token das rotas /admin/keys
+	AuthRequired bool   `mapstructure:"AUTH_REQUIRED"`
+	APIKeysStore string `mapstructure:"API_KEYS_STORE"`
+	APIKeysPath  string `mapstructure:"API_KEYS_PATH"`             // Vazio (com o store file) mantém as chaves só em memória
+	AdminToken   string `mapstructure:"ADMIN_TOKEN" secret:"true"` // Vazio desliga as rotas de administração
+
+	// Intervalo de atualização das observações da WeatherAPI, teto do Cache-Control: max-age (0 desliga o cache)
+	CacheMaxAge time.Duration `mapstructure:"CACHE_MAX_AGE"`
+
+	// Tempo máximo de cada chamada à ViaCEP e à WeatherAPI
+	UpstreamTimeout time.Duration `mapstructure:"UPSTREAM_TIMEOUT" reload:"true"`
+
+	// Circuit breaker dos provedores: falhas seguidas até abrir e tempo aberto até a chamada de teste
+	BreakerFailureThreshold int           `mapstructure:"BREAKER_FAILURE_THRESHOLD"`
+	BreakerCooldown         time.Duration `mapstructure:"BREAKER_COOLDOWN"`
+
+	// Validade dos resultados das verificações de /readyz
+	ReadinessCacheTTL time.Duration `mapstructure:"READINESS_CACHE_TTL"`
+
+	// Tempo de vida das respostas da ViaCEP e da WeatherAPI guardadas em memória (0, o padrão, desliga o cache)
+	LocationCacheTTL time.Duration `mapstructure:"LOCATION_CACHE_TTL" reload:"true"`
+	WeatherCacheTTL  time.Duration `mapstructure:"WEATHER_CACHE_TTL" reload:"true"`
+
+	// Política de arredondamento das temperaturas (casas decimais por unidade e modo de desempate)
+	TempPrecisionC   int    `mapstructure:"TEMP_PRECISION_C"`
+	TempPrecisionF   int    `mapstructure:"TEMP_PRECISION_F"`
+	TempPrecisionK   int    `mapstructure:"TEMP_PRECISION_K"`
+	TempRoundingMode string `mapstructure:"TEMP_ROUNDING_MODE"`
+
+	// Limites da consulta em lote (POST /weather/batch)
+	BatchMaxSize     int           `mapstructure:"BATCH_MAX_SIZE"`
+	BatchConcurrency int           `mapstructure:"BATCH_CONCURRENCY"`
+	BatchTimeout     time.Duration `mapstructure:"BATCH_TIMEOUT"`
+
+	// Streaming (SSE e WebSocket): intervalo de consulta por cidade, heartbeat e limites
+	StreamPollInterval   time.Duration `mapstructure:"STREAM_POLL_INTERVAL"`
+	StreamHeartbeat      time.Duration `mapstructure:"STREAM_HEARTBEAT"`
+	StreamMaxSubscribers int           `mapstructure:"STREAM_MAX_SUBSCRIBERS"`
+	StreamAllowedOrigins []string      `mapstructure:"STREAM_ALLOWED_ORIGINS"` // Origens extras aceitas no WebSocket, separadas por vírgula
+
+	// Assinaturas (webhooks): arquivo de persistência, intervalo das verificações e entrega dos avisos
+	SubscriptionsFile         string        `mapstructure:"SUBSCRIPTIONS_FILE"` // Vazio mantém as assinaturas só em memória
+	SubscriptionCheckInterval time.Duration `mapstructure:"SUBSCRIPTION_CHECK_INTERVAL"`
+	WebhookMaxAttempts        int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookBackoff            time.Duration `mapstructure:"WEBHOOK_BACKOFF"`
+	WebhookTimeout            time.Duration `mapstructure:"WEBHOOK_TIMEOUT" reload:"true"`
+	WebhookConcurrency        int           `mapstructure:"WEBHOOK_CONCURRENCY"` // Entregas simultâneas por verificação
 }
+
+// redactedValue substitui os valores dos campos com a tag secret:"true".
+const redactedValue = "[REDACTED]"
+
+// Redacted retorna a configuração por nome de variável, para inspeção. Os
+// segredos preenchidos aparecem como [REDACTED] e as durações como texto (ex: 30s).
+func (c *Config) Redacted() map[string]any {
+	out := map[string]any{}
+	v := reflect.ValueOf(c).Elem()
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		value := v.Field(i).Interface()
+		switch {
+		case field.Tag.Get("secret") == "true" && !v.Field(i).IsZero():
+			value = redactedValue
+		case field.Type == reflect.TypeFor[time.Duration]():
+			value = value.(time.Duration).String()
+		}
+		out[field.Tag.Get("mapstructure")] = value
+	}
+	return out
+}
+
+// defaults são os valores das variáveis não definidas no ambiente nem no .env.
+var defaults = map[string]any{
+	"WEB_SERVER_PORT":             "8080",
+	"GRPC_PORT":                   "50051",
+	"HTTP_READ_HEADER_TIMEOUT":    "5s",
+	"HTTP_READ_TIMEOUT":           "15s",
+	"HTTP_WRITE_TIMEOUT":          "30s",
+	"HTTP_IDLE_TIMEOUT":           "2m",
+	"HTTP_MAX_HEADER_BYTES":       64 << 10,
+	"ADMIN_ADDR":                  "127.0.0.1:9090",
+	"SHUTDOWN_TIMEOUT":            "10s",
+	"CONFIG_RELOAD_INTERVAL":      "10s",
+	"TLS_RELOAD_INTERVAL":         "10s",
+	"LOG_FORMAT":                  "json",
+	"LOG_LEVEL":                   "info",
+	"OTEL_SERVICE_NAME":           "weather-api",
+	"UPSTREAM_TIMEOUT":            "5s",
+	"CACHE_MAX_AGE":               "15m",
+	"BREAKER_FAILURE_THRESHOLD":   5,
+	"BREAKER_COOLDOWN":            "30s",
+	"READINESS_CACHE_TTL":         "10s",
+	"LOCATION_CACHE_TTL":          "0s",
+	"WEATHER_CACHE_TTL":           "0s",
+	"RATE_LIMIT_TIERS":            "anonymous=60/1m:20,authenticated=600/1m:60",
+	"AUTH_REQUIRED":               false,
+	"API_KEYS_STORE":              "file",
+	"API_KEYS_PATH":               "data/api_keys.json",
+	"TEMP_PRECISION_C":            1,
+	"TEMP_PRECISION_F":            1,
+	"TEMP_PRECISION_K":            2,
+	"TEMP_ROUNDING_MODE":          "half-even",
+	"BATCH_MAX_SIZE":              100,
+	"BATCH_CONCURRENCY":           8,
+	"BATCH_TIMEOUT":               "10s",
+	"STREAM_POLL_INTERVAL":        "30s",
+	"STREAM_HEARTBEAT":            "15s",
+	"STREAM_MAX_SUBSCRIBERS":      1000,
+	"SUBSCRIPTIONS_FILE":          "data/subscriptions.json",
+	"SUBSCRIPTION_CHECK_INTERVAL": "5m",
+	"WEBHOOK_MAX_ATTEMPTS":        5,
+	"WEBHOOK_BACKOFF":             "1s",
+	"WEBHOOK_TIMEOUT":             "10s",
+	"WEBHOOK_CONCURRENCY":         10,
+}
+
+// aliases são os nomes antigos aceitos para uma variável, em ordem de
+// preferência. Valem só quando o nome atual não está definido; PORT é a
+// variável injetada pelo Cloud Run.
+var aliases = map[string][]string{
+	"WEB_SERVER_PORT": {"WEBSERVER_PORT", "PORT"},
+}
+
+// LoadConfig lê a configuração das variáveis de ambiente e do arquivo .env em
+// path, sem flags. Veja Loader.
 func LoadConfig(path string) (*Config, error) {
-	var cfg Config
-	viper.SetConfigName(".env") 
-	viper.SetConfigType("env")  
-	viper.AddConfigPath(path)   
-	viper.AutomaticEnv()        
-	err := viper.ReadInConfig()
-	if _, ok := err.(viper.ConfigFileNotFoundError); !ok && err != nil {
-		return nil, err
+	return NewLoader(path).Load()
+}
+
+// Loader monta a configuração em camadas. Da menor para a maior precedência:
+// padrões, arquivo de configuração (YAML, TOML ou JSON), arquivo .env,
+// variáveis de ambiente e flags. Cada Loader usa a própria instância do
+// viper, então duas configurações podem ser lidas no mesmo processo.
+type Loader struct {
+	Dir  string // Diretório do .env; vazio usa o diretório atual
+	File string // Arquivo de configuração, com o formato pela extensão; vazio usa CONFIG_FILE
+
+	flags *pflag.FlagSet
+}
+
+// NewLoader cria um Loader que lê o .env em dir.
+func NewLoader(dir string) *Loader {
+	return &Loader{Dir: dir}
+}
+
+// RegisterFlags adiciona ao conjunto a flag --config e uma flag por variável,
+// com o nome em minúsculas e hífens (ex: --web-server-port para WEB_SERVER_PORT).
+// Só as flags informadas na linha de comando sobrepõem as outras camadas.
+func (l *Loader) RegisterFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&l.File, "config", "", "config file (YAML, TOML or JSON); overrides CONFIG_FILE")
+	t := reflect.TypeFor[Config]()
+	for i := range t.NumField() {
+		key := t.Field(i).Tag.Get("mapstructure")
+		value := ""
+		if d, ok := defaults[key]; ok {
+			value = fmt.Sprint(d)
+		}
+		flags.String(FlagName(key), value, "sets "+key)
+		if t.Field(i).Type.Kind() == reflect.Bool {
+			flags.Lookup(FlagName(key)).NoOptDefVal = "true" // --auth-required equivale a --auth-required=true
+		}
 	}
-	err = viper.Unmarshal(&cfg)
-	if err != nil {
+	l.flags = flags
+}
+
+// FlagName retorna o nome da flag de uma variável (ex: web-server-port).
+func FlagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+// Load lê as camadas e valida o resultado. Problemas de conversão e de
+// validação voltam todos juntos em um *ValidationError; nesse caso, a
+// configuração lida também volta, para inspeção (ex: --print-config).
+func (l *Loader) Load() (*Config, error) {
+	v := viper.New()
+	v.AutomaticEnv()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+	// Sem o BindEnv, o AutomaticEnv só enxerga as chaves com padrão ou presentes nos arquivos
+	for _, key := range keys() {
+		v.BindEnv(key)
+		if l.flags != nil {
+			v.BindPFlag(key, l.flags.Lookup(FlagName(key)))
+		}
+	}
+
+	if file := l.configFile(); file != "" {
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("could not read config file %s: %w", file, err)
+		}
+	}
+	if err := mergeEnvFile(v, filepath.Join(l.Dir, ".env")); err != nil {
 		return nil, err
 	}
+	applyAliases(v, l.isSet)
+
+	var cfg Config
+	var problems []Problem
+	if err := v.Unmarshal(&cfg); err != nil {
+		problems = decodeProblems(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		problems = mergeProblems(problems, err.(*ValidationError).Problems)
+	}
+	if len(problems) > 0 {
+		return &cfg, &ValidationError{Problems: problems}
+	}
 	return &cfg, nil
 }
+
+// mergeEnvFile junta o .env à configuração, por cima do arquivo de
+// configuração. A falta do .env não é um erro.
+func mergeEnvFile(v *viper.Viper, path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", path, err)
+	}
+	defer f.Close()
+	v.SetConfigType("env")
+	if err := v.MergeConfig(f); err != nil {
+		return fmt.Errorf("could not read %s: %w", path, err)
+	}
+	return nil
+}
+
+// isSet informa se a variável foi definida no ambiente ou por flag.
+func (l *Loader) isSet(key string) bool {
+	return os.Getenv(key) != "" || (l.flags != nil && l.flags.Changed(FlagName(key)))
+}
+
+// keys lista os nomes das variáveis, na ordem dos campos de Config.
+func keys() []string {
+	t := reflect.TypeFor[Config]()
+	out := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		out = append(out, t.Field(i).Tag.Get("mapstructure"))
+	}
+	return out
+}
+
+// applyAliases copia para o nome atual o valor de um nome antigo, do ambiente
+// ou dos arquivos, quando o nome atual não está definido em nenhuma camada.
+func applyAliases(v *viper.Viper, isSet func(key string) bool) {
+	for key, names := range aliases {
+		if isSet(key) || v.InConfig(key) {
+			continue
+		}
+		for _, name := range names {
+			if value := os.Getenv(name); value != "" {
+				v.Set(key, value)
+				break
+			}
+			if v.InConfig(name) {
+				v.Set(key, v.Get(name))
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeEnvFile cria um .env com o conteúdo informado e retorna o diretório.
+func writeEnvFile(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte(content), 0o600))
+	return dir
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := LoadConfig(writeEnvFile(t, "WEATHER_API_KEY=abc\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "8080", cfg.WebServerPort)
+		assert.Equal(t, "50051", cfg.GRPCPort)
+		assert.Equal(t, 5*time.Second, cfg.UpstreamTimeout)
+		assert.Equal(t, []string{"anonymous=60/1m:20", "authenticated=600/1m:60"}, cfg.RateLimitTiers)
+		assert.False(t, cfg.AuthRequired, "API keys are opt-in")
+		assert.Zero(t, cfg.LocationCacheTTL, "provider caches are opt-in")
+		assert.Zero(t, cfg.WeatherCacheTTL)
+	})
+
+	t.Run("Environment Overrides The File", func(t *testing.T) {
+		t.Setenv("WEB_SERVER_PORT", "9000")
+		t.Setenv("ADMIN_TOKEN", "secret") // Sem padrão e fora do .env
+		t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,192.168.0.1")
+		cfg, err := LoadConfig(writeEnvFile(t, "WEATHER_API_KEY=abc\nWEB_SERVER_PORT=8081\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "9000", cfg.WebServerPort)
+		assert.Equal(t, "secret", cfg.AdminToken)
+		assert.Equal(t, []string{"10.0.0.0/8", "192.168.0.1"}, cfg.TrustedProxies)
+	})
+
+	t.Run("Legacy Port Names", func(t *testing.T) {
+		cfg, err := LoadConfig(writeEnvFile(t, "WEATHER_API_KEY=abc\nWEBSERVER_PORT=8081\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "8081", cfg.WebServerPort)
+
+		t.Setenv("PORT", "9000")
+		cfg, err = LoadConfig(writeEnvFile(t, "WEATHER_API_KEY=abc\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "9000", cfg.WebServerPort)
+
+		cfg, err = LoadConfig(writeEnvFile(t, "WEATHER_API_KEY=abc\nWEB_SERVER_PORT=8082\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "8082", cfg.WebServerPort, "the current name wins")
+	})
+
+	t.Run("All Problems At Once", func(t *testing.T) {
+		t.Setenv("GRPC_PORT", "70000")
+		t.Setenv("UPSTREAM_TIMEOUT", "5")
+		t.Setenv("BATCH_MAX_SIZE", "many")
+		t.Setenv("BREAKER_COOLDOWN", "-1s")
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "otel-collector:4318")
+		t.Setenv("TLS_CERT_FILE", "tls.crt")
+		t.Setenv("LOG_FORMAT", "xml")
+		_, err := LoadConfig(writeEnvFile(t, ""))
+
+		var verr *ValidationError
+		require.ErrorAs(t, err, &verr)
+		assert.Equal(t, []Problem{
+			{Key: "WEATHER_API_KEY", Message: "is required"},
+			{Key: "GRPC_PORT", Message: `must be a port between 1 and 65535, got "70000"`},
+			{Key: "TLS_KEY_FILE", Message: "must be set together with TLS_CERT_FILE"},
+			{Key: "LOG_FORMAT", Message: `must be one of json, text, got "xml"`},
+			{Key: "OTEL_EXPORTER_OTLP_ENDPOINT", Message: `must be an http or https URL (e.g. http://otel-collector:4318), got "otel-collector:4318"`},
+			{Key: "UPSTREAM_TIMEOUT", Message: `invalid value: time: missing unit in duration "5"`},
+			{Key: "BREAKER_COOLDOWN", Message: "must be a positive duration (e.g. 10s), got -1s"},
+			{Key: "BATCH_MAX_SIZE", Message: `cannot parse value as int: strconv.ParseInt: parsing "many": invalid syntax`},
+		}, verr.Problems)
+		assert.Contains(t, err.Error(), "invalid configuration: WEATHER_API_KEY: is required; GRPC_PORT:")
+	})
+
+	t.Run("Admin Listener Beyond Loopback Requires A Token", func(t *testing.T) {
+		for _, addr := range []string{"127.0.0.1:9090", "[::1]:9090", "localhost:9090"} {
+			_, err := LoadConfig(writeEnvFile(t, "WEATHER_API_KEY=abc\nADMIN_ADDR="+addr+"\n"))
+			assert.NoError(t, err, addr)
+		}
+
+		_, err := LoadConfig(writeEnvFile(t, "WEATHER_API_KEY=abc\nADMIN_ADDR=:9090\n"))
+		var verr *ValidationError
+		require.ErrorAs(t, err, &verr)
+		assert.Equal(t, []Problem{{Key: "ADMIN_ADDR", Message: `listening beyond loopback requires ADMIN_TOKEN, got ":9090"`}}, verr.Problems)
+
+		_, err = LoadConfig(writeEnvFile(t, "WEATHER_API_KEY=abc\nADMIN_ADDR=0.0.0.0:9090\nADMIN_TOKEN=s3cret\n"))
+		assert.NoError(t, err)
+	})
+}
+
+func TestLoader(t *testing.T) {
+	writeFile := func(t *testing.T, name, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	t.Run("Precedence", func(t *testing.T) {
+		file := writeFile(t, "config.yaml", "weather_api_key: abc\ngrpc_port: 6000\nlog_level: debug\nupstream_timeout: 1s\nbatch_max_size: 10\n")
+		l := NewLoader(writeEnvFile(t, "LOG_LEVEL=warn\nUPSTREAM_TIMEOUT=2s\nBATCH_MAX_SIZE=20\n"))
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		l.RegisterFlags(flags)
+		t.Setenv("UPSTREAM_TIMEOUT", "3s")
+		t.Setenv("BATCH_MAX_SIZE", "30")
+		require.NoError(t, flags.Parse([]string{"--config", file, "--batch-max-size", "40", "--auth-required"}))
+
+		cfg, err := l.Load()
+		require.NoError(t, err)
+		assert.Equal(t, "6000", cfg.GRPCPort, "config file over defaults")
+		assert.Equal(t, "warn", cfg.LogLevel, ".env over the config file")
+		assert.Equal(t, 3*time.Second, cfg.UpstreamTimeout, "environment over .env")
+		assert.Equal(t, 40, cfg.BatchMaxSize, "flags over everything")
+		assert.True(t, cfg.AuthRequired)
+		assert.Equal(t, "8080", cfg.WebServerPort, "unset flags keep the lower layers")
+	})
+
+	t.Run("TOML And JSON Files", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, content := range map[string]string{
+			"config.toml": "WEATHER_API_KEY = \"abc\"\nTRUSTED_PROXIES = [\"10.0.0.0/8\"]\n",
+			"config.json": `{"WEATHER_API_KEY": "abc", "TRUSTED_PROXIES": ["10.0.0.0/8"]}`,
+		} {
+			cfg, err := (&Loader{Dir: dir, File: writeFile(t, name, content)}).Load()
+			require.NoError(t, err, name)
+			assert.Equal(t, []string{"10.0.0.0/8"}, cfg.TrustedProxies, name)
+		}
+
+		t.Setenv("CONFIG_FILE", writeFile(t, "config.yml", "weather_api_key: abc\ngrpc_port: 6000\n"))
+		cfg, err := NewLoader(dir).Load()
+		require.NoError(t, err)
+		assert.Equal(t, "6000", cfg.GRPCPort)
+
+		_, err = (&Loader{Dir: dir, File: filepath.Join(dir, "missing.yaml")}).Load()
+		assert.ErrorContains(t, err, "could not read config file")
+	})
+
+	t.Run("Loaders Are Isolated", func(t *testing.T) {
+		a, err := LoadConfig(writeEnvFile(t, "WEATHER_API_KEY=abc\nADMIN_ADDR=127.0.0.1:9191\n"))
+		require.NoError(t, err)
+		b, err := LoadConfig(writeEnvFile(t, "WEATHER_API_KEY=abc\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:9191", a.AdminAddr)
+		assert.Equal(t, "127.0.0.1:9090", b.AdminAddr)
+	})
+
+	t.Run("Invalid Config Is Returned For Inspection", func(t *testing.T) {
+		cfg, err := LoadConfig(writeEnvFile(t, "GRPC_PORT=6000\n"))
+		var verr *ValidationError
+		require.ErrorAs(t, err, &verr)
+		require.NotNil(t, cfg)
+		assert.Equal(t, "6000", cfg.GRPCPort)
+	})
+}
+
+func TestConfig_Reload(t *testing.T) {
+	cfg, err := LoadConfig(writeEnvFile(t, "WEATHER_API_KEY=abc\n"))
+	require.NoError(t, err)
+
+	next := *cfg
+	next.WeatherAPIKey = "rotated"
+	next.UpstreamTimeout = 2 * time.Second
+	next.GRPCPort = "6000"
+	next.LocationCacheTTL = time.Hour // Ligar o cache exige reinício
+	merged, changed, restart := cfg.Reload(&next)
+
+	assert.Equal(t, []string{"WEATHER_API_KEY", "UPSTREAM_TIMEOUT"}, changed)
+	assert.Equal(t, []string{"GRPC_PORT", "LOCATION_CACHE_TTL"}, restart)
+	assert.Equal(t, "rotated", merged.WeatherAPIKey)
+	assert.Equal(t, 2*time.Second, merged.UpstreamTimeout)
+	assert.Equal(t, "50051", merged.GRPCPort, "settings that need a restart keep the current value")
+	assert.Zero(t, merged.LocationCacheTTL)
+	assert.Equal(t, "abc", cfg.WeatherAPIKey, "the current config is not modified")
+
+	_, changed, restart = cfg.Reload(cfg)
+	assert.Empty(t, changed)
+	assert.Empty(t, restart)
+}
+
+func TestLoader_Watch(t *testing.T) {
+	dir := writeEnvFile(t, "WEATHER_API_KEY=abc\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := NewLoader(dir).Watch(ctx, 10*time.Millisecond)
+
+	select {
+	case <-changes:
+		t.Fatal("no change yet")
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("WEATHER_API_KEY=rotated\n"), 0o600))
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not detected")
+	}
+}
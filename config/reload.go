@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// restartOnly lista as mudanças de campos recarregáveis que ainda assim exigem
+// reinício: ligar ou desligar um cache ou o limite por cliente muda as camadas
+// montadas na inicialização.
+var restartOnly = map[string]func(old, next *Config) bool{
+	"LOCATION_CACHE_TTL": func(old, next *Config) bool {
+		return (old.LocationCacheTTL > 0) != (next.LocationCacheTTL > 0)
+	},
+	"WEATHER_CACHE_TTL": func(old, next *Config) bool {
+		return (old.WeatherCacheTTL > 0) != (next.WeatherCacheTTL > 0)
+	},
+	"RATE_LIMIT_TIERS": func(old, next *Config) bool {
+		return hasTiers(old.RateLimitTiers) != hasTiers(next.RateLimitTiers)
+	},
+}
+
+func hasTiers(specs []string) bool {
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload compara a configuração em uso com uma nova leitura e retorna a que
+// passa a valer: os campos recarregáveis (tag reload:"true") vêm de next e os
+// demais continuam os de c. changed lista as variáveis recarregáveis que
+// mudaram; restart, as que mudaram mas só valem depois de reiniciar.
+func (c *Config) Reload(next *Config) (merged *Config, changed, restart []string) {
+	out := *c
+	cur, nv, mv := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem(), reflect.ValueOf(&out).Elem()
+	for i := range cur.NumField() {
+		if reflect.DeepEqual(cur.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		field := cur.Type().Field(i)
+		key := field.Tag.Get("mapstructure")
+		if field.Tag.Get("reload") != "true" || (restartOnly[key] != nil && restartOnly[key](c, next)) {
+			restart = append(restart, key)
+			continue
+		}
+		mv.Field(i).Set(nv.Field(i))
+		changed = append(changed, key)
+	}
+	return &out, changed, restart
+}
+
+// fileStamp identifica uma versão de um arquivo; o zero indica que ele não existe.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch verifica o arquivo de configuração e o .env a cada interval, até ctx
+// ser cancelado, e avisa no canal retornado quando algum muda, é criado ou é
+// removido. Avisos seguidos antes de uma leitura do canal viram um só. Com
+// interval <= 0, o canal nunca recebe.
+func (l *Loader) Watch(ctx context.Context, interval time.Duration) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	if interval <= 0 {
+		return changes
+	}
+	files := l.files()
+	stamps := stat(files)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			current := stat(files)
+			if reflect.DeepEqual(current, stamps) {
+				continue
+			}
+			stamps = current
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changes
+}
+
+// files lista os arquivos lidos por Load.
+func (l *Loader) files() []string {
+	files := []string{filepath.Join(l.Dir, ".env")}
+	if file := l.configFile(); file != "" {
+		files = append(files, file)
+	}
+	return files
+}
+
+// configFile retorna o arquivo de configuração: File ou, vazio, CONFIG_FILE.
+func (l *Loader) configFile() string {
+	if l.File != "" {
+		return l.File
+	}
+	return os.Getenv("CONFIG_FILE")
+}
+
+func stat(files []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(files))
+	for _, name := range files {
+		if info, err := os.Stat(name); err == nil {
+			stamps[name] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/service"
+)
+
+// Problem é um valor de configuração inválido.
+type Problem struct {
+	Key     string // Nome da variável (ex: WEB_SERVER_PORT)
+	Message string
+}
+
+// ValidationError reúne todos os problemas encontrados na configuração, para
+// que sejam corrigidos de uma vez.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.Key + ": " + p.Message
+	}
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(msgs, "; "))
+}
+
+// Validate confere os valores já convertidos: obrigatórios, portas, durações,
+// URLs e opções com valores fixos. Retorna um *ValidationError com todos os
+// problemas ou nil.
+func (c *Config) Validate() error {
+	var v validator
+	v.required("WEATHER_API_KEY", c.WeatherAPIKey)
+	v.port("WEB_SERVER_PORT", c.WebServerPort)
+	v.port("GRPC_PORT", c.GRPCPort)
+	if c.WebServerPort != "" && c.WebServerPort == c.GRPCPort {
+		v.add("GRPC_PORT", "must differ from WEB_SERVER_PORT")
+	}
+	if c.AdminAddr != "" {
+		if _, port, err := net.SplitHostPort(c.AdminAddr); err != nil {
+			v.add("ADMIN_ADDR", "must be host:port (e.g. 127.0.0.1:9090) or empty, got %q", c.AdminAddr)
+		} else {
+			v.port("ADMIN_ADDR", port)
+			if c.AdminToken == "" && !loopback(c.AdminAddr) {
+				v.add("ADMIN_ADDR", "listening beyond loopback requires ADMIN_TOKEN, got %q", c.AdminAddr)
+			}
+		}
+	}
+
+	v.positive("HTTP_READ_HEADER_TIMEOUT", c.HTTPReadHeaderTimeout)
+	v.nonNegative("HTTP_READ_TIMEOUT", c.HTTPReadTimeout)
+	v.nonNegative("HTTP_WRITE_TIMEOUT", c.HTTPWriteTimeout)
+	v.nonNegative("HTTP_IDLE_TIMEOUT", c.HTTPIdleTimeout)
+	v.atLeast("HTTP_MAX_HEADER_BYTES", c.HTTPMaxHeaderBytes, 1)
+	v.positive("SHUTDOWN_TIMEOUT", c.ShutdownTimeout)
+	v.nonNegative("CONFIG_RELOAD_INTERVAL", c.ConfigReloadInterval)
+
+	switch {
+	case c.TLSCertFile != "" && c.TLSKeyFile == "":
+		v.add("TLS_KEY_FILE", "must be set together with TLS_CERT_FILE")
+	case c.TLSCertFile == "" && c.TLSKeyFile != "":
+		v.add("TLS_CERT_FILE", "must be set together with TLS_KEY_FILE")
+	case c.TLSCertFile == "" && c.TLSClientCAFile != "":
+		v.add("TLS_CLIENT_CA_FILE", "requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	v.positive("TLS_RELOAD_INTERVAL", c.TLSReloadInterval)
+
+	v.oneOf("LOG_FORMAT", strings.ToLower(c.LogFormat), "json", "text")
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		v.add("LOG_LEVEL", "must be debug, info, warn or error, got %q", c.LogLevel)
+	}
+	if c.OTLPEndpoint != "" {
+		if u, err := url.Parse(c.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add("OTEL_EXPORTER_OTLP_ENDPOINT", "must be an http or https URL (e.g. http://otel-collector:4318), got %q", c.OTLPEndpoint)
+		}
+	}
+
+	for _, raw := range c.TrustedProxies {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		if _, err := netip.ParsePrefix(raw); err != nil {
+			if _, err := netip.ParseAddr(raw); err != nil {
+				v.add("TRUSTED_PROXIES", "%q is not an IP address or CIDR range", raw)
+			}
+		}
+	}
+	for _, spec := range c.RateLimitTiers {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		if _, err := service.ParseRateLimitTier(spec); err != nil {
+			v.add("RATE_LIMIT_TIERS", "%s", err)
+		}
+	}
+	v.oneOf("API_KEYS_STORE", c.APIKeysStore, "file", "sqlite")
+
+	v.nonNegative("CACHE_MAX_AGE", c.CacheMaxAge)
+	v.positive("UPSTREAM_TIMEOUT", c.UpstreamTimeout)
+	v.atLeast("BREAKER_FAILURE_THRESHOLD", c.BreakerFailureThreshold, 1)
+	v.positive("BREAKER_COOLDOWN", c.BreakerCooldown)
+	v.nonNegative("READINESS_CACHE_TTL", c.ReadinessCacheTTL)
+	v.nonNegative("LOCATION_CACHE_TTL", c.LocationCacheTTL)
+	v.nonNegative("WEATHER_CACHE_TTL", c.WeatherCacheTTL)
+
+	v.between("TEMP_PRECISION_C", c.TempPrecisionC, 0, service.MaxPrecision)
+	v.between("TEMP_PRECISION_F", c.TempPrecisionF, 0, service.MaxPrecision)
+	v.between("TEMP_PRECISION_K", c.TempPrecisionK, 0, service.MaxPrecision)
+	if _, err := service.ParseRoundingMode(c.TempRoundingMode); err != nil {
+		v.add("TEMP_ROUNDING_MODE", "must be %s or %s, got %q", service.RoundHalfEven, service.RoundHalfUp, c.TempRoundingMode)
+	}
+
+	v.atLeast("BATCH_MAX_SIZE", c.BatchMaxSize, 1)
+	v.atLeast("BATCH_CONCURRENCY", c.BatchConcurrency, 1)
+	v.positive("BATCH_TIMEOUT", c.BatchTimeout)
+	v.positive("STREAM_POLL_INTERVAL", c.StreamPollInterval)
+	v.positive("STREAM_HEARTBEAT", c.StreamHeartbeat)
+	v.atLeast("STREAM_MAX_SUBSCRIBERS", c.StreamMaxSubscribers, 1)
+	v.positive("SUBSCRIPTION_CHECK_INTERVAL", c.SubscriptionCheckInterval)
+	v.atLeast("WEBHOOK_MAX_ATTEMPTS", c.WebhookMaxAttempts, 1)
+	v.positive("WEBHOOK_BACKOFF", c.WebhookBackoff)
+	v.positive("WEBHOOK_TIMEOUT", c.WebhookTimeout)
+	v.atLeast("WEBHOOK_CONCURRENCY", c.WebhookConcurrency, 1)
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+// loopback informa se o endereço host:port só aceita conexões locais. Host
+// vazio (ex: ":9090") escuta em todas as interfaces.
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
+
+// validator acumula os problemas encontrados por Validate.
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) add(key, format string, args ...any) {
+	v.problems = append(v.problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(key, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(key, "is required")
+	}
+}
+
+func (v *validator) port(key, value string) {
+	if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+		v.add(key, "must be a port between 1 and 65535, got %q", value)
+	}
+}
+
+func (v *validator) positive(key string, d time.Duration) {
+	if d <= 0 {
+		v.add(key, "must be a positive duration (e.g. 10s), got %s", d)
+	}
+}
+
+func (v *validator) nonNegative(key string, d time.Duration) {
+	if d < 0 {
+		v.add(key, "must not be negative (0 disables it), got %s", d)
+	}
+}
+
+func (v *validator) atLeast(key string, n, min int) {
+	if n < min {
+		v.add(key, "must be at least %d, got %d", min, n)
+	}
+}
+
+func (v *validator) between(key string, n, min, max int) {
+	if n < min || n > max {
+		v.add(key, "must be between %d and %d, got %d", min, max, n)
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		v.add(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+}
+
+// decodeKey acha o nome da variável nas mensagens do mapstructure, como em
+// "error decoding 'UPSTREAM_TIMEOUT': time: invalid duration".
+var decodeKey = regexp.MustCompile(`'([A-Z0-9_]+)'`)
+
+// decodeProblems converte os erros de conversão do viper (ex: uma duração sem
+// unidade) em problemas, um por variável.
+func decodeProblems(err error) []Problem {
+	errs := []error{err}
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		errs = joined.Unwrap()
+	}
+	problems := make([]Problem, 0, len(errs))
+	for _, e := range errs {
+		msg := e.Error()
+		m := decodeKey.FindStringSubmatchIndex(msg)
+		if m == nil {
+			problems = append(problems, Problem{Key: "config", Message: msg})
+			continue
+		}
+		key := msg[m[2]:m[3]]
+		if rest, ok := strings.CutPrefix(msg, "error decoding '"+key+"': "); ok {
+			msg = "invalid value: " + rest // "invalid value: time: invalid duration ..."
+		} else {
+			msg = msg[:m[0]] + "value" + msg[m[1]:] // "cannot parse value as int: ..."
+		}
+		problems = append(problems, Problem{Key: key, Message: msg})
+	}
+	return problems
+}
+
+// mergeProblems junta os problemas de conversão e de validação na ordem dos
+// campos de Config. Uma variável que não pôde ser convertida tem só o erro de
+// conversão: o valor validado seria o zero, não o informado.
+func mergeProblems(decoded, validated []Problem) []Problem {
+	out := slices.Clone(decoded)
+	for _, p := range validated {
+		if !slices.ContainsFunc(decoded, func(d Problem) bool { return d.Key == p.Key }) {
+			out = append(out, p)
+		}
+	}
+	order := keys()
+	slices.SortStableFunc(out, func(a, b Problem) int {
+		return slices.Index(order, a.Key) - slices.Index(order, b.Key)
+	})
+	return out
+}
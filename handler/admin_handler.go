@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminHandler contém as dependências das rotas do listener de administração,
+// que respondem sempre em JSON.
+type AdminHandler struct {
+	Config   func() map[string]any              // Configuração efetiva, já sem os segredos
+	Caches   map[string]service.Cache           // Por nome (service.CacheLocation, service.CacheWeather)
+	Breakers map[string]*service.CircuitBreaker // Por provedor
+	Build    entity.BuildInfo
+	Logger   *slog.Logger
+}
+
+// NewAdminHandler cria uma nova instância de AdminHandler, com a versão do
+// binário lida das informações de build.
+func NewAdminHandler(config func() map[string]any, caches map[string]service.Cache, breakers []*service.CircuitBreaker) *AdminHandler {
+	byProvider := make(map[string]*service.CircuitBreaker, len(breakers))
+	for _, b := range breakers {
+		byProvider[b.Status().Provider] = b
+	}
+	return &AdminHandler{Config: config, Caches: caches, Breakers: byProvider, Build: ReadBuildInfo(time.Now()), Logger: slog.Default()}
+}
+
+// ReadBuildInfo monta a versão a partir das informações gravadas pelo go build
+// (módulo, commit do git e versão do Go).
+func ReadBuildInfo(startedAt time.Time) entity.BuildInfo {
+	info := entity.BuildInfo{StartedAt: startedAt.UTC()}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Version = bi.Main.Version
+	info.GoVersion = bi.GoVersion
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.RevisionTime = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+// GetConfig é o handler para GET /config.
+func (h *AdminHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Config())
+}
+
+// GetBuildInfo é o handler para GET /buildinfo.
+func (h *AdminHandler) GetBuildInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Build)
+}
+
+// ListCaches é o handler para GET /caches.
+func (h *AdminHandler) ListCaches(w http.ResponseWriter, r *http.Request) {
+	out := make([]entity.CacheInfo, 0, len(h.Caches))
+	for name, c := range h.Caches {
+		out = append(out, cacheInfo(name, c.Stats()))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	writeJSON(w, http.StatusOK, out)
+}
+
+// FlushCache é o handler para POST /caches/{name}/flush: descarta todas as
+// entradas, e as próximas consultas voltam ao provedor.
+func (h *AdminHandler) FlushCache(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	c, ok := h.Caches[name]
+	if !ok {
+		WriteProblem(w, r, apperr.CodeCacheNotFound, "no cache named "+name)
+		return
+	}
+	c.Flush()
+	h.Logger.InfoContext(r.Context(), "cache flushed", "cache", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EvictCacheKey é o handler para DELETE /caches/{name}/keys/{key}. A chave é
+// o CEP no cache location e o nome da cidade no cache weather.
+func (h *AdminHandler) EvictCacheKey(w http.ResponseWriter, r *http.Request) {
+	name, key := chi.URLParam(r, "name"), chi.URLParam(r, "key")
+	c, ok := h.Caches[name]
+	if !ok {
+		WriteProblem(w, r, apperr.CodeCacheNotFound, "no cache named "+name)
+		return
+	}
+	if !c.Evict(key) {
+		WriteProblem(w, r, apperr.CodeCacheNotFound, "cache "+name+" has no entry for "+key)
+		return
+	}
+	h.Logger.InfoContext(r.Context(), "cache entry evicted", "cache", name, "key", key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListBreakers é o handler para GET /breakers.
+func (h *AdminHandler) ListBreakers(w http.ResponseWriter, r *http.Request) {
+	out := make([]entity.BreakerInfo, 0, len(h.Breakers))
+	for _, b := range h.Breakers {
+		out = append(out, breakerInfo(b.Status()))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Provider < out[j].Provider })
+	writeJSON(w, http.StatusOK, out)
+}
+
+// OpenBreaker é o handler para POST /breakers/{provider}/open: as consultas ao
+// provedor passam a falhar na hora, até o cooldown ou um close.
+func (h *AdminHandler) OpenBreaker(w http.ResponseWriter, r *http.Request) {
+	h.setBreaker(w, r, (*service.CircuitBreaker).Trip, "circuit breaker opened manually")
+}
+
+// CloseBreaker é o handler para POST /breakers/{provider}/close: o provedor
+// volta a ser chamado e as falhas são zeradas.
+func (h *AdminHandler) CloseBreaker(w http.ResponseWriter, r *http.Request) {
+	h.setBreaker(w, r, (*service.CircuitBreaker).Reset, "circuit breaker closed manually")
+}
+
+func (h *AdminHandler) setBreaker(w http.ResponseWriter, r *http.Request, apply func(*service.CircuitBreaker), msg string) {
+	provider := chi.URLParam(r, "provider")
+	b, ok := h.Breakers[provider]
+	if !ok {
+		WriteProblem(w, r, apperr.CodeBreakerNotFound, "no circuit breaker for provider "+provider)
+		return
+	}
+	apply(b)
+	h.Logger.WarnContext(r.Context(), msg, "provider", provider)
+	writeJSON(w, http.StatusOK, breakerInfo(b.Status()))
+}
+
+func cacheInfo(name string, s service.CacheStats) entity.CacheInfo {
+	info := entity.CacheInfo{Name: name, Hits: s.Hits, Misses: s.Misses, Entries: s.Entries}
+	if total := s.Hits + s.Misses; total > 0 {
+		info.HitRatio = float64(s.Hits) / float64(total)
+	}
+	return info
+}
+
+func breakerInfo(s service.BreakerStatus) entity.BreakerInfo {
+	info := entity.BreakerInfo{Provider: s.Provider, State: s.State, Failures: s.Failures}
+	info.OpenedAt = timeOrNil(s.OpenedAt)
+	info.LastSuccess = timeOrNil(s.LastSuccess)
+	info.LastFailure = timeOrNil(s.LastFailure)
+	return info
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// writeJSON escreve v em JSON, sem negociação de formato.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler(t *testing.T) {
+	mockLocation := new(mocks.LocationFinder)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+	location := service.NewCachedLocationFinder(mockLocation, time.Hour)
+	_, err := location.GetLocationByCEP(context.Background(), "01001000")
+	require.NoError(t, err)
+	_, err = location.GetLocationByCEP(context.Background(), "01001000")
+	require.NoError(t, err)
+
+	breaker := service.NewCircuitBreaker(service.ProviderViaCEP, 5, time.Minute)
+	h := NewAdminHandler(func() map[string]any { return map[string]any{"WEATHER_API_KEY": "[REDACTED]"} },
+		map[string]service.Cache{service.CacheLocation: location}, []*service.CircuitBreaker{breaker})
+	r := chi.NewRouter()
+	r.Get("/config", h.GetConfig)
+	r.Get("/buildinfo", h.GetBuildInfo)
+	r.Get("/caches", h.ListCaches)
+	r.Post("/caches/{name}/flush", h.FlushCache)
+	r.Delete("/caches/{name}/keys/{key}", h.EvictCacheKey)
+	r.Get("/breakers", h.ListBreakers)
+	r.Post("/breakers/{provider}/open", h.OpenBreaker)
+	r.Post("/breakers/{provider}/close", h.CloseBreaker)
+
+	do := func(method, target string, out any) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		if out != nil {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), out), rr.Body.String())
+		}
+		return rr
+	}
+
+	t.Run("Config And Build Info", func(t *testing.T) {
+		var config map[string]any
+		rr := do("GET", "/config", &config)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		assert.Equal(t, "[REDACTED]", config["WEATHER_API_KEY"])
+
+		var build entity.BuildInfo
+		do("GET", "/buildinfo", &build)
+		assert.NotEmpty(t, build.GoVersion)
+		assert.False(t, build.StartedAt.IsZero())
+	})
+
+	t.Run("Caches", func(t *testing.T) {
+		var caches []entity.CacheInfo
+		do("GET", "/caches", &caches)
+		assert.Equal(t, []entity.CacheInfo{{Name: "location", Hits: 1, Misses: 1, Entries: 1, HitRatio: 0.5}}, caches)
+
+		assert.Equal(t, http.StatusNoContent, do("DELETE", "/caches/location/keys/01001000", nil).Code)
+		var problem entity.Problem
+		assert.Equal(t, http.StatusNotFound, do("DELETE", "/caches/location/keys/01001000", &problem).Code)
+		assert.Equal(t, "cache_not_found", problem.Code)
+
+		_, _ = location.GetLocationByCEP(context.Background(), "01001000")
+		assert.Equal(t, http.StatusNoContent, do("POST", "/caches/location/flush", nil).Code)
+		assert.Zero(t, location.Stats().Entries)
+		assert.Equal(t, http.StatusNotFound, do("POST", "/caches/nope/flush", nil).Code)
+	})
+
+	t.Run("Breakers", func(t *testing.T) {
+		var info entity.BreakerInfo
+		rr := do("POST", "/breakers/viacep/open", &info)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, service.BreakerOpen, info.State)
+		assert.NotNil(t, info.OpenedAt)
+		_, err := service.NewBreakerLocationFinder(mockLocation, breaker).GetLocationByCEP(context.Background(), "01001000")
+		assert.ErrorIs(t, err, service.ErrCircuitOpen)
+
+		var breakers []entity.BreakerInfo
+		do("GET", "/breakers", &breakers)
+		require.Len(t, breakers, 1)
+		assert.Equal(t, service.BreakerOpen, breakers[0].State)
+
+		do("POST", "/breakers/viacep/close", &info)
+		assert.Equal(t, service.BreakerClosed, info.State)
+
+		var problem entity.Problem
+		assert.Equal(t, http.StatusNotFound, do("POST", "/breakers/nope/open", &problem).Code)
+		assert.Equal(t, "breaker_not_found", problem.Code)
+	})
+}
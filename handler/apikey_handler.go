@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Limites de POST /admin/keys.
+const (
+	maxAPIKeyBody    = 4 << 10
+	maxAPIKeyNameLen = 100
+)
+
+// APIKeyHandler contém as dependências para as rotas de administração das chaves de API.
+type APIKeyHandler struct {
+	Store  service.APIKeyStore
+	Logger *slog.Logger
+}
+
+// NewAPIKeyHandler cria uma nova instância de APIKeyHandler.
+func NewAPIKeyHandler(store service.APIKeyStore) *APIKeyHandler {
+	return &APIKeyHandler{Store: store, Logger: slog.Default()}
+}
+
+// CreateAPIKey é o handler para POST /admin/keys. A resposta é a única, junto
+// com a da rotação, que traz o valor da chave.
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !checkFormat(w, r) {
+		return
+	}
+
+	// 1. Ler e validar o pedido
+	var in entity.APIKeyInput
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIKeyBody)).Decode(&in); err != nil {
+		WriteProblem(w, r, apperr.CodeInvalidRequestBody, "request body must be a JSON object with name, scopes and quota")
+		return
+	}
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" || len(in.Name) > maxAPIKeyNameLen {
+		WriteProblem(w, r, apperr.CodeInvalidRequestBody, "name is required and must have at most 100 characters")
+		return
+	}
+	scopes, err := service.NormalizeScopes(in.Scopes)
+	if err != nil {
+		writeError(w, r, err, apperr.CodeInvalidScope, err.Error())
+		return
+	}
+	if in.Quota != "" {
+		if _, err := service.ParseQuota(in.Quota); err != nil {
+			writeError(w, r, err, apperr.CodeInvalidQuota, err.Error())
+			return
+		}
+	}
+
+	// 2. Gerar a chave e gravar só o hash
+	secret, prefix, hash := service.NewAPIKeySecret()
+	key := entity.APIKey{
+		ID:        service.NewAPIKeyID(),
+		Name:      in.Name,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		Quota:     in.Quota,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := h.Store.CreateAPIKey(r.Context(), key, hash); err != nil {
+		h.Logger.ErrorContext(r.Context(), "error saving API key", "error", err)
+		WriteProblem(w, r, apperr.CodeInternalError, "error while saving API key")
+		return
+	}
+	h.Logger.InfoContext(r.Context(), "API key created", "api_key_id", key.ID, "api_key_prefix", key.Prefix, "api_key_name", key.Name, "scopes", key.Scopes)
+
+	key.Key = secret
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+key.ID)
+	render(w, r, http.StatusCreated, &key)
+}
+
+// ListAPIKeys é o handler para GET /admin/keys, inclusive as chaves revogadas.
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if !checkFormat(w, r) {
+		return
+	}
+	keys, err := h.Store.ListAPIKeys(r.Context())
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "error listing API keys", "error", err)
+		WriteProblem(w, r, apperr.CodeInternalError, "error while listing API keys")
+		return
+	}
+	render(w, r, http.StatusOK, keys)
+}
+
+// GetAPIKey é o handler para GET /admin/keys/{id}.
+func (h *APIKeyHandler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !checkFormat(w, r) {
+		return
+	}
+	key, err := h.Store.GetAPIKey(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err, apperr.CodeInternalError, "error while fetching API key")
+		return
+	}
+	render(w, r, http.StatusOK, key)
+}
+
+// RotateAPIKey é o handler para POST /admin/keys/{id}/rotate: gera um novo
+// valor para a chave, mantendo ID, escopos e cota. O valor anterior deixa de
+// valer imediatamente.
+func (h *APIKeyHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !checkFormat(w, r) {
+		return
+	}
+	secret, prefix, hash := service.NewAPIKeySecret()
+	key, err := h.Store.RotateAPIKey(r.Context(), chi.URLParam(r, "id"), hash, prefix, time.Now().UTC())
+	if err != nil {
+		writeError(w, r, err, apperr.CodeInternalError, "error while rotating API key")
+		return
+	}
+	h.Logger.InfoContext(r.Context(), "API key rotated", "api_key_id", key.ID, "api_key_prefix", key.Prefix, "api_key_name", key.Name)
+	key.Key = secret
+	render(w, r, http.StatusOK, key)
+}
+
+// RevokeAPIKey é o handler para DELETE /admin/keys/{id}. A chave continua
+// listada, com revoked_at, mas deixa de autenticar.
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.Store.RevokeAPIKey(r.Context(), id, time.Now().UTC()); err != nil {
+		writeError(w, r, err, apperr.CodeInternalError, "error while revoking API key")
+		return
+	}
+	h.Logger.InfoContext(r.Context(), "API key revoked", "api_key_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyHandler(t *testing.T) {
+	store, err := service.NewFileAPIKeyStore("")
+	require.NoError(t, err)
+
+	h := NewAPIKeyHandler(store)
+	r := chi.NewRouter()
+	r.Post("/admin/keys", h.CreateAPIKey)
+	r.Get("/admin/keys", h.ListAPIKeys)
+	r.Get("/admin/keys/{id}", h.GetAPIKey)
+	r.Post("/admin/keys/{id}/rotate", h.RotateAPIKey)
+	r.Delete("/admin/keys/{id}", h.RevokeAPIKey)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+	find := func(raw string) (*entity.APIKey, error) {
+		return store.FindAPIKeyByHash(context.Background(), service.HashAPIKey(raw))
+	}
+
+	rr := do("POST", "/admin/keys", `{"name":" acme ","scopes":["subscriptions:write","weather:read","weather:read"],"quota":"600/1m:60"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created entity.APIKey
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "/admin/keys/"+created.ID, rr.Header().Get("Location"))
+	assert.Equal(t, "acme", created.Name)
+	assert.Equal(t, []string{"subscriptions:write", "weather:read"}, created.Scopes, "scopes are sorted and deduplicated")
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+	found, err := find(created.Key)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, found.ID)
+
+	t.Run("Key Is Only Shown On Creation", func(t *testing.T) {
+		rr := do("GET", "/admin/keys/"+created.ID, "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotContains(t, rr.Body.String(), created.Key)
+
+		rr = do("GET", "/admin/keys", "")
+		var keys []entity.APIKey
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &keys))
+		assert.Len(t, keys, 1)
+		assert.Empty(t, keys[0].Key)
+	})
+
+	t.Run("Invalid Requests", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			body   string
+			status int
+			code   string
+		}{
+			{"Malformed Body", `[]`, http.StatusBadRequest, "invalid_request_body"},
+			{"Missing Name", `{"scopes":["weather:read"]}`, http.StatusBadRequest, "invalid_request_body"},
+			{"Invalid Scope", `{"name":"acme","scopes":["admin"]}`, http.StatusUnprocessableEntity, "invalid_scope"},
+			{"Invalid Quota", `{"name":"acme","quota":"lots"}`, http.StatusUnprocessableEntity, "invalid_quota"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rr := do("POST", "/admin/keys", tt.body)
+				assert.Equal(t, tt.status, rr.Code)
+				var problem entity.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, tt.code, problem.Code)
+			})
+		}
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		rr := do("POST", "/admin/keys/"+created.ID+"/rotate", "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		var rotated entity.APIKey
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rotated))
+		assert.Equal(t, created.ID, rotated.ID)
+		assert.NotEqual(t, created.Key, rotated.Key)
+		assert.NotNil(t, rotated.RotatedAt)
+
+		_, err := find(created.Key)
+		assert.ErrorIs(t, err, service.ErrAPIKeyNotFound, "the previous key stops working")
+		_, err = find(rotated.Key)
+		assert.NoError(t, err)
+		created.Key = rotated.Key
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, do("DELETE", "/admin/keys/"+created.ID, "").Code)
+		_, err := find(created.Key)
+		assert.ErrorIs(t, err, service.ErrAPIKeyNotFound)
+
+		rr := do("DELETE", "/admin/keys/"+created.ID, "")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		var problem entity.Problem
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, "api_key_not_found", problem.Code)
+		assert.Equal(t, http.StatusNotFound, do("POST", "/admin/keys/"+created.ID+"/rotate", "").Code)
+
+		rr = do("GET", "/admin/keys/"+created.ID, "")
+		assert.Equal(t, http.StatusOK, rr.Code, "revoked keys are still listed")
+		assert.Contains(t, rr.Body.String(), "revoked_at")
+	})
+}
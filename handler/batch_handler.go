@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+)
+
+// BatchHandler contém as dependências para o handler de consulta em lote.
+type BatchHandler struct {
+	Lookup    *service.BatchLookup
+	Converter service.TemperatureConverter
+	MaxSize   int
+	Timeout   time.Duration
+	Logger    *slog.Logger
+}
+
+// NewBatchHandler cria uma nova instância de BatchHandler.
+func NewBatchHandler(lookup *service.BatchLookup, conv service.TemperatureConverter, maxSize int, timeout time.Duration) *BatchHandler {
+	if maxSize <= 0 {
+		maxSize = service.DefaultBatchMaxSize
+	}
+	if timeout <= 0 {
+		timeout = service.DefaultBatchTimeout
+	}
+	return &BatchHandler{Lookup: lookup, Converter: conv, MaxSize: maxSize, Timeout: timeout, Logger: slog.Default()}
+}
+
+// GetWeatherBatch é o handler para as rotas POST /v1/weather/batch e POST /weather/batch.
+// O corpo é um array JSON de CEPs; a resposta traz um item por CEP, na mesma ordem.
+func (h *BatchHandler) GetWeatherBatch(w http.ResponseWriter, r *http.Request) {
+	if !checkFormat(w, r) {
+		return
+	}
+
+	converter, err := converterForRequest(h.Converter, r)
+	if err != nil {
+		WriteProblem(w, r, apperr.CodeInvalidPrecision, err.Error())
+		return
+	}
+
+	// 1. Ler e validar a lista de CEPs
+	var ceps []string
+	body := http.MaxBytesReader(w, r.Body, int64(h.MaxSize)*32+1024) // ~32 bytes por CEP é folga suficiente
+	if err := json.NewDecoder(body).Decode(&ceps); err != nil {
+		// Um corpo acima do limite é um lote grande demais, não um JSON inválido
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			WriteProblem(w, r, apperr.CodeBatchTooLarge, fmt.Sprintf("request body too large (max %d bytes)", tooLarge.Limit))
+			return
+		}
+		WriteProblem(w, r, apperr.CodeInvalidRequestBody, "request body must be a JSON array of zipcodes")
+		return
+	}
+	if len(ceps) == 0 {
+		WriteProblem(w, r, apperr.CodeInvalidRequestBody, "at least one zipcode is required")
+		return
+	}
+	if len(ceps) > h.MaxSize {
+		WriteProblem(w, r, apperr.CodeBatchTooLarge, fmt.Sprintf("too many zipcodes (max %d)", h.MaxSize))
+		return
+	}
+
+	// 2. Consultar todos os CEPs respeitando o tempo total do lote
+	logging.Add(r.Context(), slog.Int("batch_size", len(ceps)))
+	ctx, cancel := context.WithTimeout(r.Context(), h.Timeout)
+	defer cancel()
+	results := h.Lookup.Lookup(ctx, ceps)
+
+	// 3. Converter cada resultado ou descrever seu erro
+	items := make([]entity.BatchItem, len(results))
+	for i, res := range results {
+		items[i].CEP = res.CEP
+		if res.Err != nil {
+			h.Logger.WarnContext(r.Context(), "error in batch lookup", "item_cep", res.CEP, "error", res.Err)
+			items[i].Error = batchProblem(r, res)
+			continue
+		}
+		output := converter.ConvertTemperatures(res.Weather.TempC)
+		output.City = res.City
+		output.Indices = converter.ThermalIndices(res.Weather)
+		items[i].Result = output
+	}
+
+	render(w, r, http.StatusOK, items)
+}
+
+// batchProblem descreve a falha de um item com o mesmo problem+json que /weather/{cep} retornaria.
+func batchProblem(r *http.Request, res service.BatchResult) *entity.Problem {
+	var p *entity.Problem
+	if res.City == "" {
+		p = NewProblem(r, apperr.CodeFor(res.Err, apperr.CodeLocationLookupFailed), apperr.Detail(res.Err, "error while fetching location"))
+	} else {
+		p = NewProblem(r, apperr.CodeFor(res.Err, apperr.CodeWeatherLookupFailed), apperr.Detail(res.Err, "error while fetching weather data"))
+	}
+	// Aponta para a rota individual da mesma versão (ex: /v1/weather/01001000)
+	p.Instance = strings.TrimSuffix(r.URL.Path, "batch") + res.CEP
+	return p
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBatchHandler_GetWeatherBatch(t *testing.T) {
+	setupRouter := func(h *BatchHandler) *chi.Mux {
+		r := chi.NewRouter()
+		r.Post("/weather/batch", h.GetWeatherBatch)
+		return r
+	}
+
+	t.Run("Mixed Results", func(t *testing.T) {
+		mockLocation := new(mocks.LocationFinder)
+		mockWeather := new(mocks.WeatherFinder)
+		lookup := service.NewBatchLookup(mockLocation, mockWeather, 4)
+		handler := NewBatchHandler(lookup, service.NewStandardTemperatureConverter(), 10, time.Second)
+		r := setupRouter(handler)
+
+		mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil).Once()
+		mockLocation.On("GetLocationByCEP", mock.Anything, "01311000").Return("São Paulo", nil).Once()
+		mockLocation.On("GetLocationByCEP", mock.Anything, "99999999").Return("", service.ErrCEPNotFound).Once()
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 25}, nil).Once()
+
+		req := httptest.NewRequest("POST", "/weather/batch", strings.NewReader(`["01001000","99999999","01311000"]`))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var items []entity.BatchItem
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &items))
+		assert.Len(t, items, 3)
+		assert.Equal(t, "São Paulo", items[0].Result.City)
+		assert.Equal(t, 77.0, items[0].Result.TempF)
+		assert.Nil(t, items[0].Error)
+		assert.Nil(t, items[1].Result)
+		assert.Equal(t, http.StatusNotFound, items[1].Error.Status)
+		assert.Equal(t, "zipcode_not_found", items[1].Error.Code)
+		assert.Equal(t, "can not find zipcode", items[1].Error.Detail)
+		assert.Equal(t, "/weather/99999999", items[1].Error.Instance)
+		assert.Equal(t, "01311000", items[2].CEP)
+		mockWeather.AssertNumberOfCalls(t, "GetWeatherByCity", 1)
+	})
+
+	t.Run("Invalid Body", func(t *testing.T) {
+		handler := NewBatchHandler(service.NewBatchLookup(new(mocks.LocationFinder), new(mocks.WeatherFinder), 1), service.NewStandardTemperatureConverter(), 10, time.Second)
+		r := setupRouter(handler)
+
+		for _, body := range []string{`{"cep":"01001000"}`, `[]`, ``} {
+			req := httptest.NewRequest("POST", "/weather/batch", strings.NewReader(body))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+			assert.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("Too Many Zipcodes", func(t *testing.T) {
+		handler := NewBatchHandler(service.NewBatchLookup(new(mocks.LocationFinder), new(mocks.WeatherFinder), 1), service.NewStandardTemperatureConverter(), 2, time.Second)
+		r := setupRouter(handler)
+
+		req := httptest.NewRequest("POST", "/weather/batch", strings.NewReader(`["01001000","01001001","01001002"]`))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		var problem entity.Problem
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, "batch_too_large", problem.Code)
+		assert.Equal(t, "too many zipcodes (max 2)", problem.Detail)
+	})
+
+	t.Run("Body Too Large", func(t *testing.T) {
+		handler := NewBatchHandler(service.NewBatchLookup(new(mocks.LocationFinder), new(mocks.WeatherFinder), 1), service.NewStandardTemperatureConverter(), 2, time.Second)
+		r := setupRouter(handler)
+
+		// Limite de 2*32+1024 bytes; o decoder estoura antes de fechar o array
+		body := `["` + strings.Repeat("0", 2000) + `"]`
+		req := httptest.NewRequest("POST", "/weather/batch", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+		var problem entity.Problem
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, "batch_too_large", problem.Code)
+		assert.Equal(t, "request body too large (max 1088 bytes)", problem.Detail)
+	})
+}
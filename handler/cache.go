@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCacheMaxAge é o intervalo em que a WeatherAPI atualiza as observações,
+// usado como teto do max-age das respostas de clima.
+const DefaultCacheMaxAge = 15 * time.Minute
+
+// cacheable descreve como validar uma resposta de clima.
+type cacheable struct {
+	observedAt time.Time     // Momento da observação do provedor; zero quando desconhecido
+	maxAge     time.Duration // Teto do max-age; zero desliga o cache (no-cache)
+	tagged     any           // Valor usado no ETag no lugar do corpo (ETag fraco); nil usa o corpo
+	private    bool          // Só o cache do próprio cliente pode guardar a resposta
+}
+
+// renderCached responde v como render, acrescentando Cache-Control, ETag e
+// Last-Modified, e responde 304 quando If-None-Match ou If-Modified-Since
+// indicam que o cliente já tem a mesma representação.
+func renderCached(w http.ResponseWriter, r *http.Request, v any, c cacheable) {
+	format, body, ok := negotiateBody(w, r, v)
+	if !ok {
+		return
+	}
+
+	etag := strongETag(body)
+	if c.tagged != nil {
+		// Campos que mudam a cada requisição (ex: metadados da v2) ficam de fora:
+		// o corpo não é idêntico byte a byte, então o ETag é fraco
+		tagged, err := encode(format, c.tagged)
+		if err == nil {
+			etag = "W/" + strongETag(append([]byte(format+"\n"), tagged...))
+		}
+	}
+	w.Header().Set("ETag", etag)
+	cc := cacheControl(c.observedAt, c.maxAge, time.Now())
+	if c.private {
+		cc = "private, " + strings.TrimPrefix(cc, "public, ")
+	} else {
+		// A mesma URL com chave de API tem outra resposta (privada, ou 401/429)
+		w.Header().Add("Vary", "Authorization, X-API-Key")
+	}
+	w.Header().Set("Cache-Control", cc)
+	if !c.observedAt.IsZero() {
+		w.Header().Set("Last-Modified", c.observedAt.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, c.observedAt) {
+		// Como no http.ServeContent, os headers do corpo não acompanham o 304
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// cacheControl calcula o tempo de vida restante da observação: a próxima
+// atualização do provedor é esperada maxAge depois dela. Sem o momento da
+// observação, o cliente deve revalidar a cada uso.
+func cacheControl(observedAt time.Time, maxAge time.Duration, now time.Time) string {
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+	remaining := time.Duration(0)
+	if !observedAt.IsZero() {
+		remaining = min(max(maxAge-now.Sub(observedAt), 0), maxAge)
+	}
+	return "public, max-age=" + strconv.Itoa(int(remaining/time.Second))
+}
+
+// strongETag é um ETag forte sobre os bytes da representação.
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified avalia as pré-condições de um GET (RFC 9110, seção 13.2.2):
+// If-None-Match tem precedência e usa a comparação fraca; If-Modified-Since só
+// vale na ausência dele e quando o momento da observação é conhecido.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagMatches aplica a comparação fraca entre a lista do If-None-Match e o ETag atual.
+func etagMatches(header, etag string) bool {
+	current := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == current {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPResolver descobre o IP do cliente de uma requisição. O header
+// X-Forwarded-For só é considerado quando a conexão vem de um proxy confiável,
+// e é lido da direita para a esquerda até o primeiro endereço não confiável,
+// para que um cliente não consiga forjar o próprio IP.
+type ClientIPResolver struct {
+	TrustedProxies []netip.Prefix
+}
+
+// NewClientIPResolver cria o resolvedor a partir de IPs ou faixas CIDR
+// (ex: "10.0.0.0/8", "127.0.0.1").
+func NewClientIPResolver(trusted []string) (*ClientIPResolver, error) {
+	c := &ClientIPResolver{}
+	for _, raw := range trusted {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if !strings.Contains(raw, "/") {
+			addr, err := netip.ParseAddr(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+			}
+			c.TrustedProxies = append(c.TrustedProxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+		}
+		c.TrustedProxies = append(c.TrustedProxies, prefix.Masked())
+	}
+	return c, nil
+}
+
+// ClientIP retorna o IP do cliente, ou um endereço inválido se RemoteAddr não
+// puder ser interpretado. Um resolvedor nil usa apenas RemoteAddr.
+func (c *ClientIPResolver) ClientIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	peer = peer.Unmap()
+	if c == nil || !c.trusted(peer) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break // Entrada malformada: não dá para confiar no que vem antes dela
+		}
+		client = addr.Unmap()
+		if !c.trusted(client) {
+			break
+		}
+	}
+	return client
+}
+
+func (c *ClientIPResolver) trusted(addr netip.Addr) bool {
+	for _, p := range c.TrustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
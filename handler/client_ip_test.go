@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8", " 192.168.1.1 ", "::1"})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"No Proxy", "203.0.113.7:1234", nil, "203.0.113.7"},
+		{"Untrusted Peer Ignores Header", "203.0.113.7:1234", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"Trusted Peer", "10.1.2.3:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"Chain Of Trusted Proxies", "10.1.2.3:1234", []string{"198.51.100.1, 192.168.1.1", "10.9.9.9"}, "198.51.100.1"},
+		{"Spoofed Left Entries", "10.1.2.3:1234", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"Malformed Entry Stops", "10.1.2.3:1234", []string{"198.51.100.1, garbage, 10.2.2.2"}, "10.2.2.2"},
+		{"IPv6 Loopback Proxy", "[::1]:1234", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"IPv4 Mapped", "[::ffff:203.0.113.7]:1234", nil, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, v := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			assert.Equal(t, tt.want, resolver.ClientIP(req).String())
+		})
+	}
+
+	_, err = NewClientIPResolver([]string{"not-an-ip"})
+	assert.Error(t, err)
+
+	var none *ClientIPResolver
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.1.2.3:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	assert.Equal(t, "10.1.2.3", none.ClientIP(req).String())
+}
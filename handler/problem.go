@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ProblemContentType é o Content-Type das respostas de erro (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypeBaseURI é a base dos URIs de "type"; cada código aponta para sua seção em docs/errors.md.
+const ProblemTypeBaseURI = "https://github.com/MchlAlex/fc-lab02/blob/main/docs/errors.md#"
+
+// NewProblem monta o corpo RFC 7807 para o código informado.
+func NewProblem(r *http.Request, code apperr.Code, detail string) *entity.Problem {
+	pt := apperr.Lookup(code)
+	p := &entity.Problem{
+		Type:   ProblemTypeBaseURI + string(pt.Code),
+		Title:  pt.Title,
+		Status: pt.Status,
+		Detail: detail,
+		Code:   string(pt.Code),
+	}
+	if r != nil {
+		p.Instance = r.URL.Path
+		p.RequestID = middleware.GetReqID(r.Context())
+	}
+	return p
+}
+
+// WriteProblem escreve uma resposta de erro no formato negociado para a
+// requisição (application/problem+json por padrão).
+func WriteProblem(w http.ResponseWriter, r *http.Request, code apperr.Code, detail string) {
+	p := NewProblem(r, code, detail)
+	if r != nil {
+		logging.Add(r.Context(), slog.String("error_code", p.Code))
+	}
+	render(w, r, p.Status, p)
+}
+
+// NotFound responde às rotas inexistentes com um problem+json.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, apperr.CodeRouteNotFound, "no route matches "+r.URL.Path)
+}
+
+// writeError escreve o problem+json correspondente a err, incluindo o header
+// Retry-After quando a falha é temporária.
+func writeError(w http.ResponseWriter, r *http.Request, err error, fallback apperr.Code, fallbackDetail string) {
+	code := apperr.CodeFor(err, fallback)
+	if wait := apperr.RetryAfter(err, code); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	}
+	WriteProblem(w, r, code, apperr.Detail(err, fallbackDetail))
+}
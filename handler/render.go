@@ -0,0 +1,371 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format é um formato de resposta suportado pela API.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatXML  Format = "xml"
+	FormatCSV  Format = "csv"
+	FormatYAML Format = "yaml"
+	FormatText Format = "text"
+)
+
+// problemXMLNamespace é o namespace do application/problem+xml (RFC 7807, apêndice A).
+const problemXMLNamespace = "urn:ietf:rfc:7807"
+
+var (
+	ErrUnknownFormat  = errors.New("unknown format")
+	ErrNotAcceptable  = errors.New("no acceptable format")
+	errNotRenderable  = errors.New("value cannot be rendered in this format")
+	supportedFormats  = []Format{FormatJSON, FormatXML, FormatCSV, FormatYAML, FormatText}
+	formatContentType = map[Format]string{
+		FormatJSON: "application/json",
+		FormatXML:  "application/xml",
+		FormatCSV:  "text/csv; charset=utf-8",
+		FormatYAML: "application/yaml",
+		FormatText: "text/plain; charset=utf-8",
+	}
+	// mediaTypeFormats associa os media types aceitos no header Accept a cada formato.
+	mediaTypeFormats = map[string]Format{
+		"application/json":         FormatJSON,
+		"application/problem+json": FormatJSON,
+		"application/*":            FormatJSON,
+		"*/*":                      FormatJSON,
+		"application/xml":          FormatXML,
+		"application/problem+xml":  FormatXML,
+		"text/xml":                 FormatXML,
+		"text/csv":                 FormatCSV,
+		"application/yaml":         FormatYAML,
+		"application/x-yaml":       FormatYAML,
+		"text/yaml":                FormatYAML,
+		"text/plain":               FormatText,
+		"text/*":                   FormatText,
+	}
+)
+
+// NegotiateFormat escolhe o formato da resposta. O parâmetro ?format= tem
+// precedência sobre o header Accept; sem nenhum dos dois, a resposta é JSON.
+func NegotiateFormat(r *http.Request) (Format, error) {
+	if raw := r.URL.Query().Get("format"); raw != "" {
+		f := Format(strings.ToLower(raw))
+		if _, ok := formatContentType[f]; !ok {
+			return "", fmt.Errorf("%w: %q", ErrUnknownFormat, raw)
+		}
+		return f, nil
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return FormatJSON, nil
+	}
+
+	type candidate struct {
+		format Format
+		q      float64
+		order  int
+	}
+	var candidates []candidate
+	for i, part := range strings.Split(accept, ",") {
+		mediaType, q := parseMediaRange(part)
+		if f, ok := mediaTypeFormats[mediaType]; ok && q > 0 {
+			candidates = append(candidates, candidate{f, q, i})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w for Accept %q", ErrNotAcceptable, accept)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].format, nil
+}
+
+// parseMediaRange extrai o media type e o peso q de um item do header Accept.
+func parseMediaRange(part string) (string, float64) {
+	params := strings.Split(part, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+	q := 1.0
+	for _, p := range params[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if ok && strings.EqualFold(key, "q") {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+	}
+	return mediaType, q
+}
+
+// render escreve v com o status informado no formato negociado para a requisição.
+// Se não houver formato aceitável, responde 406 em problem+json.
+func render(w http.ResponseWriter, r *http.Request, status int, v any) {
+	_, body, ok := negotiateBody(w, r, v)
+	if !ok {
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// negotiateBody codifica v no formato negociado e define Content-Type e Vary.
+// Em caso de falha, já responde com o problem+json e retorna ok=false.
+func negotiateBody(w http.ResponseWriter, r *http.Request, v any) (Format, []byte, bool) {
+	format, err := NegotiateFormat(r)
+	if err != nil {
+		writeNegotiationProblem(w, r, err)
+		return "", nil, false
+	}
+	body, err := encode(format, v)
+	if errors.Is(err, errNotRenderable) {
+		writeNegotiationProblem(w, r, fmt.Errorf("%w: %s is not available for this resource", ErrNotAcceptable, format))
+		return "", nil, false
+	}
+	if err != nil {
+		writeNegotiationProblem(w, r, err)
+		return "", nil, false
+	}
+
+	w.Header().Set("Content-Type", contentType(format, v))
+	w.Header().Add("Vary", "Accept")
+	return format, body, true
+}
+
+// writeNegotiationProblem responde a falhas de negociação sempre em JSON, já
+// que o formato pedido é justamente o que não pôde ser atendido.
+func writeNegotiationProblem(w http.ResponseWriter, r *http.Request, err error) {
+	code := apperr.CodeNotAcceptable
+	if errors.Is(err, ErrUnknownFormat) {
+		code = apperr.CodeInvalidFormat
+	} else if !errors.Is(err, ErrNotAcceptable) {
+		code = apperr.CodeInternalError
+	}
+	p := NewProblem(r, code, err.Error())
+	body, _ := json.Marshal(p)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	w.Write(append(body, '\n'))
+}
+
+// checkFormat valida a negociação antes de qualquer chamada externa, para que
+// um formato inválido não custe uma consulta à ViaCEP/WeatherAPI.
+func checkFormat(w http.ResponseWriter, r *http.Request) bool {
+	if _, err := NegotiateFormat(r); err != nil {
+		writeNegotiationProblem(w, r, err)
+		return false
+	}
+	return true
+}
+
+func contentType(format Format, v any) string {
+	if _, ok := v.(*entity.Problem); ok {
+		switch format {
+		case FormatJSON:
+			return ProblemContentType
+		case FormatXML:
+			return "application/problem+xml"
+		}
+	}
+	return formatContentType[format]
+}
+
+func encode(format Format, v any) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case FormatXML:
+		buf.WriteString(xml.Header)
+		enc := xml.NewEncoder(&buf)
+		enc.Indent("", "  ")
+		if err := encodeXML(enc, v); err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+	case FormatYAML:
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+		enc.Close()
+	case FormatCSV:
+		header, rows, err := csvRecords(v)
+		if err != nil {
+			return nil, err
+		}
+		cw := csv.NewWriter(&buf)
+		cw.Write(header)
+		cw.WriteAll(rows)
+		if err := cw.Error(); err != nil {
+			return nil, err
+		}
+	case FormatText:
+		text, err := textLines(v)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(text)
+	default:
+		if err := json.NewEncoder(&buf).Encode(v); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeXML(enc *xml.Encoder, v any) error {
+	switch v := v.(type) {
+	case *entity.Problem:
+		return enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Space: problemXMLNamespace, Local: "problem"}})
+	case *entity.WeatherOutput:
+		return enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "weather"}})
+	case *entity.WeatherOutputV2:
+		return enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "weather"}})
+	case *entity.Subscription:
+		return enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "subscription"}})
+	case []entity.Subscription:
+		return enc.EncodeElement(struct {
+			Items []entity.Subscription `xml:"subscription"`
+		}{v}, xml.StartElement{Name: xml.Name{Local: "subscriptions"}})
+	case *entity.APIKey:
+		return enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "api_key"}})
+	case []entity.APIKey:
+		return enc.EncodeElement(struct {
+			Items []entity.APIKey `xml:"api_key"`
+		}{v}, xml.StartElement{Name: xml.Name{Local: "api_keys"}})
+	case []entity.DeadLetter:
+		return enc.EncodeElement(struct {
+			Items []entity.DeadLetter `xml:"dead_letter"`
+		}{v}, xml.StartElement{Name: xml.Name{Local: "dead_letters"}})
+	case []entity.BatchItem:
+		return enc.EncodeElement(struct {
+			Items []entity.BatchItem `xml:"item"`
+		}{v}, xml.StartElement{Name: xml.Name{Local: "batch"}})
+	default:
+		return enc.Encode(v)
+	}
+}
+
+// Colunas do CSV de clima. A ordem é parte do contrato: novas colunas só entram no fim.
+var weatherCSVHeader = []string{
+	"city", "temp_C", "temp_F", "temp_K",
+	"heat_index_C", "heat_index_F", "heat_index_K",
+	"wind_chill_C", "wind_chill_F", "wind_chill_K",
+	"dew_point_C", "dew_point_F", "dew_point_K",
+	"humidex_C", "humidex_F", "humidex_K",
+	"apparent_temperature_C", "apparent_temperature_F", "apparent_temperature_K",
+}
+
+// Colunas do CSV da v2: as da v1 precedidas do CEP e seguidas das condições e metadados.
+var weatherV2CSVHeader = append(append([]string{"cep"}, weatherCSVHeader...), "humidity", "wind_kph", "provider", "retrieved_at")
+
+var problemCSVHeader = []string{"type", "title", "status", "detail", "instance", "code", "request_id"}
+
+func csvRecords(v any) ([]string, [][]string, error) {
+	switch v := v.(type) {
+	case *entity.WeatherOutput:
+		return weatherCSVHeader, [][]string{weatherCSVRow(v)}, nil
+	case *entity.WeatherOutputV2:
+		row := append([]string{v.Location.CEP}, weatherCSVRow(v1View(v))...)
+		row = append(row, formatNumber(v.Conditions.Humidity), formatNumber(v.Conditions.WindKph), v.Metadata.Provider, v.Metadata.RetrievedAt.Format(time.RFC3339))
+		return weatherV2CSVHeader, [][]string{row}, nil
+	case []entity.BatchItem:
+		header := append([]string{"cep", "status", "error_code", "error_detail"}, weatherCSVHeader...)
+		rows := make([][]string, len(v))
+		for i, item := range v {
+			if item.Error != nil {
+				rows[i] = append([]string{item.CEP, strconv.Itoa(item.Error.Status), item.Error.Code, item.Error.Detail}, make([]string, len(weatherCSVHeader))...)
+				continue
+			}
+			rows[i] = append([]string{item.CEP, strconv.Itoa(http.StatusOK), "", ""}, weatherCSVRow(item.Result)...)
+		}
+		return header, rows, nil
+	case *entity.Problem:
+		return problemCSVHeader, [][]string{{v.Type, v.Title, strconv.Itoa(v.Status), v.Detail, v.Instance, v.Code, v.RequestID}}, nil
+	default:
+		return nil, nil, errNotRenderable
+	}
+}
+
+func weatherCSVRow(o *entity.WeatherOutput) []string {
+	row := []string{o.City, formatNumber(o.TempC), formatNumber(o.TempF), formatNumber(o.TempK)}
+	if o.Indices == nil {
+		return append(row, make([]string, len(weatherCSVHeader)-len(row))...)
+	}
+	for _, t := range []entity.Temperature{o.Indices.HeatIndex, o.Indices.WindChill, o.Indices.DewPoint, o.Indices.Humidex, o.Indices.ApparentTemperature} {
+		row = append(row, formatNumber(t.C), formatNumber(t.F), formatNumber(t.K))
+	}
+	return row
+}
+
+// v1View expõe uma resposta da v2 no formato da v1, para reaproveitar o CSV e o texto.
+func v1View(v *entity.WeatherOutputV2) *entity.WeatherOutput {
+	return &entity.WeatherOutput{
+		City:    v.Location.City,
+		TempC:   v.Temperature.C,
+		TempF:   v.Temperature.F,
+		TempK:   v.Temperature.K,
+		Indices: v.Indices,
+	}
+}
+
+// formatNumber usa a mesma representação curta do encoding/json.
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func textLines(v any) (string, error) {
+	switch v := v.(type) {
+	case *entity.WeatherOutput:
+		return weatherText(v) + "\n", nil
+	case *entity.WeatherOutputV2:
+		return weatherText(v1View(v)) + "\n", nil
+	case []entity.BatchItem:
+		var b strings.Builder
+		for _, item := range v {
+			if item.Error != nil {
+				fmt.Fprintf(&b, "%s: error %d %s: %s\n", item.CEP, item.Error.Status, item.Error.Code, item.Error.Detail)
+				continue
+			}
+			fmt.Fprintf(&b, "%s: %s\n", item.CEP, weatherText(item.Result))
+		}
+		return b.String(), nil
+	case *entity.Problem:
+		line := fmt.Sprintf("%d %s (%s)", v.Status, v.Title, v.Code)
+		if v.Detail != "" {
+			line += ": " + v.Detail
+		}
+		return line + "\n", nil
+	default:
+		return "", errNotRenderable
+	}
+}
+
+// weatherText monta a linha legível "São Paulo: 25.0 °C / 77.0 °F / 298.2 K",
+// sempre com uma casa decimal.
+func weatherText(o *entity.WeatherOutput) string {
+	return fmt.Sprintf("%s: %s °C / %s °F / %s K", o.City, oneDecimal(o.TempC), oneDecimal(o.TempF), oneDecimal(o.TempK))
+}
+
+// oneDecimal arredonda sobre a representação decimal (298.15 -> "298.2"); %.1f
+// arredondaria o binário 298.1499... para "298.1".
+func oneDecimal(v float64) string {
+	return strconv.FormatFloat(service.Round(v, 1, service.RoundHalfUp), 'f', 1, 64)
+}
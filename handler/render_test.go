@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		accept   string
+		expected Format
+		err      error
+	}{
+		{"Default", "/", "", FormatJSON, nil},
+		{"Wildcard", "/", "*/*", FormatJSON, nil},
+		{"XML", "/", "application/xml", FormatXML, nil},
+		{"Problem XML", "/", "application/problem+xml", FormatXML, nil},
+		{"CSV", "/", "text/csv", FormatCSV, nil},
+		{"YAML", "/", "application/x-yaml", FormatYAML, nil},
+		{"Text", "/", "text/plain; charset=utf-8", FormatText, nil},
+		{"Browser", "/", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", FormatXML, nil},
+		{"Q Values", "/", "application/json;q=0.5, text/csv", FormatCSV, nil},
+		{"Zero Q", "/", "text/csv;q=0, application/yaml", FormatYAML, nil},
+		{"Query Wins", "/?format=yaml", "application/xml", FormatYAML, nil},
+		{"Query Case Insensitive", "/?format=TEXT", "", FormatText, nil},
+		{"Unknown Query", "/?format=pdf", "", "", ErrUnknownFormat},
+		{"Nothing Acceptable", "/", "image/png", "", ErrNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			format, err := NegotiateFormat(req)
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+}
+
+func TestWeatherHandler_GetWeatherByCEP_Formats(t *testing.T) {
+	setupRouter := func() *chi.Mux {
+		mockLocation := new(mocks.LocationFinder)
+		mockWeather := new(mocks.WeatherFinder)
+		mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+		mockLocation.On("GetLocationByCEP", mock.Anything, "123").Return("", service.ErrInvalidCEPFormat)
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 25}, nil)
+		h := NewWeatherHandler(mockLocation, mockWeather, service.NewStandardTemperatureConverter())
+		r := chi.NewRouter()
+		r.Get("/weather/{cep}", h.GetWeatherByCEP)
+		return r
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"JSON", "/weather/01001000", "", http.StatusOK, "application/json",
+			`{"city":"São Paulo","temp_C":25,"temp_F":77,"temp_K":298.15}` + "\n"},
+		{"XML", "/weather/01001000", "application/xml", http.StatusOK, "application/xml",
+			"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<weather>\n  <city>São Paulo</city>\n  <temp_C>25</temp_C>\n  <temp_F>77</temp_F>\n  <temp_K>298.15</temp_K>\n</weather>\n"},
+		{"YAML", "/weather/01001000?format=yaml", "", http.StatusOK, "application/yaml",
+			"city: São Paulo\ntemp_C: 25\ntemp_F: 77\ntemp_K: 298.15\n"},
+		{"CSV", "/weather/01001000", "text/csv", http.StatusOK, "text/csv; charset=utf-8",
+			"city,temp_C,temp_F,temp_K,heat_index_C,heat_index_F,heat_index_K,wind_chill_C,wind_chill_F,wind_chill_K,dew_point_C,dew_point_F,dew_point_K,humidex_C,humidex_F,humidex_K,apparent_temperature_C,apparent_temperature_F,apparent_temperature_K\n" +
+				"São Paulo,25,77,298.15,,,,,,,,,,,,,,,\n"},
+		{"Text", "/weather/01001000", "text/plain", http.StatusOK, "text/plain; charset=utf-8",
+			"São Paulo: 25.0 °C / 77.0 °F / 298.2 K\n"},
+		{"Problem XML", "/weather/123", "application/xml", http.StatusUnprocessableEntity, "application/problem+xml",
+			"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<problem xmlns=\"urn:ietf:rfc:7807\">\n  <type>https://github.com/MchlAlex/fc-lab02/blob/main/docs/errors.md#invalid_zipcode</type>\n  <title>Invalid zipcode</title>\n  <status>422</status>\n  <detail>invalid zipcode</detail>\n  <instance>/weather/123</instance>\n  <code>invalid_zipcode</code>\n</problem>\n"},
+		{"Problem Text", "/weather/123?format=text", "", http.StatusUnprocessableEntity, "text/plain; charset=utf-8",
+			"422 Invalid zipcode (invalid_zipcode): invalid zipcode\n"},
+		{"Problem CSV", "/weather/123?format=csv", "", http.StatusUnprocessableEntity, "text/csv; charset=utf-8",
+			"type,title,status,detail,instance,code,request_id\nhttps://github.com/MchlAlex/fc-lab02/blob/main/docs/errors.md#invalid_zipcode,Invalid zipcode,422,invalid zipcode,/weather/123,invalid_zipcode,\n"},
+		{"Problem YAML", "/weather/123?format=yaml", "", http.StatusUnprocessableEntity, "application/yaml",
+			"type: https://github.com/MchlAlex/fc-lab02/blob/main/docs/errors.md#invalid_zipcode\ntitle: Invalid zipcode\nstatus: 422\ndetail: invalid zipcode\ninstance: /weather/123\ncode: invalid_zipcode\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			setupRouter().ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Equal(t, tt.contentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", rr.Header().Get("Vary"))
+			assert.Equal(t, tt.body, rr.Body.String())
+		})
+	}
+
+	t.Run("Not Acceptable Skips Upstream", func(t *testing.T) {
+		mockLocation := new(mocks.LocationFinder)
+		h := NewWeatherHandler(mockLocation, new(mocks.WeatherFinder), service.NewStandardTemperatureConverter())
+		r := chi.NewRouter()
+		r.Get("/weather/{cep}", h.GetWeatherByCEP)
+
+		req := httptest.NewRequest("GET", "/weather/01001000", nil)
+		req.Header.Set("Accept", "image/png")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+		assert.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Body.String(), `"code":"not_acceptable"`)
+		mockLocation.AssertNotCalled(t, "GetLocationByCEP", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid Format", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		setupRouter().ServeHTTP(rr, httptest.NewRequest("GET", "/weather/01001000?format=pdf", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"code":"invalid_format"`)
+	})
+}
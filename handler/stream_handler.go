@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/coder/websocket"
+	"github.com/go-chi/chi/v5"
+)
+
+// DefaultStreamHeartbeat é o intervalo padrão entre heartbeats dos streams.
+const DefaultStreamHeartbeat = 15 * time.Second
+
+// sseRetry é o intervalo de reconexão sugerido ao EventSource (campo retry).
+const sseRetry = 5 * time.Second
+
+// StreamHandler contém as dependências para as rotas de streaming (SSE e WebSocket).
+type StreamHandler struct {
+	LocationService service.LocationFinder
+	Poller          *service.WeatherPoller
+	Converter       service.TemperatureConverter
+	Heartbeat       time.Duration
+	OriginPatterns  []string // Origens aceitas no WebSocket além da própria (ex: "*.example.com")
+	Logger          *slog.Logger
+}
+
+// NewStreamHandler cria uma nova instância de StreamHandler.
+func NewStreamHandler(loc service.LocationFinder, poller *service.WeatherPoller, conv service.TemperatureConverter, heartbeat time.Duration, originPatterns []string) *StreamHandler {
+	if heartbeat <= 0 {
+		heartbeat = DefaultStreamHeartbeat
+	}
+	return &StreamHandler{
+		LocationService: loc,
+		Poller:          poller,
+		Converter:       conv,
+		Heartbeat:       heartbeat,
+		OriginPatterns:  originPatterns,
+		Logger:          slog.Default(),
+	}
+}
+
+// streamPayload monta o corpo de cada leitura no formato da versão da rota.
+type streamPayload func(r *http.Request, cep string, reading service.Reading, conv service.TemperatureConverter) any
+
+func payloadV1(_ *http.Request, _ string, reading service.Reading, conv service.TemperatureConverter) any {
+	return weatherOutputV1(reading.City, reading.Weather, conv)
+}
+
+func payloadV2(r *http.Request, cep string, reading service.Reading, conv service.TemperatureConverter) any {
+	location := entity.Location{CEP: cep, City: reading.City, Region: reading.Weather.Region, Country: "BR"}
+	return weatherOutputV2(r, location, reading.Weather, conv, reading.At)
+}
+
+// StreamWeatherByCEP é o handler para GET /v1/weather/{cep}/stream (Server-Sent Events).
+func (h *StreamHandler) StreamWeatherByCEP(w http.ResponseWriter, r *http.Request) {
+	h.serveSSE(w, r, payloadV1)
+}
+
+// StreamWeatherByCEPV2 é o handler para GET /v2/weather/{cep}/stream (Server-Sent Events).
+func (h *StreamHandler) StreamWeatherByCEPV2(w http.ResponseWriter, r *http.Request) {
+	h.serveSSE(w, r, payloadV2)
+}
+
+// WatchWeatherByCEP é o handler para GET /v1/weather/{cep}/ws (WebSocket).
+func (h *StreamHandler) WatchWeatherByCEP(w http.ResponseWriter, r *http.Request) {
+	h.serveWebSocket(w, r, payloadV1)
+}
+
+// WatchWeatherByCEPV2 é o handler para GET /v2/weather/{cep}/ws (WebSocket).
+func (h *StreamHandler) WatchWeatherByCEPV2(w http.ResponseWriter, r *http.Request) {
+	h.serveWebSocket(w, r, payloadV2)
+}
+
+// subscribe valida a requisição, resolve a cidade e assina o poller. Em caso de
+// falha já escreve o problem+json (sempre em JSON) e retorna ok=false.
+func (h *StreamHandler) subscribe(w http.ResponseWriter, r *http.Request) (string, service.TemperatureConverter, *service.Subscription, bool) {
+	errReq := jsonRequest(r)
+
+	converter, err := converterForRequest(h.Converter, r)
+	if err != nil {
+		WriteProblem(w, errReq, apperr.CodeInvalidPrecision, err.Error())
+		return "", nil, nil, false
+	}
+
+	cep := chi.URLParam(r, "cep")
+	if cep == "" {
+		WriteProblem(w, errReq, apperr.CodeMissingZipcode, "CEP parameter is missing")
+		return "", nil, nil, false
+	}
+
+	logging.Add(r.Context(), slog.String("cep", cep))
+	city, err := h.LocationService.GetLocationByCEP(r.Context(), cep)
+	if err != nil {
+		h.Logger.WarnContext(r.Context(), "error finding location", "error", err)
+		writeError(w, errReq, err, apperr.CodeLocationLookupFailed, "error while fetching location")
+		return "", nil, nil, false
+	}
+
+	logging.Add(r.Context(), slog.String("city", city))
+	sub, err := h.Poller.Subscribe(city)
+	if err != nil {
+		h.Logger.WarnContext(r.Context(), "rejecting stream", "error", err)
+		writeError(w, errReq, err, apperr.CodeInternalError, "")
+		return "", nil, nil, false
+	}
+	return cep, converter, sub, true
+}
+
+// serveSSE envia cada leitura como um evento com id (para Last-Event-ID) e as
+// falhas do provedor como eventos "problem". Comentários servem de heartbeat.
+func (h *StreamHandler) serveSSE(w http.ResponseWriter, r *http.Request, payload streamPayload) {
+	rc := http.NewResponseController(w)
+	cep, converter, sub, ok := h.subscribe(w, r)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	// Streams não têm prazo de escrita; o servidor pode ter WriteTimeout global
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Desativa o buffer de proxies como o nginx
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	if err := rc.Flush(); err != nil {
+		h.Logger.ErrorContext(r.Context(), "streaming unsupported", "error", err)
+		return
+	}
+
+	lastID := parseLastEventID(r.Header.Get("Last-Event-ID"))
+	failing := false // Último evento enviado foi um problem
+	heartbeat := time.NewTicker(h.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.Done:
+			return // O EventSource reconecta, em outra instância
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case reading := <-sub.C:
+			if reading.Err != nil {
+				data, _ := json.Marshal(readingProblem(r, reading))
+				fmt.Fprintf(w, "event: problem\ndata: %s\n\n", data)
+				failing = true
+				break
+			}
+			if skipReading(reading, lastID, failing) {
+				continue // O cliente já recebeu esta leitura antes de reconectar
+			}
+			lastID, failing = reading.ID, false
+			data, _ := json.Marshal(payload(r, cep, reading, converter))
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", reading.ID, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// StreamMessage é uma mensagem do WebSocket de clima.
+type StreamMessage struct {
+	Type string `json:"type"`         // "weather" ou "problem"
+	ID   int64  `json:"id,omitempty"` // ID da leitura, aceito em ?last_event_id= na reconexão
+	Data any    `json:"data"`
+}
+
+// serveWebSocket envia as leituras como mensagens JSON (StreamMessage). O
+// heartbeat é um ping; clientes que não respondem são desconectados.
+func (h *StreamHandler) serveWebSocket(w http.ResponseWriter, r *http.Request, payload streamPayload) {
+	cep, converter, sub, ok := h.subscribe(w, r)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: h.OriginPatterns})
+	if err != nil {
+		h.Logger.WarnContext(r.Context(), "websocket handshake failed", "error", err)
+		return
+	}
+	defer conn.CloseNow()
+
+	// O cliente não envia mensagens; CloseRead trata os frames de controle e
+	// cancela o contexto quando a conexão é encerrada
+	ctx := conn.CloseRead(r.Context())
+	lastID := parseLastEventID(r.URL.Query().Get("last_event_id"))
+	failing := false
+	heartbeat := time.NewTicker(h.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var msg StreamMessage
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Done:
+			conn.Close(websocket.StatusGoingAway, "server shutting down")
+			return
+		case <-heartbeat.C:
+			pingCtx, cancel := context.WithTimeout(ctx, h.Heartbeat)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return
+			}
+			continue
+		case reading := <-sub.C:
+			if reading.Err != nil {
+				msg = StreamMessage{Type: "problem", Data: readingProblem(r, reading)}
+				failing = true
+				break
+			}
+			if skipReading(reading, lastID, failing) {
+				continue
+			}
+			lastID, failing = reading.ID, false
+			msg = StreamMessage{Type: "weather", ID: reading.ID, Data: payload(r, cep, reading, converter)}
+		}
+
+		data, _ := json.Marshal(msg)
+		writeCtx, cancel := context.WithTimeout(ctx, h.Heartbeat)
+		err := conn.Write(writeCtx, websocket.MessageText, data)
+		cancel()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// skipReading diz se o cliente já tem a leitura. Depois de um problem, a
+// leitura de mesmo ID que o poller reenvia na recuperação passa, para limpar o erro.
+func skipReading(reading service.Reading, lastID int64, failing bool) bool {
+	return reading.ID < lastID || (reading.ID == lastID && !failing)
+}
+
+// readingProblem descreve a falha de uma consulta do poller com o mesmo
+// problem+json que /weather/{cep} retornaria.
+func readingProblem(r *http.Request, reading service.Reading) *entity.Problem {
+	return NewProblem(r, apperr.CodeFor(reading.Err, apperr.CodeWeatherLookupFailed), apperr.Detail(reading.Err, "error while fetching weather data"))
+}
+
+func parseLastEventID(raw string) int64 {
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id < 0 {
+		return 0
+	}
+	return id
+}
+
+// jsonRequest devolve uma cópia de r que negocia JSON, para as respostas de
+// erro de rotas cujo Accept não é um formato da API (ex: text/event-stream).
+func jsonRequest(r *http.Request) *http.Request {
+	r2 := r.Clone(r.Context())
+	r2.Header.Set("Accept", ProblemContentType)
+	q := r2.URL.Query()
+	q.Del("format")
+	r2.URL.RawQuery = q.Encode()
+	return r2
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/coder/websocket"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newStreamServer(t *testing.T, maxSubscribers int) *httptest.Server {
+	t.Helper()
+	mockLocation := new(mocks.LocationFinder)
+	mockWeather := new(mocks.WeatherFinder)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "123").Return("", service.ErrInvalidCEPFormat)
+	mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 25, Humidity: 60, WindKph: 10}, nil)
+
+	poller := service.NewWeatherPoller(mockWeather, time.Hour, maxSubscribers)
+	h := NewStreamHandler(mockLocation, poller, service.NewStandardTemperatureConverter(), 20*time.Millisecond, nil)
+	r := chi.NewRouter()
+	r.Get("/v1/weather/{cep}/stream", h.StreamWeatherByCEP)
+	r.Get("/v2/weather/{cep}/ws", h.WatchWeatherByCEPV2)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// readEvent lê o próximo bloco do stream SSE (linhas até a linha em branco).
+func readEvent(t *testing.T, r *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func openSSE(t *testing.T, url, lastEventID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp, bufio.NewReader(resp.Body)
+}
+
+func TestStreamHandler_SSE(t *testing.T) {
+	srv := newStreamServer(t, 10)
+
+	resp, body := openSSE(t, srv.URL+"/v1/weather/01001000/stream", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, []string{"retry: 5000"}, readEvent(t, body))
+
+	event := readEvent(t, body)
+	require.Len(t, event, 2)
+	assert.True(t, strings.HasPrefix(event[0], "id: "))
+	var out entity.WeatherOutput
+	assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(event[1], "data: ")), &out))
+	assert.Equal(t, "São Paulo", out.City)
+	assert.Equal(t, 25.0, out.TempC)
+	assert.Equal(t, []string{": heartbeat"}, readEvent(t, body))
+
+	t.Run("Reconnect Skips Readings Already Received", func(t *testing.T) {
+		_, body := openSSE(t, srv.URL+"/v1/weather/01001000/stream", strings.TrimPrefix(event[0], "id: "))
+		readEvent(t, body) // retry
+		assert.Equal(t, []string{": heartbeat"}, readEvent(t, body))
+	})
+
+	t.Run("Errors Before Streaming Are Problems", func(t *testing.T) {
+		resp, _ := openSSE(t, srv.URL+"/v1/weather/123/stream", "")
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Equal(t, ProblemContentType, resp.Header.Get("Content-Type"))
+	})
+}
+
+func TestStreamHandler_ErrorThenRecovery(t *testing.T) {
+	newServer := func(t *testing.T) *httptest.Server {
+		t.Helper()
+		mockLocation := new(mocks.LocationFinder)
+		mockWeather := new(mocks.WeatherFinder)
+		weather := &entity.CurrentWeather{TempC: 25, Humidity: 60, WindKph: 10}
+		mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(weather, nil).Once()
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(nil, service.ErrUpstreamUnavailable).Once()
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(weather, nil) // Mesma observação, mesmo ID
+
+		poller := service.NewWeatherPoller(mockWeather, 30*time.Millisecond, 10)
+		h := NewStreamHandler(mockLocation, poller, service.NewStandardTemperatureConverter(), time.Hour, nil)
+		r := chi.NewRouter()
+		r.Get("/v1/weather/{cep}/stream", h.StreamWeatherByCEP)
+		r.Get("/v2/weather/{cep}/ws", h.WatchWeatherByCEPV2)
+		srv := httptest.NewServer(r)
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	t.Run("SSE", func(t *testing.T) {
+		resp, body := openSSE(t, newServer(t).URL+"/v1/weather/01001000/stream", "")
+		// Sem a leitura esperada, o teste falha em vez de esperar pelo heartbeat
+		time.AfterFunc(2*time.Second, func() { resp.Body.Close() })
+		readEvent(t, body) // retry
+
+		first := readEvent(t, body)
+		require.Len(t, first, 2)
+		problem := readEvent(t, body)
+		require.Len(t, problem, 2)
+		assert.Equal(t, "event: problem", problem[0])
+		assert.Equal(t, first, readEvent(t, body), "the recovery reading clears the error")
+	})
+
+	t.Run("WebSocket", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(newServer(t).URL, "http")+"/v2/weather/01001000/ws", nil)
+		require.NoError(t, err)
+		defer conn.CloseNow()
+
+		var types []string
+		var ids []int64
+		for range 3 {
+			_, data, err := conn.Read(ctx)
+			require.NoError(t, err)
+			var msg StreamMessage
+			require.NoError(t, json.Unmarshal(data, &msg))
+			types, ids = append(types, msg.Type), append(ids, msg.ID)
+		}
+		assert.Equal(t, []string{"weather", "problem", "weather"}, types)
+		assert.Equal(t, ids[0], ids[2])
+	})
+}
+
+func TestStreamHandler_SubscriberLimit(t *testing.T) {
+	srv := newStreamServer(t, 1)
+
+	_, body := openSSE(t, srv.URL+"/v1/weather/01001000/stream", "")
+	readEvent(t, body)
+
+	resp, _ := openSSE(t, srv.URL+"/v1/weather/01001000/stream", "")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+	var problem entity.Problem
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, "stream_limit_reached", problem.Code)
+}
+
+func TestStreamHandler_WebSocket(t *testing.T) {
+	srv := newStreamServer(t, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"/v2/weather/01001000/ws", nil)
+	require.NoError(t, err)
+	defer conn.CloseNow()
+
+	_, data, err := conn.Read(ctx)
+	require.NoError(t, err)
+	var msg struct {
+		Type string                 `json:"type"`
+		ID   int64                  `json:"id"`
+		Data entity.WeatherOutputV2 `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &msg))
+	assert.Equal(t, "weather", msg.Type)
+	assert.Positive(t, msg.ID)
+	assert.Equal(t, "01001000", msg.Data.Location.CEP)
+	assert.Equal(t, 25.0, msg.Data.Temperature.C)
+
+	// O heartbeat (ping) é respondido pelo cliente enquanto ele lê
+	readCtx, cancelRead := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancelRead()
+	_, _, err = conn.Read(readCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
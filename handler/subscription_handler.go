@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxSubscriptionBody limita o corpo de POST /v2/subscriptions.
+const maxSubscriptionBody = 4 << 10
+
+// SubscriptionHandler contém as dependências para as rotas de assinaturas (webhooks).
+type SubscriptionHandler struct {
+	LocationService service.LocationFinder
+	Store           service.SubscriptionStore
+	Owner           func(r *http.Request) string // ID da chave de API da requisição; nil trata todas como anônimas
+	Logger          *slog.Logger
+}
+
+// NewSubscriptionHandler cria uma nova instância de SubscriptionHandler.
+func NewSubscriptionHandler(loc service.LocationFinder, store service.SubscriptionStore) *SubscriptionHandler {
+	return &SubscriptionHandler{LocationService: loc, Store: store, Logger: slog.Default()}
+}
+
+// CreateSubscription é o handler para POST /v2/subscriptions. A resposta é a
+// única que traz o segredo usado para conferir a assinatura dos avisos.
+func (h *SubscriptionHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	if !checkFormat(w, r) {
+		return
+	}
+
+	// 1. Ler e validar o pedido
+	var in entity.SubscriptionInput
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubscriptionBody)).Decode(&in); err != nil {
+		WriteProblem(w, r, apperr.CodeInvalidRequestBody, "request body must be a JSON object with cep, condition and callback_url")
+		return
+	}
+	if in.CEP == "" {
+		WriteProblem(w, r, apperr.CodeMissingZipcode, "cep is required")
+		return
+	}
+	cond, err := service.ParseCondition(in.Condition)
+	if err != nil {
+		WriteProblem(w, r, apperr.CodeInvalidCondition, err.Error())
+		return
+	}
+	if err := service.ValidateCallbackURL(in.CallbackURL); err != nil {
+		WriteProblem(w, r, apperr.CodeInvalidCallbackURL, err.Error())
+		return
+	}
+
+	// 2. Confirmar que o CEP existe, para não agendar consultas que sempre falham
+	logging.Add(r.Context(), slog.String("cep", in.CEP))
+	city, err := h.LocationService.GetLocationByCEP(r.Context(), in.CEP)
+	if err != nil {
+		h.Logger.WarnContext(r.Context(), "error finding location", "error", err)
+		writeError(w, r, err, apperr.CodeLocationLookupFailed, "error while fetching location")
+		return
+	}
+
+	// 3. Gravar a assinatura
+	sub := entity.Subscription{
+		ID:          service.NewSubscriptionID(),
+		APIKeyID:    h.owner(r),
+		CEP:         in.CEP,
+		City:        city,
+		Condition:   cond.String(),
+		CallbackURL: in.CallbackURL,
+		Secret:      service.NewSubscriptionSecret(),
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := h.Store.CreateSubscription(r.Context(), sub); err != nil {
+		h.Logger.ErrorContext(r.Context(), "error saving subscription", "error", err)
+		WriteProblem(w, r, apperr.CodeInternalError, "error while saving subscription")
+		return
+	}
+
+	logging.Add(r.Context(), slog.String("city", city), slog.String("subscription_id", sub.ID))
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+sub.ID)
+	render(w, r, http.StatusCreated, &sub)
+}
+
+// ListSubscriptions é o handler para GET /v2/subscriptions. Cada chave de API
+// vê só as próprias assinaturas.
+func (h *SubscriptionHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if !checkFormat(w, r) {
+		return
+	}
+	all, err := h.Store.ListSubscriptions(r.Context())
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "error listing subscriptions", "error", err)
+		WriteProblem(w, r, apperr.CodeInternalError, "error while listing subscriptions")
+		return
+	}
+	owner := h.owner(r)
+	subs := make([]entity.Subscription, 0, len(all))
+	for _, sub := range all {
+		if sub.APIKeyID == owner {
+			sub.Secret = ""
+			subs = append(subs, sub)
+		}
+	}
+	render(w, r, http.StatusOK, subs)
+}
+
+// GetSubscription é o handler para GET /v2/subscriptions/{id}.
+func (h *SubscriptionHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	if !checkFormat(w, r) {
+		return
+	}
+	sub, err := h.find(r, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err, apperr.CodeInternalError, "error while fetching subscription")
+		return
+	}
+	sub.Secret = ""
+	render(w, r, http.StatusOK, sub)
+}
+
+// UpdateSubscription é o handler para PATCH /v2/subscriptions/{id}. Mudar o
+// CEP ou a condição zera o estado da última verificação, para que o novo
+// limite seja avisado assim que for cruzado.
+func (h *SubscriptionHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	if !checkFormat(w, r) {
+		return
+	}
+	sub, err := h.find(r, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, err, apperr.CodeInternalError, "error while fetching subscription")
+		return
+	}
+
+	// 1. Ler e validar as alterações
+	var in entity.SubscriptionUpdate
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubscriptionBody)).Decode(&in); err != nil ||
+		(in.CEP == "" && in.Condition == "" && in.CallbackURL == "") {
+		WriteProblem(w, r, apperr.CodeInvalidRequestBody, "request body must be a JSON object with cep, condition or callback_url")
+		return
+	}
+	reset := false
+	if in.Condition != "" {
+		cond, err := service.ParseCondition(in.Condition)
+		if err != nil {
+			WriteProblem(w, r, apperr.CodeInvalidCondition, err.Error())
+			return
+		}
+		reset = reset || cond.String() != sub.Condition
+		sub.Condition = cond.String()
+	}
+	if in.CallbackURL != "" {
+		if err := service.ValidateCallbackURL(in.CallbackURL); err != nil {
+			WriteProblem(w, r, apperr.CodeInvalidCallbackURL, err.Error())
+			return
+		}
+		sub.CallbackURL = in.CallbackURL
+	}
+
+	// 2. Um CEP novo precisa existir, como na criação
+	if in.CEP != "" && in.CEP != sub.CEP {
+		logging.Add(r.Context(), slog.String("cep", in.CEP))
+		city, err := h.LocationService.GetLocationByCEP(r.Context(), in.CEP)
+		if err != nil {
+			h.Logger.WarnContext(r.Context(), "error finding location", "error", err)
+			writeError(w, r, err, apperr.CodeLocationLookupFailed, "error while fetching location")
+			return
+		}
+		sub.CEP, sub.City, reset = in.CEP, city, true
+	}
+	if reset {
+		sub.Matching, sub.LastCheckedAt = false, nil
+	}
+
+	// 3. Gravar
+	if err := h.Store.UpdateSubscription(r.Context(), *sub); err != nil {
+		writeError(w, r, err, apperr.CodeInternalError, "error while saving subscription")
+		return
+	}
+	logging.Add(r.Context(), slog.String("subscription_id", sub.ID))
+	sub.Secret = ""
+	render(w, r, http.StatusOK, sub)
+}
+
+// DeleteSubscription é o handler para DELETE /v2/subscriptions/{id}.
+func (h *SubscriptionHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.find(r, id); err != nil {
+		writeError(w, r, err, apperr.CodeInternalError, "error while deleting subscription")
+		return
+	}
+	if err := h.Store.DeleteSubscription(r.Context(), id); err != nil {
+		writeError(w, r, err, apperr.CodeInternalError, "error while deleting subscription")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeadLetters é o handler para GET /v2/subscriptions/dead-letters: os
+// avisos que não foram entregues após todas as tentativas, das assinaturas da
+// chave de API da requisição.
+func (h *SubscriptionHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if !checkFormat(w, r) {
+		return
+	}
+	all, err := h.Store.ListDeadLetters(r.Context())
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "error listing dead letters", "error", err)
+		WriteProblem(w, r, apperr.CodeInternalError, "error while listing dead letters")
+		return
+	}
+	owner := h.owner(r)
+	dls := make([]entity.DeadLetter, 0, len(all))
+	for _, dl := range all {
+		if dl.APIKeyID == owner {
+			dls = append(dls, dl)
+		}
+	}
+	render(w, r, http.StatusOK, dls)
+}
+
+// owner retorna o ID da chave de API da requisição, vazio sem chave.
+func (h *SubscriptionHandler) owner(r *http.Request) string {
+	if h.Owner == nil {
+		return ""
+	}
+	return h.Owner(r)
+}
+
+// find busca a assinatura da chave de API da requisição. As de outras chaves
+// respondem como inexistentes, para não revelar quais IDs existem.
+func (h *SubscriptionHandler) find(r *http.Request, id string) (*entity.Subscription, error) {
+	sub, err := h.Store.GetSubscription(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.APIKeyID != h.owner(r) {
+		return nil, service.ErrSubscriptionNotFound
+	}
+	return sub, nil
+}
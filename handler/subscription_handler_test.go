@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionHandler(t *testing.T) {
+	mockLocation := new(mocks.LocationFinder)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "99999999").Return("", service.ErrCEPNotFound)
+	store, err := service.NewFileSubscriptionStore("")
+	require.NoError(t, err)
+
+	h := NewSubscriptionHandler(mockLocation, store)
+	r := chi.NewRouter()
+	r.Post("/v2/subscriptions", h.CreateSubscription)
+	r.Get("/v2/subscriptions", h.ListSubscriptions)
+	r.Get("/v2/subscriptions/dead-letters", h.ListDeadLetters)
+	r.Get("/v2/subscriptions/{id}", h.GetSubscription)
+	r.Patch("/v2/subscriptions/{id}", h.UpdateSubscription)
+	r.Delete("/v2/subscriptions/{id}", h.DeleteSubscription)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := do("POST", "/v2/subscriptions", `{"cep":"01001000","condition":"temp_c>35","callback_url":"https://example.com/hook"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created entity.Subscription
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "/v2/subscriptions/"+created.ID, rr.Header().Get("Location"))
+	assert.Equal(t, "São Paulo", created.City)
+	assert.Equal(t, "temp_C > 35", created.Condition, "condition is normalized")
+	assert.True(t, strings.HasPrefix(created.Secret, "whsec_"))
+
+	t.Run("Secret Is Only Shown On Creation", func(t *testing.T) {
+		rr := do("GET", "/v2/subscriptions/"+created.ID, "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "secret")
+
+		rr = do("GET", "/v2/subscriptions", "")
+		var subs []entity.Subscription
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &subs))
+		assert.Len(t, subs, 1)
+		assert.Empty(t, subs[0].Secret)
+
+		stored, _ := store.GetSubscription(context.Background(), created.ID)
+		assert.Equal(t, created.Secret, stored.Secret)
+	})
+
+	t.Run("Invalid Requests", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			body   string
+			status int
+			code   string
+		}{
+			{"Malformed Body", `[]`, http.StatusBadRequest, "invalid_request_body"},
+			{"Missing CEP", `{"condition":"> 35","callback_url":"https://example.com"}`, http.StatusBadRequest, "missing_zipcode"},
+			{"Invalid Condition", `{"cep":"01001000","condition":"hot","callback_url":"https://example.com"}`, http.StatusUnprocessableEntity, "invalid_condition"},
+			{"Invalid Callback", `{"cep":"01001000","condition":"> 35","callback_url":"ftp://example.com"}`, http.StatusUnprocessableEntity, "invalid_callback_url"},
+			{"Unknown CEP", `{"cep":"99999999","condition":"> 35","callback_url":"https://example.com"}`, http.StatusNotFound, "zipcode_not_found"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rr := do("POST", "/v2/subscriptions", tt.body)
+				assert.Equal(t, tt.status, rr.Code)
+				var problem entity.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, tt.code, problem.Code)
+			})
+		}
+	})
+
+	t.Run("Dead Letters", func(t *testing.T) {
+		store.AddDeadLetter(context.Background(), entity.DeadLetter{Event: entity.SubscriptionEvent{ID: "evt", SubscriptionID: created.ID}, Attempts: 5})
+		rr := do("GET", "/v2/subscriptions/dead-letters", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var dls []entity.DeadLetter
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &dls))
+		assert.Len(t, dls, 1)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		require.NoError(t, store.UpdateSubscriptionState(context.Background(), created.ID, true, created.CreatedAt))
+
+		rr := do("PATCH", "/v2/subscriptions/"+created.ID, `{"callback_url":"https://example.com/other"}`)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var updated entity.Subscription
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &updated))
+		assert.Equal(t, "https://example.com/other", updated.CallbackURL)
+		assert.Equal(t, "temp_C > 35", updated.Condition)
+		assert.True(t, updated.Matching, "a new callback keeps the state")
+		assert.Empty(t, updated.Secret)
+
+		rr = do("PATCH", "/v2/subscriptions/"+created.ID, `{"condition":"< 5"}`)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		updated = entity.Subscription{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &updated))
+		assert.Equal(t, "temp_C < 5", updated.Condition)
+		assert.False(t, updated.Matching, "a new condition is checked from scratch")
+		assert.Nil(t, updated.LastCheckedAt)
+
+		stored, _ := store.GetSubscription(context.Background(), created.ID)
+		assert.Equal(t, created.Secret, stored.Secret, "the secret does not change")
+		assert.Equal(t, "temp_C < 5", stored.Condition)
+
+		tests := []struct {
+			name, target, body string
+			status             int
+			code               string
+		}{
+			{"Empty Body", created.ID, `{}`, http.StatusBadRequest, "invalid_request_body"},
+			{"Invalid Condition", created.ID, `{"condition":"hot"}`, http.StatusUnprocessableEntity, "invalid_condition"},
+			{"Invalid Callback", created.ID, `{"callback_url":"http://127.0.0.1/hook"}`, http.StatusUnprocessableEntity, "invalid_callback_url"},
+			{"Unknown CEP", created.ID, `{"cep":"99999999"}`, http.StatusNotFound, "zipcode_not_found"},
+			{"Unknown Subscription", "missing", `{"condition":"< 5"}`, http.StatusNotFound, "subscription_not_found"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rr := do("PATCH", "/v2/subscriptions/"+tt.target, tt.body)
+				assert.Equal(t, tt.status, rr.Code)
+				var problem entity.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, tt.code, problem.Code)
+			})
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, do("DELETE", "/v2/subscriptions/"+created.ID, "").Code)
+
+		rr := do("DELETE", "/v2/subscriptions/"+created.ID, "")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		var problem entity.Problem
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, "subscription_not_found", problem.Code)
+		assert.Equal(t, http.StatusNotFound, do("GET", "/v2/subscriptions/"+created.ID, "").Code)
+	})
+}
+
+func TestSubscriptionHandler_Ownership(t *testing.T) {
+	mockLocation := new(mocks.LocationFinder)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+	store, err := service.NewFileSubscriptionStore("")
+	require.NoError(t, err)
+
+	h := NewSubscriptionHandler(mockLocation, store)
+	h.Owner = func(r *http.Request) string { return r.Header.Get("X-API-Key") }
+	r := chi.NewRouter()
+	r.Post("/v2/subscriptions", h.CreateSubscription)
+	r.Get("/v2/subscriptions", h.ListSubscriptions)
+	r.Get("/v2/subscriptions/dead-letters", h.ListDeadLetters)
+	r.Get("/v2/subscriptions/{id}", h.GetSubscription)
+	r.Patch("/v2/subscriptions/{id}", h.UpdateSubscription)
+	r.Delete("/v2/subscriptions/{id}", h.DeleteSubscription)
+
+	do := func(key, method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	list := func(key string) []entity.Subscription {
+		var subs []entity.Subscription
+		require.NoError(t, json.Unmarshal(do(key, "GET", "/v2/subscriptions", "").Body.Bytes(), &subs))
+		return subs
+	}
+
+	rr := do("k1", "POST", "/v2/subscriptions", `{"cep":"01001000","condition":"> 35","callback_url":"https://example.com/hook"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created entity.Subscription
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, "k1", created.APIKeyID)
+	require.NoError(t, store.AddDeadLetter(context.Background(), entity.DeadLetter{Event: entity.SubscriptionEvent{ID: "evt", SubscriptionID: created.ID}, APIKeyID: "k1"}))
+
+	assert.Len(t, list("k1"), 1)
+	for _, other := range []string{"k2", ""} {
+		assert.Empty(t, list(other))
+		assert.Equal(t, http.StatusNotFound, do(other, "GET", "/v2/subscriptions/"+created.ID, "").Code)
+		assert.Equal(t, http.StatusNotFound, do(other, "PATCH", "/v2/subscriptions/"+created.ID, `{"condition":"< 5"}`).Code)
+		assert.Equal(t, http.StatusNotFound, do(other, "DELETE", "/v2/subscriptions/"+created.ID, "").Code)
+		assert.JSONEq(t, `[]`, do(other, "GET", "/v2/subscriptions/dead-letters", "").Body.String())
+	}
+
+	_, err = store.GetSubscription(context.Background(), created.ID)
+	assert.NoError(t, err, "other keys must not delete the subscription")
+	assert.Equal(t, http.StatusNoContent, do("k1", "DELETE", "/v2/subscriptions/"+created.ID, "").Code)
+}
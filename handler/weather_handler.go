@@ -1,15 +1,17 @@
 package handler
 
 import (
-	"encoding/json"
-	"errors"
-	"log"
+	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
 	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
 	"github.com/MchlAlex/fc-lab02/internal/service"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 // WeatherHandler contém as dependências para o handler de clima.
@@ -17,6 +19,9 @@ type WeatherHandler struct {
 	LocationService service.LocationFinder
 	WeatherService  service.WeatherFinder
 	Converter       service.TemperatureConverter
+	ClientIP        *ClientIPResolver // Proxies confiáveis para /weather/me; nil usa só o endereço da conexão
+	CacheMaxAge     time.Duration     // Teto do Cache-Control: max-age; zero responde no-cache
+	Logger          *slog.Logger
 }
 
 // NewWeatherHandler cria uma nova instância de WeatherHandler.
@@ -25,56 +30,248 @@ func NewWeatherHandler(loc service.LocationFinder, weather service.WeatherFinder
 		LocationService: loc,
 		WeatherService:  weather,
 		Converter:       conv,
+		CacheMaxAge:     DefaultCacheMaxAge,
+		Logger:          slog.Default(),
 	}
 }
 
-// GetWeatherByCEP é o handler para a rota GET /weather/{cep}.
+// GetWeatherByCEP é o handler para as rotas GET /v1/weather/{cep} e GET /weather/{cep}.
 func (h *WeatherHandler) GetWeatherByCEP(w http.ResponseWriter, r *http.Request) {
+	h.respondV1(w, r, h.locateCEP, false)
+}
+
+// GetWeatherByCEPV2 é o handler para a rota GET /v2/weather/{cep}, com
+// temperatura, local e metadados em objetos separados.
+func (h *WeatherHandler) GetWeatherByCEPV2(w http.ResponseWriter, r *http.Request) {
+	h.respondV2(w, r, h.locateCEP, false)
+}
+
+// GetWeatherByCity é o handler para GET /v1/weather/city/{name}?uf=.
+func (h *WeatherHandler) GetWeatherByCity(w http.ResponseWriter, r *http.Request) {
+	h.respondV1(w, r, h.locateCity, false)
+}
+
+// GetWeatherByCityV2 é o handler para GET /v2/weather/city/{name}?uf=.
+func (h *WeatherHandler) GetWeatherByCityV2(w http.ResponseWriter, r *http.Request) {
+	h.respondV2(w, r, h.locateCity, false)
+}
+
+// GetWeatherByCoords é o handler para GET /v1/weather/coords?lat=&lon=.
+func (h *WeatherHandler) GetWeatherByCoords(w http.ResponseWriter, r *http.Request) {
+	h.respondV1(w, r, h.locateCoords, false)
+}
+
+// GetWeatherByCoordsV2 é o handler para GET /v2/weather/coords?lat=&lon=.
+func (h *WeatherHandler) GetWeatherByCoordsV2(w http.ResponseWriter, r *http.Request) {
+	h.respondV2(w, r, h.locateCoords, false)
+}
+
+// GetWeatherByClientIP é o handler para GET /v1/weather/me, que localiza o
+// cliente pelo IP.
+func (h *WeatherHandler) GetWeatherByClientIP(w http.ResponseWriter, r *http.Request) {
+	h.respondV1(w, r, h.locateClientIP, true)
+}
+
+// GetWeatherByClientIPV2 é o handler para GET /v2/weather/me.
+func (h *WeatherHandler) GetWeatherByClientIPV2(w http.ResponseWriter, r *http.Request) {
+	h.respondV2(w, r, h.locateClientIP, true)
+}
+
+// respondV1 e respondV2 respondem com a consulta de locate. perClient indica
+// que o local depende de quem pergunta (ex: /weather/me), o que torna a
+// resposta privada para os caches.
+func (h *WeatherHandler) respondV1(w http.ResponseWriter, r *http.Request, locate locator, perClient bool) {
+	res, ok := h.lookup(w, r, locate)
+	if !ok {
+		return
+	}
+
+	// 3. Converter temperaturas, calcular os índices térmicos e incluir a cidade
+	finalResponse := weatherOutputV1(res.location.City, res.weather, res.converter)
+
+	// 4. Responder com sucesso no formato negociado (JSON por padrão), com os headers de cache
+	renderCached(w, r, finalResponse, h.cacheable(r, res, nil, perClient)) // 200 ✅ Envia o struct completo com "city" (ou 304)
+}
+
+func (h *WeatherHandler) respondV2(w http.ResponseWriter, r *http.Request, locate locator, perClient bool) {
+	res, ok := h.lookup(w, r, locate)
+	if !ok {
+		return
+	}
+
+	out := weatherOutputV2(r, res.location, res.weather, res.converter, res.retrievedAt)
+	// Os metadados mudam a cada requisição e ficam fora do ETag
+	tagged := *out
+	tagged.Metadata = entity.Metadata{}
+	renderCached(w, r, out, h.cacheable(r, res, &tagged, perClient))
+}
+
+// cacheable monta a política de cache da resposta a partir da observação
+// consultada. Respostas a requisições com chave de API são privadas, para que
+// um cache compartilhado não as entregue a outro cliente sem autenticação.
+func (h *WeatherHandler) cacheable(r *http.Request, res lookupResult, tagged any, perClient bool) cacheable {
+	private := perClient || r.Header.Get("X-API-Key") != "" || r.Header.Get("Authorization") != ""
+	return cacheable{observedAt: res.weather.ObservedAt, maxAge: h.CacheMaxAge, tagged: tagged, private: private}
+}
+
+// lookupResult guarda o que todas as rotas de clima têm em comum.
+type lookupResult struct {
+	location    entity.Location
+	weather     *entity.CurrentWeather
+	converter   service.TemperatureConverter
+	retrievedAt time.Time
+}
+
+// locator resolve o local pedido e consulta o clima. Em caso de falha já
+// escreve o problem+json e retorna ok=false.
+type locator func(w http.ResponseWriter, r *http.Request) (entity.Location, *entity.CurrentWeather, bool)
+
+// lookup valida a negociação e a precisão e delega a consulta a locate, de modo
+// que CEP, cidade, coordenadas e IP passem pelo mesmo fluxo de resposta.
+func (h *WeatherHandler) lookup(w http.ResponseWriter, r *http.Request, locate locator) (lookupResult, bool) {
+	if !checkFormat(w, r) {
+		return lookupResult{}, false
+	}
+
+	// 0. Aplicar a precisão pedida na requisição, se houver
+	converter, err := converterForRequest(h.Converter, r)
+	if err != nil {
+		WriteProblem(w, r, apperr.CodeInvalidPrecision, err.Error()) // 400
+		return lookupResult{}, false
+	}
+
+	location, weather, ok := locate(w, r)
+	if !ok {
+		return lookupResult{}, false
+	}
+	return lookupResult{location: location, weather: weather, converter: converter, retrievedAt: time.Now().UTC()}, true
+}
+
+// locateCEP resolve o CEP na ViaCEP e consulta o clima da cidade.
+func (h *WeatherHandler) locateCEP(w http.ResponseWriter, r *http.Request) (entity.Location, *entity.CurrentWeather, bool) {
 	cep := chi.URLParam(r, "cep")
 	if cep == "" {
-		http.Error(w, "CEP parameter is missing", http.StatusBadRequest)
-		return
+		WriteProblem(w, r, apperr.CodeMissingZipcode, "CEP parameter is missing") // 400
+		return entity.Location{}, nil, false
 	}
 
 	// 1. Buscar localização pelo CEP
-	city, err := h.LocationService.GetLocationByCEP(cep)
+	logging.Add(r.Context(), slog.String("cep", cep))
+	city, err := h.LocationService.GetLocationByCEP(r.Context(), cep)
 	if err != nil {
-		log.Printf("Error finding location for CEP %s: %v", cep, err)
-		if errors.Is(err, service.ErrInvalidCEPFormat) {
-			w.WriteHeader(http.StatusUnprocessableEntity) // 422
-			json.NewEncoder(w).Encode(entity.ErrorResponse{Message: "invalid zipcode"})
-			return
-		}
-		if errors.Is(err, service.ErrCEPNotFound) {
-			w.WriteHeader(http.StatusNotFound) // 404
-			json.NewEncoder(w).Encode(entity.ErrorResponse{Message: "can not find zipcode"})
-			return
-		}
-		// Outros erros (falha na API ViaCEP, etc.)
-		http.Error(w, "Internal server error while fetching location", http.StatusInternalServerError)
-		return
+		h.Logger.WarnContext(r.Context(), "error finding location", "error", err)
+		// 422 para formato inválido, 404 para CEP inexistente, 429/502/503/504 para falhas do provedor
+		writeError(w, r, err, apperr.CodeLocationLookupFailed, "error while fetching location")
+		return entity.Location{}, nil, false
 	}
 
 	// 2. Buscar clima pela cidade
-	tempC, err := h.WeatherService.GetWeatherByCity(city)
+	logging.Add(r.Context(), slog.String("city", city))
+	weather, err := h.WeatherService.GetWeatherByCity(r.Context(), city)
 	if err != nil {
-		log.Printf("Error finding weather for city %s (from CEP %s): %v", city, cep, err)
-		// Tratar erros específicos da WeatherAPI se necessário, mas por padrão retorna 500
-		http.Error(w, "Internal server error while fetching weather data", http.StatusInternalServerError)
-		return
+		h.Logger.WarnContext(r.Context(), "error finding weather", "error", err)
+		// 404 se a WeatherAPI não conhece a cidade; 429/502/503/504 para falhas do provedor
+		writeError(w, r, err, apperr.CodeWeatherLookupFailed, "error while fetching weather data")
+		return entity.Location{}, nil, false
+	}
+
+	// A cidade da ViaCEP é mantida: é a que a /v1 sempre respondeu
+	return entity.Location{CEP: cep, City: city, Region: weather.Region, Country: "BR"}, weather, true
+}
+
+// locateCity consulta o clima de uma cidade brasileira pelo nome, com a UF opcional.
+func (h *WeatherHandler) locateCity(w http.ResponseWriter, r *http.Request) (entity.Location, *entity.CurrentWeather, bool) {
+	name := chi.URLParam(r, "name")
+	query, err := service.CityQuery(name, r.URL.Query().Get("uf"))
+	if err != nil {
+		writeError(w, r, err, apperr.CodeInvalidCityName, err.Error())
+		return entity.Location{}, nil, false
+	}
+	return h.locateQuery(w, r, query, name)
+}
+
+// locateCoords consulta o clima das coordenadas informadas em graus decimais.
+func (h *WeatherHandler) locateCoords(w http.ResponseWriter, r *http.Request) (entity.Location, *entity.CurrentWeather, bool) {
+	query, err := service.CoordinatesQuery(r.URL.Query().Get("lat"), r.URL.Query().Get("lon"))
+	if err != nil {
+		WriteProblem(w, r, apperr.CodeInvalidCoordinates, err.Error())
+		return entity.Location{}, nil, false
 	}
+	return h.locateQuery(w, r, query, query)
+}
 
-	// 3. Converter temperaturas e incluir a cidade
-	weatherOutput := h.Converter.ConvertTemperatures(tempC)
-	finalResponse := &entity.WeatherOutput{
-		City:  city, // ✅ Inclui a cidade
-		TempC: weatherOutput.TempC,
-		TempF: weatherOutput.TempF,
-		TempK: weatherOutput.TempK,
+// locateClientIP consulta o clima do local do IP do cliente. IPs privados ou
+// de loopback não têm localização e são recusados sem chamar a WeatherAPI.
+func (h *WeatherHandler) locateClientIP(w http.ResponseWriter, r *http.Request) (entity.Location, *entity.CurrentWeather, bool) {
+	ip := h.ClientIP.ClientIP(r)
+	if !ip.IsValid() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() || ip.IsMulticast() {
+		WriteProblem(w, r, apperr.CodeLocationUnknown, "client IP "+ip.String()+" cannot be geolocated")
+		return entity.Location{}, nil, false
 	}
+	return h.locateQuery(w, r, ip.String(), ip.String())
+}
 
-	// 4. Responder com sucesso
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)             // 200
-	json.NewEncoder(w).Encode(finalResponse) // ✅ Envia o struct completo com "city"
+// locateQuery consulta a WeatherAPI com uma consulta livre (cidade, "lat,lon"
+// ou IP) e descreve o local que ela resolveu. fallbackCity é usado quando a
+// WeatherAPI não informa o nome do local.
+func (h *WeatherHandler) locateQuery(w http.ResponseWriter, r *http.Request, query, fallbackCity string) (entity.Location, *entity.CurrentWeather, bool) {
+	logging.Add(r.Context(), slog.String("query", query))
+	weather, err := h.WeatherService.GetWeatherByCity(r.Context(), query)
+	if err != nil {
+		h.Logger.WarnContext(r.Context(), "error finding weather", "error", err)
+		writeError(w, r, err, apperr.CodeWeatherLookupFailed, "error while fetching weather data")
+		return entity.Location{}, nil, false
+	}
+	city := weather.Place
+	if city == "" {
+		city = fallbackCity
+	}
+	logging.Add(r.Context(), slog.String("city", city))
+	return entity.Location{City: city, Region: weather.Region, Country: service.CountryCode(weather.Country)}, weather, true
+}
+
+// weatherOutputV1 monta a resposta da /v1 (formato congelado).
+func weatherOutputV1(city string, weather *entity.CurrentWeather, conv service.TemperatureConverter) *entity.WeatherOutput {
+	out := conv.ConvertTemperatures(weather.TempC)
+	return &entity.WeatherOutput{
+		City:    city, // ✅ Inclui a cidade
+		TempC:   out.TempC,
+		TempF:   out.TempF,
+		TempK:   out.TempK,
+		Indices: conv.ThermalIndices(weather),
+	}
+}
+
+// weatherOutputV2 monta a resposta da /v2.
+func weatherOutputV2(r *http.Request, location entity.Location, weather *entity.CurrentWeather, conv service.TemperatureConverter, retrievedAt time.Time) *entity.WeatherOutputV2 {
+	temps := conv.ConvertTemperatures(weather.TempC)
+	return &entity.WeatherOutputV2{
+		Location:    location,
+		Temperature: entity.Temperature{C: temps.TempC, F: temps.TempF, K: temps.TempK},
+		Conditions:  entity.Conditions{Humidity: weather.Humidity, WindKph: weather.WindKph},
+		Indices:     conv.ThermalIndices(weather),
+		Metadata: entity.Metadata{
+			APIVersion:  "2",
+			Provider:    service.ProviderWeatherAPI,
+			RetrievedAt: retrievedAt.UTC(),
+			RequestID:   middleware.GetReqID(r.Context()),
+		},
+	}
+}
+
+// converterForRequest retorna o conversor a ser usado na requisição, aplicando o
+// parâmetro ?precision= quando presente e suportado pelo conversor.
+func converterForRequest(conv service.TemperatureConverter, r *http.Request) (service.TemperatureConverter, error) {
+	raw := r.URL.Query().Get("precision")
+	if raw == "" {
+		return conv, nil
+	}
+	digits, err := service.ParsePrecision(raw)
+	if err != nil {
+		return nil, err
+	}
+	if pc, ok := conv.(service.PrecisionConverter); ok {
+		return pc.WithPrecision(digits), nil
+	}
+	return conv, nil
 }
@@ -2,61 +2,29 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	// Ajuste o import path para o seu projeto, se necessário
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
 	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+	"github.com/MchlAlex/fc-lab02/internal/service"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-// MockLocationFinder é um mock para service.LocationFinder.
-type MockLocationFinder struct {
-	mock.Mock
-}
-
-func (m *MockLocationFinder) GetLocationByCEP(cep string) (string, error) {
-	args := m.Called(cep)
-	return args.String(0), args.Error(1)
-}
-
-// MockWeatherFinder é um mock para service.WeatherFinder.
-type MockWeatherFinder struct {
-	mock.Mock
-}
-
-func (m *MockWeatherFinder) GetWeatherByCity(city string) (float64, error) {
-	args := m.Called(city)
-	// Precisamos converter o primeiro argumento para float64
-	// Adiciona verificação para evitar panic se Get(0) não for float64
-	val, ok := args.Get(0).(float64)
-	if !ok {
-		// Se não for float64 (pode acontecer em cenários de erro onde o retorno é 0),
-		// retorne um valor padrão ou trate conforme necessário.
-		// Neste caso, retornar 0.0 é razoável se o erro for o foco.
-		return 0.0, args.Error(1)
-	}
-	return val, args.Error(1)
-}
-
-// MockTemperatureConverter é um mock para service.TemperatureConverter.
-type MockTemperatureConverter struct {
-	mock.Mock
-}
-
-func (m *MockTemperatureConverter) ConvertTemperatures(tempC float64) *entity.WeatherOutput {
-	args := m.Called(tempC)
-	// Retorna o ponteiro para WeatherOutput ou nil se não for encontrado
-	if output, ok := args.Get(0).(*entity.WeatherOutput); ok {
-		return output
-	}
-	return nil
-}
-
 func TestWeatherHandler_GetWeatherByCEP(t *testing.T) {
 	setupRouter := func(h *WeatherHandler) *chi.Mux {
 		r := chi.NewRouter()
@@ -65,15 +33,16 @@ func TestWeatherHandler_GetWeatherByCEP(t *testing.T) {
 	}
 
 	t.Run("Success", func(t *testing.T) {
-		mockLocation := new(MockLocationFinder)
-		mockWeather := new(MockWeatherFinder)
-		mockConverter := new(MockTemperatureConverter)
+		mockLocation := new(mocks.LocationFinder)
+		mockWeather := new(mocks.WeatherFinder)
+		mockConverter := new(mocks.TemperatureConverter)
 		handler := NewWeatherHandler(mockLocation, mockWeather, mockConverter)
 		r := setupRouter(handler)
 
 		cep := "01001000"
 		city := "São Paulo"
 		tempC := 25.0
+		weather := &entity.CurrentWeather{TempC: tempC, Humidity: 60, WindKph: 10}
 		// ✅ Inclui o campo "City" no expectedOutput
 		expectedOutput := &entity.WeatherOutput{
 			City:  city, // Novo campo
@@ -82,10 +51,11 @@ func TestWeatherHandler_GetWeatherByCEP(t *testing.T) {
 			TempK: 298.15,
 		}
 
-		mockLocation.On("GetLocationByCEP", cep).Return(city, nil).Once()
-		mockWeather.On("GetWeatherByCity", city).Return(tempC, nil).Once()
+		mockLocation.On("GetLocationByCEP", mock.Anything, cep).Return(city, nil).Once()
+		mockWeather.On("GetWeatherByCity", mock.Anything, city).Return(weather, nil).Once()
 		// ✅ O mockConverter deve retornar o expectedOutput completo
 		mockConverter.On("ConvertTemperatures", tempC).Return(expectedOutput).Once()
+		mockConverter.On("ThermalIndices", weather).Return(nil).Once()
 
 		req := httptest.NewRequest("GET", "/weather/"+cep, nil)
 		rr := httptest.NewRecorder()
@@ -109,3 +79,390 @@ func TestWeatherHandler_GetWeatherByCEP(t *testing.T) {
 		mockConverter.AssertExpectations(t)
 	})
 }
+
+func TestWeatherHandler_GetWeatherByCEPV2(t *testing.T) {
+	mockLocation := new(mocks.LocationFinder)
+	mockWeather := new(mocks.WeatherFinder)
+	handler := NewWeatherHandler(mockLocation, mockWeather, service.NewStandardTemperatureConverter())
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Get("/v2/weather/{cep}", handler.GetWeatherByCEPV2)
+
+	weather := &entity.CurrentWeather{TempC: 25, Humidity: 60, WindKph: 10}
+	mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil).Once()
+	mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(weather, nil).Once()
+
+	before := time.Now().UTC()
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/v2/weather/01001000", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var out entity.WeatherOutputV2
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &out))
+	assert.Equal(t, entity.Location{CEP: "01001000", City: "São Paulo", Country: "BR"}, out.Location)
+	assert.Equal(t, entity.Temperature{C: 25, F: 77, K: 298.15}, out.Temperature)
+	assert.Equal(t, entity.Conditions{Humidity: 60, WindKph: 10}, out.Conditions)
+	assert.NotNil(t, out.Indices)
+	assert.Equal(t, "2", out.Metadata.APIVersion)
+	assert.Equal(t, service.ProviderWeatherAPI, out.Metadata.Provider)
+	assert.WithinDuration(t, before, out.Metadata.RetrievedAt, time.Second)
+	assert.NotEmpty(t, out.Metadata.RequestID)
+
+	mockLocation.AssertExpectations(t)
+	mockWeather.AssertExpectations(t)
+}
+
+func TestWeatherHandler_GetWeatherByCEP_Precision(t *testing.T) {
+	setupRouter := func(h *WeatherHandler) *chi.Mux {
+		r := chi.NewRouter()
+		r.Get("/weather/{cep}", h.GetWeatherByCEP)
+		return r
+	}
+
+	t.Run("Precision Override", func(t *testing.T) {
+		mockLocation := new(mocks.LocationFinder)
+		mockWeather := new(mocks.WeatherFinder)
+		handler := NewWeatherHandler(mockLocation, mockWeather, service.NewStandardTemperatureConverter())
+		r := setupRouter(handler)
+
+		mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil).Once()
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 15.55}, nil).Once()
+
+		req := httptest.NewRequest("GET", "/weather/01001000?precision=3", nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"city":"São Paulo","temp_C":15.55,"temp_F":59.99,"temp_K":288.7}`, rr.Body.String())
+	})
+
+	t.Run("Invalid Precision", func(t *testing.T) {
+		mockLocation := new(mocks.LocationFinder)
+		mockWeather := new(mocks.WeatherFinder)
+		handler := NewWeatherHandler(mockLocation, mockWeather, service.NewStandardTemperatureConverter())
+		r := setupRouter(handler)
+
+		req := httptest.NewRequest("GET", "/weather/01001000?precision=42", nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var problem entity.Problem
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, "invalid_precision", problem.Code)
+		mockLocation.AssertNotCalled(t, "GetLocationByCEP", mock.Anything, "01001000")
+	})
+}
+
+func TestWeatherHandler_GetWeatherByCEP_Problems(t *testing.T) {
+	setupRouter := func(h *WeatherHandler) *chi.Mux {
+		r := chi.NewRouter()
+		r.Use(middleware.RequestID)
+		r.Get("/weather/{cep}", h.GetWeatherByCEP)
+		return r
+	}
+
+	tests := []struct {
+		name        string
+		locationErr error
+		weatherErr  error
+		status      int
+		code        string
+		detail      string
+	}{
+		{"Invalid Zipcode", service.ErrInvalidCEPFormat, nil, http.StatusUnprocessableEntity, "invalid_zipcode", "invalid zipcode"},
+		{"Zipcode Not Found", service.ErrCEPNotFound, nil, http.StatusNotFound, "zipcode_not_found", "can not find zipcode"},
+		{"Location Failure", errors.New("network error"), nil, http.StatusInternalServerError, "location_lookup_failed", "error while fetching location"},
+		{"Weather Failure", nil, service.ErrWeatherAPIFailure, http.StatusInternalServerError, "weather_lookup_failed", "error while fetching weather data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLocation := new(mocks.LocationFinder)
+			mockWeather := new(mocks.WeatherFinder)
+			handler := NewWeatherHandler(mockLocation, mockWeather, new(mocks.TemperatureConverter))
+			r := setupRouter(handler)
+
+			mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", tt.locationErr).Once()
+			mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(nil, tt.weatherErr).Maybe()
+
+			req := httptest.NewRequest("GET", "/weather/01001000", nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+
+			var problem entity.Problem
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, tt.detail, problem.Detail)
+			assert.Equal(t, ProblemTypeBaseURI+tt.code, problem.Type)
+			assert.Equal(t, "/weather/01001000", problem.Instance)
+			assert.NotEmpty(t, problem.RequestID)
+		})
+	}
+}
+
+// TestErrorCatalog_Documented garante que todo código do catálogo está documentado em docs/errors.md.
+func TestErrorCatalog_Documented(t *testing.T) {
+	doc, err := os.ReadFile("../docs/errors.md")
+	assert.NoError(t, err)
+
+	for _, pt := range apperr.Catalog() {
+		assert.Contains(t, string(doc), fmt.Sprintf("| `%s` | %d |", pt.Code, pt.Status), "catalog row for %s", pt.Code)
+		assert.Contains(t, string(doc), "\n### "+string(pt.Code)+"\n", "anchor for %s", pt.Code)
+	}
+}
+
+func TestErrorCatalog_FormatCodes(t *testing.T) {
+	tests := []struct {
+		code   apperr.Code
+		status int
+		title  string
+	}{
+		{apperr.CodeInvalidFormat, http.StatusBadRequest, "Invalid format"},
+		{apperr.CodeNotAcceptable, http.StatusNotAcceptable, "Not acceptable"},
+	}
+
+	req := httptest.NewRequest("GET", "/weather/01001000", nil)
+	for _, tt := range tests {
+		p := NewProblem(req, tt.code, "detail")
+		assert.Equal(t, tt.status, p.Status, tt.code)
+		assert.Equal(t, tt.title, p.Title, tt.code)
+		assert.Equal(t, string(tt.code), p.Code, tt.code)
+	}
+}
+
+func TestWeatherHandler_GetWeatherByCEP_UpstreamErrors(t *testing.T) {
+	setupRouter := func(h *WeatherHandler) *chi.Mux {
+		r := chi.NewRouter()
+		r.Get("/weather/{cep}", h.GetWeatherByCEP)
+		return r
+	}
+
+	upstream := func(provider string, kind error, retryAfter time.Duration) error {
+		return &service.UpstreamError{Provider: provider, Kind: kind, RetryAfter: retryAfter, Err: errors.New("upstream failure")}
+	}
+
+	tests := []struct {
+		name        string
+		locationErr error
+		weatherErr  error
+		status      int
+		code        string
+		retryAfter  string
+	}{
+		{"ViaCEP Timeout", upstream(service.ProviderViaCEP, service.ErrUpstreamTimeout, 0), nil, http.StatusGatewayTimeout, "upstream_timeout", ""},
+		{"ViaCEP Unavailable", upstream(service.ProviderViaCEP, service.ErrUpstreamUnavailable, 0), nil, http.StatusServiceUnavailable, "upstream_unavailable", "30"},
+		{"WeatherAPI Timeout", nil, upstream(service.ProviderWeatherAPI, service.ErrUpstreamTimeout, 0), http.StatusGatewayTimeout, "upstream_timeout", ""},
+		{"WeatherAPI Unavailable With Retry-After", nil, upstream(service.ProviderWeatherAPI, service.ErrUpstreamUnavailable, 1500*time.Millisecond), http.StatusServiceUnavailable, "upstream_unavailable", "2"},
+		{"WeatherAPI Rate Limited", nil, upstream(service.ProviderWeatherAPI, service.ErrUpstreamRateLimited, 0), http.StatusTooManyRequests, "upstream_rate_limited", "60"},
+		{"WeatherAPI Bad Key", nil, upstream(service.ProviderWeatherAPI, service.ErrUpstreamBadKey, 0), http.StatusBadGateway, "upstream_auth_failed", ""},
+		{"WeatherAPI Location Unknown", nil, upstream(service.ProviderWeatherAPI, service.ErrLocationUnknown, 0), http.StatusNotFound, "location_unknown", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLocation := new(mocks.LocationFinder)
+			mockWeather := new(mocks.WeatherFinder)
+			handler := NewWeatherHandler(mockLocation, mockWeather, new(mocks.TemperatureConverter))
+			r := setupRouter(handler)
+
+			mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", tt.locationErr).Once()
+			mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(nil, tt.weatherErr).Maybe()
+
+			req := httptest.NewRequest("GET", "/weather/01001000", nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Equal(t, tt.retryAfter, rr.Header().Get("Retry-After"))
+			var problem entity.Problem
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(t, tt.code, problem.Code)
+			assert.NotContains(t, problem.Detail, "upstream failure") // a causa original fica só nos logs
+		})
+	}
+}
+
+func TestWeatherHandler_OtherLookups(t *testing.T) {
+	mockWeather := new(mocks.WeatherFinder)
+	handler := NewWeatherHandler(new(mocks.LocationFinder), mockWeather, service.NewStandardTemperatureConverter())
+	handler.ClientIP, _ = NewClientIPResolver([]string{"10.0.0.0/8"})
+	r := chi.NewRouter()
+	r.Get("/v1/weather/city/{name}", handler.GetWeatherByCity)
+	r.Get("/v2/weather/city/{name}", handler.GetWeatherByCityV2)
+	r.Get("/v2/weather/coords", handler.GetWeatherByCoordsV2)
+	r.Get("/v2/weather/me", handler.GetWeatherByClientIPV2)
+
+	campinas := &entity.CurrentWeather{TempC: 28, Humidity: 70, WindKph: 5, Place: "Campinas", Region: "Sao Paulo", Country: "Brazil"}
+	mockWeather.On("GetWeatherByCity", mock.Anything, "Campinas, São Paulo, Brazil").Return(campinas, nil)
+	mockWeather.On("GetWeatherByCity", mock.Anything, "-22.9,-47.06").Return(campinas, nil)
+	mockWeather.On("GetWeatherByCity", mock.Anything, "203.0.113.7").Return(&entity.CurrentWeather{TempC: 12, Place: "Lisbon", Region: "Lisboa", Country: "Portugal"}, nil)
+	mockWeather.On("GetWeatherByCity", mock.Anything, "Atlantis, Brazil").Return(nil, &service.UpstreamError{
+		Provider: service.ProviderWeatherAPI, Kind: service.ErrLocationUnknown, Err: errors.New("no location"),
+	})
+
+	get := func(target, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		if remoteAddr != "" {
+			req.RemoteAddr = remoteAddr
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("City V1", func(t *testing.T) {
+		rr := get("/v1/weather/city/Campinas?uf=SP", "", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var out entity.WeatherOutput
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &out))
+		assert.Equal(t, "Campinas", out.City)
+		assert.Equal(t, 82.4, out.TempF)
+	})
+
+	t.Run("City V2", func(t *testing.T) {
+		rr := get("/v2/weather/city/Campinas?uf=sp", "", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var out entity.WeatherOutputV2
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &out))
+		assert.Equal(t, entity.Location{City: "Campinas", Region: "Sao Paulo", Country: "BR"}, out.Location)
+		assert.NotContains(t, rr.Body.String(), `"cep"`)
+	})
+
+	t.Run("Coordinates", func(t *testing.T) {
+		rr := get("/v2/weather/coords?lat=-22.90&lon=-47.06", "", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var out entity.WeatherOutputV2
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &out))
+		assert.Equal(t, "Campinas", out.Location.City)
+	})
+
+	t.Run("Client IP Through Trusted Proxy", func(t *testing.T) {
+		// O primeiro endereço é forjado pelo cliente; vale o último antes do proxy confiável
+		rr := get("/v2/weather/me", "10.0.0.5:443", http.Header{"X-Forwarded-For": {"1.2.3.4, 203.0.113.7"}})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var out entity.WeatherOutputV2
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &out))
+		assert.Equal(t, entity.Location{City: "Lisbon", Region: "Lisboa", Country: "Portugal"}, out.Location)
+	})
+
+	tests := []struct {
+		name   string
+		target string
+		remote string
+		header http.Header
+		status int
+		code   string
+	}{
+		{"Invalid State", "/v1/weather/city/Campinas?uf=XX", "", nil, http.StatusBadRequest, "invalid_state"},
+		{"Invalid City Name", "/v1/weather/city/Campinas,%20SP", "", nil, http.StatusUnprocessableEntity, "invalid_city_name"},
+		{"Unknown City", "/v1/weather/city/Atlantis", "", nil, http.StatusNotFound, "location_unknown"},
+		{"Missing Coordinates", "/v2/weather/coords?lat=-22.9", "", nil, http.StatusBadRequest, "invalid_coordinates"},
+		{"Out Of Range", "/v2/weather/coords?lat=-22.9&lon=181", "", nil, http.StatusBadRequest, "invalid_coordinates"},
+		{"Private Client IP", "/v2/weather/me", "192.168.0.10:5000", nil, http.StatusNotFound, "location_unknown"},
+		{"Untrusted Forwarded For", "/v2/weather/me", "127.0.0.1:5000", http.Header{"X-Forwarded-For": {"203.0.113.7"}}, http.StatusNotFound, "location_unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := get(tt.target, tt.remote, tt.header)
+			assert.Equal(t, tt.status, rr.Code)
+			var problem entity.Problem
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(t, tt.code, problem.Code)
+		})
+	}
+}
+
+func TestWeatherHandler_Caching(t *testing.T) {
+	mockLocation := new(mocks.LocationFinder)
+	mockWeather := new(mocks.WeatherFinder)
+	observedAt := time.Now().UTC().Add(-5 * time.Minute).Truncate(time.Second)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+	mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 25, Humidity: 60, WindKph: 10, ObservedAt: observedAt}, nil)
+
+	handler := NewWeatherHandler(mockLocation, mockWeather, service.NewStandardTemperatureConverter())
+	r := chi.NewRouter()
+	r.Get("/v1/weather/{cep}", handler.GetWeatherByCEP)
+	r.Get("/v2/weather/{cep}", handler.GetWeatherByCEPV2)
+
+	get := func(target string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := get("/v1/weather/01001000", nil)
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag, "strong ETag")
+	assert.Equal(t, observedAt.Format(http.TimeFormat), first.Header().Get("Last-Modified"))
+	maxAge, err := strconv.Atoi(strings.TrimPrefix(first.Header().Get("Cache-Control"), "public, max-age="))
+	require.NoError(t, err)
+	assert.InDelta(t, 600, maxAge, 2, "refresh interval minus the observation age")
+
+	t.Run("If-None-Match", func(t *testing.T) {
+		rr := get("/v1/weather/01001000", map[string]string{"If-None-Match": `"other", ` + etag})
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Body.String())
+		assert.Equal(t, etag, rr.Header().Get("ETag"))
+		assert.NotEmpty(t, rr.Header().Get("Cache-Control"))
+
+		assert.Equal(t, http.StatusOK, get("/v1/weather/01001000", map[string]string{"If-None-Match": `"other"`}).Code)
+		assert.NotEqual(t, etag, get("/v1/weather/01001000?format=xml", nil).Header().Get("ETag"), "ETag varies by representation")
+	})
+
+	t.Run("If-Modified-Since", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotModified, get("/v1/weather/01001000", map[string]string{"If-Modified-Since": observedAt.Format(http.TimeFormat)}).Code)
+		assert.Equal(t, http.StatusOK, get("/v1/weather/01001000", map[string]string{"If-Modified-Since": observedAt.Add(-time.Minute).Format(http.TimeFormat)}).Code)
+		assert.Equal(t, http.StatusOK, get("/v1/weather/01001000", map[string]string{
+			"If-Modified-Since": observedAt.Format(http.TimeFormat), "If-None-Match": `"other"`,
+		}).Code, "If-None-Match takes precedence")
+	})
+
+	t.Run("V2 Weak ETag", func(t *testing.T) {
+		rr := get("/v2/weather/01001000", nil)
+		etag := rr.Header().Get("ETag")
+		assert.True(t, strings.HasPrefix(etag, `W/"`), etag)
+		assert.Equal(t, etag, get("/v2/weather/01001000", nil).Header().Get("ETag"), "metadata does not change the ETag")
+		assert.Equal(t, http.StatusNotModified, get("/v2/weather/01001000", map[string]string{"If-None-Match": etag}).Code)
+	})
+
+	t.Run("Shared Caches", func(t *testing.T) {
+		assert.Equal(t, []string{"Accept", "Authorization, X-API-Key"}, first.Header().Values("Vary"))
+
+		for _, header := range []map[string]string{{"X-API-Key": "key"}, {"Authorization": "Bearer key"}} {
+			rr := get("/v1/weather/01001000", header)
+			assert.Regexp(t, `^private, max-age=\d+$`, rr.Header().Get("Cache-Control"))
+			assert.Equal(t, []string{"Accept"}, rr.Header().Values("Vary"))
+		}
+
+		mockWeather.On("GetWeatherByCity", mock.Anything, "203.0.113.7").Return(&entity.CurrentWeather{TempC: 25, Place: "Campinas", ObservedAt: observedAt}, nil)
+		r.Get("/v1/weather/me", handler.GetWeatherByClientIP)
+		req := httptest.NewRequest("GET", "/v1/weather/me", nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Regexp(t, `^private, max-age=\d+$`, rr.Header().Get("Cache-Control"), "the location depends on the client IP")
+	})
+}
+
+func TestCacheControl(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, "public, max-age=600", cacheControl(now.Add(-5*time.Minute), 15*time.Minute, now))
+	assert.Equal(t, "public, max-age=0", cacheControl(now.Add(-time.Hour), 15*time.Minute, now), "overdue observation")
+	assert.Equal(t, "public, max-age=900", cacheControl(now.Add(time.Minute), 15*time.Minute, now), "clock skew is capped")
+	assert.Equal(t, "public, max-age=0", cacheControl(time.Time{}, 15*time.Minute, now), "unknown observation")
+	assert.Equal(t, "no-cache", cacheControl(now, 0, now))
+}
@@ -0,0 +1,221 @@
+// Package apperr reúne o catálogo de códigos de erro da API (docs/errors.md) e
+// a tradução dos erros dos serviços para ele, compartilhados pelas rotas HTTP e
+// pelo servidor gRPC.
+package apperr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/service"
+)
+
+// Code é um código de erro estável e legível por máquina.
+// Códigos publicados não devem ser renomeados nem reaproveitados.
+type Code string
+
+const (
+	CodeMissingZipcode       Code = "missing_zipcode"
+	CodeInvalidZipcode       Code = "invalid_zipcode"
+	CodeZipcodeNotFound      Code = "zipcode_not_found"
+	CodeInvalidPrecision     Code = "invalid_precision"
+	CodeInvalidCityName      Code = "invalid_city_name"
+	CodeInvalidState         Code = "invalid_state"
+	CodeInvalidCoordinates   Code = "invalid_coordinates"
+	CodeInvalidRequestBody   Code = "invalid_request_body"
+	CodeInvalidFormat        Code = "invalid_format"
+	CodeNotAcceptable        Code = "not_acceptable"
+	CodeBatchTooLarge        Code = "batch_too_large"
+	CodeLocationLookupFailed Code = "location_lookup_failed"
+	CodeWeatherLookupFailed  Code = "weather_lookup_failed"
+	CodeTimeout              Code = "timeout"
+	CodeUpstreamTimeout      Code = "upstream_timeout"
+	CodeUpstreamUnavailable  Code = "upstream_unavailable"
+	CodeUpstreamRateLimited  Code = "upstream_rate_limited"
+	CodeUpstreamAuthFailed   Code = "upstream_auth_failed"
+	CodeLocationUnknown      Code = "location_unknown"
+	CodeStreamLimitReached   Code = "stream_limit_reached"
+	CodeRateLimitExceeded    Code = "rate_limit_exceeded"
+	CodeInvalidCondition     Code = "invalid_condition"
+	CodeInvalidCallbackURL   Code = "invalid_callback_url"
+	CodeSubscriptionNotFound Code = "subscription_not_found"
+	CodeMissingAPIKey        Code = "missing_api_key"
+	CodeInvalidAPIKey        Code = "invalid_api_key"
+	CodeInsufficientScope    Code = "insufficient_scope"
+	CodeClientCertRequired   Code = "client_cert_required"
+	CodeAPIKeyNotFound       Code = "api_key_not_found"
+	CodeInvalidScope         Code = "invalid_scope"
+	CodeInvalidQuota         Code = "invalid_quota"
+	CodeCacheNotFound        Code = "cache_not_found"
+	CodeBreakerNotFound      Code = "breaker_not_found"
+	CodeRouteNotFound        Code = "route_not_found"
+	CodeMethodNotAllowed     Code = "method_not_allowed"
+	CodeInternalError        Code = "internal_error"
+)
+
+// ProblemType descreve uma entrada do catálogo de erros.
+type ProblemType struct {
+	Code   Code
+	Status int // Status HTTP
+	Title  string
+}
+
+// catalog é a fonte única de status e título de cada código; docs/errors.md a documenta.
+var catalog = map[Code]ProblemType{
+	CodeMissingZipcode:       {CodeMissingZipcode, http.StatusBadRequest, "Zipcode is missing"},
+	CodeInvalidZipcode:       {CodeInvalidZipcode, http.StatusUnprocessableEntity, "Invalid zipcode"},
+	CodeZipcodeNotFound:      {CodeZipcodeNotFound, http.StatusNotFound, "Zipcode not found"},
+	CodeInvalidPrecision:     {CodeInvalidPrecision, http.StatusBadRequest, "Invalid precision"},
+	CodeInvalidCityName:      {CodeInvalidCityName, http.StatusUnprocessableEntity, "Invalid city name"},
+	CodeInvalidState:         {CodeInvalidState, http.StatusBadRequest, "Invalid state"},
+	CodeInvalidCoordinates:   {CodeInvalidCoordinates, http.StatusBadRequest, "Invalid coordinates"},
+	CodeInvalidRequestBody:   {CodeInvalidRequestBody, http.StatusBadRequest, "Invalid request body"},
+	CodeInvalidFormat:        {CodeInvalidFormat, http.StatusBadRequest, "Invalid format"},
+	CodeNotAcceptable:        {CodeNotAcceptable, http.StatusNotAcceptable, "Not acceptable"},
+	CodeBatchTooLarge:        {CodeBatchTooLarge, http.StatusRequestEntityTooLarge, "Batch too large"},
+	CodeLocationLookupFailed: {CodeLocationLookupFailed, http.StatusInternalServerError, "Location lookup failed"},
+	CodeWeatherLookupFailed:  {CodeWeatherLookupFailed, http.StatusInternalServerError, "Weather lookup failed"},
+	CodeTimeout:              {CodeTimeout, http.StatusGatewayTimeout, "Request timed out"},
+	CodeUpstreamTimeout:      {CodeUpstreamTimeout, http.StatusGatewayTimeout, "Upstream provider timed out"},
+	CodeUpstreamUnavailable:  {CodeUpstreamUnavailable, http.StatusServiceUnavailable, "Upstream provider unavailable"},
+	CodeUpstreamRateLimited:  {CodeUpstreamRateLimited, http.StatusTooManyRequests, "Upstream provider rate limit exceeded"},
+	CodeUpstreamAuthFailed:   {CodeUpstreamAuthFailed, http.StatusBadGateway, "Upstream provider rejected credentials"},
+	CodeLocationUnknown:      {CodeLocationUnknown, http.StatusNotFound, "Location unknown to weather provider"},
+	CodeStreamLimitReached:   {CodeStreamLimitReached, http.StatusServiceUnavailable, "Stream subscriber limit reached"},
+	CodeRateLimitExceeded:    {CodeRateLimitExceeded, http.StatusTooManyRequests, "Rate limit exceeded"},
+	CodeInvalidCondition:     {CodeInvalidCondition, http.StatusUnprocessableEntity, "Invalid condition"},
+	CodeInvalidCallbackURL:   {CodeInvalidCallbackURL, http.StatusUnprocessableEntity, "Invalid callback URL"},
+	CodeSubscriptionNotFound: {CodeSubscriptionNotFound, http.StatusNotFound, "Subscription not found"},
+	CodeMissingAPIKey:        {CodeMissingAPIKey, http.StatusUnauthorized, "API key is missing"},
+	CodeInvalidAPIKey:        {CodeInvalidAPIKey, http.StatusUnauthorized, "Invalid API key"},
+	CodeInsufficientScope:    {CodeInsufficientScope, http.StatusForbidden, "Insufficient scope"},
+	CodeClientCertRequired:   {CodeClientCertRequired, http.StatusUnauthorized, "Client certificate required"},
+	CodeAPIKeyNotFound:       {CodeAPIKeyNotFound, http.StatusNotFound, "API key not found"},
+	CodeInvalidScope:         {CodeInvalidScope, http.StatusUnprocessableEntity, "Invalid scope"},
+	CodeInvalidQuota:         {CodeInvalidQuota, http.StatusUnprocessableEntity, "Invalid quota"},
+	CodeCacheNotFound:        {CodeCacheNotFound, http.StatusNotFound, "Cache or cache entry not found"},
+	CodeBreakerNotFound:      {CodeBreakerNotFound, http.StatusNotFound, "Circuit breaker not found"},
+	CodeRouteNotFound:        {CodeRouteNotFound, http.StatusNotFound, "Route not found"},
+	CodeMethodNotAllowed:     {CodeMethodNotAllowed, http.StatusMethodNotAllowed, "Method not allowed"},
+	CodeInternalError:        {CodeInternalError, http.StatusInternalServerError, "Internal server error"},
+}
+
+// Catalog retorna todas as entradas do catálogo de erros.
+func Catalog() []ProblemType {
+	out := make([]ProblemType, 0, len(catalog))
+	for _, p := range catalog {
+		out = append(out, p)
+	}
+	return out
+}
+
+// Lookup retorna a entrada do catálogo para code; códigos desconhecidos viram
+// CodeInternalError.
+func Lookup(code Code) ProblemType {
+	if pt, ok := catalog[code]; ok {
+		return pt
+	}
+	return catalog[CodeInternalError]
+}
+
+// Error é uma falha que já traz o seu código do catálogo, para os erros que não
+// vêm dos serviços (ex: autenticação e limite por cliente) e valem tanto para
+// as rotas HTTP quanto para o gRPC.
+type Error struct {
+	Code       Code
+	Detail     string
+	RetryAfter time.Duration // Espera sugerida ao cliente, se houver
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// Esperas sugeridas ao cliente quando o provedor não informa a sua.
+const (
+	defaultRetryAfterUnavailable = 30 * time.Second
+	defaultRetryAfterRateLimited = 60 * time.Second
+)
+
+// CodeFor traduz um erro dos serviços em código do catálogo. fallback é usado
+// para falhas sem tratamento específico (ex: resposta inesperada da ViaCEP).
+func CodeFor(err error, fallback Code) Code {
+	var appErr *Error
+	switch {
+	case errors.As(err, &appErr):
+		return appErr.Code
+	case errors.Is(err, service.ErrInvalidCEPFormat):
+		return CodeInvalidZipcode
+	case errors.Is(err, service.ErrCEPNotFound):
+		return CodeZipcodeNotFound
+	case errors.Is(err, service.ErrLocationUnknown):
+		return CodeLocationUnknown
+	case errors.Is(err, service.ErrUpstreamTimeout):
+		return CodeUpstreamTimeout
+	case errors.Is(err, service.ErrUpstreamUnavailable):
+		return CodeUpstreamUnavailable
+	case errors.Is(err, service.ErrUpstreamRateLimited):
+		return CodeUpstreamRateLimited
+	case errors.Is(err, service.ErrUpstreamBadKey):
+		return CodeUpstreamAuthFailed
+	case errors.Is(err, service.ErrInvalidState):
+		return CodeInvalidState
+	case errors.Is(err, service.ErrInvalidCityName):
+		return CodeInvalidCityName
+	case errors.Is(err, service.ErrTooManySubscribers):
+		return CodeStreamLimitReached
+	case errors.Is(err, service.ErrSubscriptionNotFound):
+		return CodeSubscriptionNotFound
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		return CodeAPIKeyNotFound
+	case errors.Is(err, service.ErrInvalidScope):
+		return CodeInvalidScope
+	case errors.Is(err, service.ErrInvalidQuota):
+		return CodeInvalidQuota
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return CodeTimeout
+	default:
+		return fallback
+	}
+}
+
+// Detail mantém as mensagens históricas da API ("invalid zipcode",
+// "can not find zipcode") e identifica o provedor nas falhas externas, sem
+// expor a mensagem original (que pode conter URLs com a chave da API).
+func Detail(err error, fallback string) string {
+	var upErr *service.UpstreamError
+	var appErr *Error
+	switch {
+	case errors.As(err, &appErr):
+		return appErr.Detail
+	case errors.Is(err, service.ErrInvalidCEPFormat), errors.Is(err, service.ErrCEPNotFound), errors.Is(err, service.ErrSubscriptionNotFound),
+		errors.Is(err, service.ErrAPIKeyNotFound), errors.Is(err, service.ErrInvalidScope), errors.Is(err, service.ErrInvalidQuota):
+		return err.Error()
+	case errors.As(err, &upErr):
+		return upErr.Provider + ": " + upErr.Kind.Error()
+	default:
+		return fallback
+	}
+}
+
+// RetryAfter retorna a espera a sugerir ao cliente para o código informado.
+func RetryAfter(err error, code Code) time.Duration {
+	var upErr *service.UpstreamError
+	if errors.As(err, &upErr) && upErr.RetryAfter > 0 {
+		return upErr.RetryAfter
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) && appErr.RetryAfter > 0 {
+		return appErr.RetryAfter
+	}
+	switch code {
+	case CodeUpstreamUnavailable, CodeStreamLimitReached:
+		return defaultRetryAfterUnavailable
+	case CodeUpstreamRateLimited:
+		return defaultRetryAfterRateLimited
+	default:
+		return 0
+	}
+}
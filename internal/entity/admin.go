@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// CacheInfo é um cache de provedor em GET /caches, no listener de administração.
+type CacheInfo struct {
+	Name     string  `json:"name"` // "location" ou "weather"
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	Entries  int     `json:"entries"`
+	HitRatio float64 `json:"hit_ratio"` // hits / (hits + misses); 0 antes da primeira consulta
+}
+
+// BreakerInfo é o circuit breaker de um provedor em GET /breakers.
+type BreakerInfo struct {
+	Provider    string     `json:"provider"`
+	State       string     `json:"state"`    // "closed", "open" ou "half_open"
+	Failures    int        `json:"failures"` // Falhas consecutivas
+	OpenedAt    *time.Time `json:"opened_at,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+}
+
+// BuildInfo identifica o binário em execução (GET /buildinfo).
+type BuildInfo struct {
+	Version      string    `json:"version"`            // Versão do módulo; "(devel)" em builds locais
+	Revision     string    `json:"revision,omitempty"` // Commit do build, quando compilado a partir do git
+	RevisionTime string    `json:"revision_time,omitempty"`
+	Modified     bool      `json:"modified"` // Build com alterações não commitadas
+	GoVersion    string    `json:"go_version"`
+	StartedAt    time.Time `json:"started_at"`
+}
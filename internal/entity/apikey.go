@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// APIKeyInput é o corpo de POST /admin/keys.
+type APIKeyInput struct {
+	Name   string   `json:"name"`            // Consumidor dono da chave, usado nos logs
+	Scopes []string `json:"scopes"`          // Ex: ["weather:read"]; vazio concede só weather:read
+	Quota  string   `json:"quota,omitempty"` // "limite/janela[:rajada]" (ex: "600/1m:60"); vazio usa o tier "authenticated"
+}
+
+// APIKey é uma chave de API de um consumidor. O valor da chave só é guardado
+// como hash e só aparece em Key na criação e na rotação.
+type APIKey struct {
+	ID        string     `json:"id" xml:"id" yaml:"id"`
+	Name      string     `json:"name" xml:"name" yaml:"name"`
+	Prefix    string     `json:"prefix" xml:"prefix" yaml:"prefix"` // Início da chave, para identificá-la sem expô-la
+	Scopes    []string   `json:"scopes" xml:"scopes>scope" yaml:"scopes"`
+	Quota     string     `json:"quota,omitempty" xml:"quota,omitempty" yaml:"quota,omitempty"`
+	Key       string     `json:"key,omitempty" xml:"key,omitempty" yaml:"key,omitempty"`
+	CreatedAt time.Time  `json:"created_at" xml:"created_at" yaml:"created_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty" xml:"rotated_at,omitempty" yaml:"rotated_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" xml:"revoked_at,omitempty" yaml:"revoked_at,omitempty"` // Chaves revogadas são mantidas para auditoria
+}
@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// ReadinessReport é o corpo de GET /readyz.
+type ReadinessReport struct {
+	Status string                 `json:"status"` // "ready" ou "unready"
+	Checks map[string]CheckResult `json:"checks"` // Por dependência: config, viacep, weatherapi
+}
+
+// CheckResult é o resultado de uma verificação de readiness, possivelmente
+// reaproveitado de uma execução anterior (veja CheckedAt).
+type CheckResult struct {
+	Status    string            `json:"status"` // "pass" ou "fail"
+	Error     string            `json:"error,omitempty"`
+	Details   map[string]string `json:"details,omitempty"` // Ex: estado do circuit breaker e última resposta do provedor
+	CheckedAt time.Time         `json:"checked_at"`
+}
@@ -0,0 +1,57 @@
+package entity
+
+import "time"
+
+// SubscriptionInput é o corpo de POST /v2/subscriptions.
+type SubscriptionInput struct {
+	CEP         string `json:"cep"`
+	Condition   string `json:"condition"`    // Ex: "temp_C > 35" ou "< 5" (Celsius por padrão)
+	CallbackURL string `json:"callback_url"` // URL http(s) que recebe os avisos
+}
+
+// SubscriptionUpdate é o corpo de PATCH /v2/subscriptions/{id}. Os campos
+// omitidos mantêm o valor atual.
+type SubscriptionUpdate struct {
+	CEP         string `json:"cep,omitempty"`
+	Condition   string `json:"condition,omitempty"`
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// Subscription é um webhook avisado quando a temperatura da cidade de um CEP
+// cruza um limite. Secret assina os avisos e só é exibido na criação.
+// APIKeyID é a chave que a criou: só ela a enxerga, altera e remove.
+type Subscription struct {
+	ID            string     `json:"id" xml:"id" yaml:"id"`
+	APIKeyID      string     `json:"api_key_id,omitempty" xml:"api_key_id,omitempty" yaml:"api_key_id,omitempty"` // Vazio quando criada sem chave
+	CEP           string     `json:"cep" xml:"cep" yaml:"cep"`
+	City          string     `json:"city" xml:"city" yaml:"city"`
+	Condition     string     `json:"condition" xml:"condition" yaml:"condition"`
+	CallbackURL   string     `json:"callback_url" xml:"callback_url" yaml:"callback_url"`
+	Secret        string     `json:"secret,omitempty" xml:"secret,omitempty" yaml:"secret,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" xml:"created_at" yaml:"created_at"`
+	Matching      bool       `json:"matching" xml:"matching" yaml:"matching"`                                                    // A condição era verdadeira na última verificação
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty" xml:"last_checked_at,omitempty" yaml:"last_checked_at,omitempty"` // Última verificação bem-sucedida
+}
+
+// SubscriptionEvent é o corpo enviado ao callback quando a condição passa a ser verdadeira.
+type SubscriptionEvent struct {
+	ID             string      `json:"id" xml:"id" yaml:"id"`       // Único por aviso; repetido nas novas tentativas
+	Type           string      `json:"type" xml:"type" yaml:"type"` // "threshold.crossed"
+	SubscriptionID string      `json:"subscription_id" xml:"subscription_id" yaml:"subscription_id"`
+	CEP            string      `json:"cep" xml:"cep" yaml:"cep"`
+	City           string      `json:"city" xml:"city" yaml:"city"`
+	Condition      string      `json:"condition" xml:"condition" yaml:"condition"`
+	Value          float64     `json:"value" xml:"value" yaml:"value"` // Valor que satisfez a condição, na unidade dela
+	Temperature    Temperature `json:"temperature" xml:"temperature" yaml:"temperature"`
+	OccurredAt     time.Time   `json:"occurred_at" xml:"occurred_at" yaml:"occurred_at"`
+}
+
+// DeadLetter é um aviso que não foi entregue após todas as tentativas.
+type DeadLetter struct {
+	Event       SubscriptionEvent `json:"event" xml:"event" yaml:"event"`
+	APIKeyID    string            `json:"api_key_id,omitempty" xml:"api_key_id,omitempty" yaml:"api_key_id,omitempty"` // Chave dona da assinatura
+	CallbackURL string            `json:"callback_url" xml:"callback_url" yaml:"callback_url"`
+	Attempts    int               `json:"attempts" xml:"attempts" yaml:"attempts"`
+	LastError   string            `json:"last_error" xml:"last_error" yaml:"last_error"`
+	FailedAt    time.Time         `json:"failed_at" xml:"failed_at" yaml:"failed_at"`
+}
@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 // ViaCEPResponse representa a resposta da API ViaCEP.
 type ViaCEPResponse struct {
 	Localidade string `json:"localidade"` // Nome da cidade
@@ -8,20 +10,113 @@ type ViaCEPResponse struct {
 
 // WeatherAPIResponse representa a parte relevante da resposta da API WeatherAPI.
 type WeatherAPIResponse struct {
+	Location struct {
+		Name    string `json:"name"`    // Local resolvido (cidade)
+		Region  string `json:"region"`  // Estado ou região
+		Country string `json:"country"` // Nome do país (ex: "Brazil")
+	} `json:"location"`
 	Current struct {
-		TempC float64 `json:"temp_c"` // Temperatura em Celsius
+		LastUpdatedEpoch int64   `json:"last_updated_epoch"` // Momento da observação (Unix)
+		TempC            float64 `json:"temp_c"`             // Temperatura em Celsius
+		Humidity         float64 `json:"humidity"`           // Umidade relativa (%)
+		WindKph          float64 `json:"wind_kph"`           // Velocidade do vento (km/h)
 	} `json:"current"`
 }
 
-// WeatherOutput representa a resposta final da nossa API.
+// WeatherAPIErrorResponse representa o corpo de erro da WeatherAPI.
+type WeatherAPIErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`    // Código interno (ex: 1006 = localização não encontrada)
+		Message string `json:"message"` // Descrição do erro
+	} `json:"error"`
+}
+
+// CurrentWeather representa as condições atuais obtidas do provedor de clima.
+type CurrentWeather struct {
+	TempC    float64 // Temperatura em Celsius
+	Humidity float64 // Umidade relativa (%)
+	WindKph  float64 // Velocidade do vento (km/h)
+
+	// Momento da observação informado pelo provedor, em UTC; zero quando desconhecido
+	ObservedAt time.Time
+
+	// Local resolvido pelo provedor; vazio quando o provedor não informa
+	Place   string
+	Region  string
+	Country string
+}
+
+// Temperature representa uma temperatura expressa nas três escalas.
+type Temperature struct {
+	C float64 `json:"C" xml:"C" yaml:"C"` // Celsius
+	F float64 `json:"F" xml:"F" yaml:"F"` // Fahrenheit
+	K float64 `json:"K" xml:"K" yaml:"K"` // Kelvin
+}
+
+// ThermalIndices agrupa os índices térmicos derivados de temperatura, umidade e vento.
+type ThermalIndices struct {
+	HeatIndex           Temperature `json:"heat_index" xml:"heat_index" yaml:"heat_index"`                               // Índice de calor (NOAA)
+	WindChill           Temperature `json:"wind_chill" xml:"wind_chill" yaml:"wind_chill"`                               // Sensação térmica pelo vento (Environment Canada/NWS)
+	DewPoint            Temperature `json:"dew_point" xml:"dew_point" yaml:"dew_point"`                                  // Ponto de orvalho (Magnus)
+	Humidex             Temperature `json:"humidex" xml:"humidex" yaml:"humidex"`                                        // Humidex (Environment Canada)
+	ApparentTemperature Temperature `json:"apparent_temperature" xml:"apparent_temperature" yaml:"apparent_temperature"` // Sensação térmica combinada
+}
+
+// WeatherOutput representa a resposta de /v1/weather/{cep}.
+// O formato está congelado: campos novos entram apenas em WeatherOutputV2.
 type WeatherOutput struct {
-	City  string  `json:"city"`
-	TempC float64 `json:"temp_C"` // Temperatura em Celsius
-	TempF float64 `json:"temp_F"` // Temperatura em Fahrenheit
-	TempK float64 `json:"temp_K"` // Temperatura em Kelvin
+	City    string          `json:"city" xml:"city" yaml:"city"`
+	TempC   float64         `json:"temp_C" xml:"temp_C" yaml:"temp_C"`                                  // Temperatura em Celsius
+	TempF   float64         `json:"temp_F" xml:"temp_F" yaml:"temp_F"`                                  // Temperatura em Fahrenheit
+	TempK   float64         `json:"temp_K" xml:"temp_K" yaml:"temp_K"`                                  // Temperatura em Kelvin
+	Indices *ThermalIndices `json:"indices,omitempty" xml:"indices,omitempty" yaml:"indices,omitempty"` // Índices térmicos, quando há umidade disponível
+}
+
+// WeatherOutputV2 representa a resposta de /v2/weather/{cep}.
+type WeatherOutputV2 struct {
+	Location    Location        `json:"location" xml:"location" yaml:"location"`
+	Temperature Temperature     `json:"temperature" xml:"temperature" yaml:"temperature"`
+	Conditions  Conditions      `json:"conditions" xml:"conditions" yaml:"conditions"`
+	Indices     *ThermalIndices `json:"indices,omitempty" xml:"indices,omitempty" yaml:"indices,omitempty"` // Índices térmicos, quando há umidade disponível
+	Metadata    Metadata        `json:"metadata" xml:"metadata" yaml:"metadata"`
+}
+
+// Location identifica o local consultado.
+type Location struct {
+	CEP     string `json:"cep,omitempty" xml:"cep,omitempty" yaml:"cep,omitempty"` // Só nas consultas por CEP
+	City    string `json:"city" xml:"city" yaml:"city"`
+	Region  string `json:"region,omitempty" xml:"region,omitempty" yaml:"region,omitempty"` // Estado, quando conhecido
+	Country string `json:"country" xml:"country" yaml:"country"`                            // Código ISO 3166-1 alfa-2 ("BR"); fora do Brasil, o nome informado pelo provedor
+}
+
+// Conditions reúne as demais condições informadas pelo provedor de clima.
+type Conditions struct {
+	Humidity float64 `json:"humidity" xml:"humidity" yaml:"humidity"` // Umidade relativa (%)
+	WindKph  float64 `json:"wind_kph" xml:"wind_kph" yaml:"wind_kph"` // Velocidade do vento (km/h)
+}
+
+// Metadata descreve a origem e o momento da leitura.
+type Metadata struct {
+	APIVersion  string    `json:"api_version" xml:"api_version" yaml:"api_version"`
+	Provider    string    `json:"provider" xml:"provider" yaml:"provider"`             // Provedor de clima consultado
+	RetrievedAt time.Time `json:"retrieved_at" xml:"retrieved_at" yaml:"retrieved_at"` // Momento da consulta, em UTC
+	RequestID   string    `json:"request_id,omitempty" xml:"request_id,omitempty" yaml:"request_id,omitempty"`
+}
+
+// Problem representa uma resposta de erro no formato RFC 7807 (application/problem+json).
+type Problem struct {
+	Type      string `json:"type" xml:"type" yaml:"type"`                                                 // URI que documenta o tipo de erro
+	Title     string `json:"title" xml:"title" yaml:"title"`                                              // Resumo do tipo de erro, estável entre ocorrências
+	Status    int    `json:"status" xml:"status" yaml:"status"`                                           // Status HTTP
+	Detail    string `json:"detail,omitempty" xml:"detail,omitempty" yaml:"detail,omitempty"`             // Explicação específica desta ocorrência
+	Instance  string `json:"instance,omitempty" xml:"instance,omitempty" yaml:"instance,omitempty"`       // Caminho da requisição que gerou o erro
+	Code      string `json:"code" xml:"code" yaml:"code"`                                                 // Código estável e legível por máquina (ver docs/errors.md)
+	RequestID string `json:"request_id,omitempty" xml:"request_id,omitempty" yaml:"request_id,omitempty"` // ID da requisição, para correlação com os logs
 }
 
-// ErrorResponse representa uma resposta de erro padrão.
-type ErrorResponse struct {
-	Message string `json:"message"`
+// BatchItem representa o resultado de um CEP na resposta de POST /weather/batch.
+type BatchItem struct {
+	CEP    string         `json:"cep" xml:"cep" yaml:"cep"`
+	Result *WeatherOutput `json:"result,omitempty" xml:"result,omitempty" yaml:"result,omitempty"` // Preenchido em caso de sucesso
+	Error  *Problem       `json:"error,omitempty" xml:"error,omitempty" yaml:"error,omitempty"`    // Preenchido em caso de falha, com o status que /weather/{cep} retornaria
 }
@@ -0,0 +1,174 @@
+// Package health implementa as sondas de liveness (/livez) e readiness
+// (/readyz). A readiness reúne verificações por dependência, cujos resultados
+// ficam em cache para que sondas frequentes não sobrecarreguem os provedores.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+)
+
+// Resultados das verificações e da readiness como um todo.
+const (
+	StatusPass    = "pass"
+	StatusFail    = "fail"
+	StatusReady   = "ready"
+	StatusUnready = "unready"
+)
+
+// CheckFunc verifica uma dependência. details vai para a resposta mesmo quando
+// a verificação falha.
+type CheckFunc func(ctx context.Context) (details map[string]string, err error)
+
+type check struct {
+	name string
+	run  CheckFunc
+
+	mu     sync.Mutex
+	result entity.CheckResult
+}
+
+// Checker executa as verificações de readiness. Cada resultado vale por ttl;
+// chamadas simultâneas esperam a mesma execução em vez de repeti-la.
+type Checker struct {
+	ttl     time.Duration
+	timeout atomic.Int64
+	now     func() time.Time
+	checks  []*check
+}
+
+// NewChecker cria um Checker sem verificações, sempre pronto. timeout limita
+// cada verificação.
+func NewChecker(ttl, timeout time.Duration) *Checker {
+	c := &Checker{ttl: ttl, now: time.Now}
+	c.SetTimeout(timeout)
+	return c
+}
+
+// SetTimeout troca o prazo das próximas verificações.
+func (c *Checker) SetTimeout(timeout time.Duration) {
+	c.timeout.Store(int64(timeout))
+}
+
+// Timeout retorna o prazo vigente.
+func (c *Checker) Timeout() time.Duration {
+	return time.Duration(c.timeout.Load())
+}
+
+// Add registra uma verificação.
+func (c *Checker) Add(name string, run CheckFunc) {
+	c.checks = append(c.checks, &check{name: name, run: run})
+}
+
+// Check executa as verificações em paralelo, reaproveitando os resultados
+// ainda válidos.
+func (c *Checker) Check(ctx context.Context) entity.ReadinessReport {
+	report := entity.ReadinessReport{Status: StatusReady, Checks: make(map[string]entity.CheckResult, len(c.checks))}
+	results := make([]entity.CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, ch := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.result(ctx, ch)
+		}()
+	}
+	wg.Wait()
+	for i, ch := range c.checks {
+		report.Checks[ch.name] = results[i]
+		if results[i].Status != StatusPass {
+			report.Status = StatusUnready
+		}
+	}
+	return report
+}
+
+func (c *Checker) result(ctx context.Context, ch *check) entity.CheckResult {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if !ch.result.CheckedAt.IsZero() && c.now().Sub(ch.result.CheckedAt) < c.ttl {
+		return ch.result
+	}
+
+	// O resultado é compartilhado, então não depende do cancelamento de quem pediu
+	ctx = context.WithoutCancel(ctx)
+	if timeout := time.Duration(c.timeout.Load()); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	details, err := ch.run(ctx)
+	ch.result = entity.CheckResult{Status: StatusPass, Details: details, CheckedAt: c.now().UTC()}
+	if err != nil {
+		ch.result.Status = StatusFail
+		ch.result.Error = err.Error()
+	}
+	return ch.result
+}
+
+// ServeHTTP responde /readyz: 200 quando todas as verificações passam e 503
+// caso contrário, sempre com o detalhamento por dependência.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+	status := http.StatusOK
+	if report.Status != StatusReady {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Live responde /livez: o processo está no ar e atendendo requisições. Não
+// depende de nada externo, para que falhas dos provedores não reiniciem a
+// instância.
+func Live(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(`{"status":"pass"}` + "\n"))
+}
+
+// UpstreamCheck verifica um provedor: falha com o circuito aberto, passa se
+// houve resposta do provedor nos últimos recent e, caso contrário, executa
+// probe, que deve passar pelo circuit breaker para que o resultado conte nele.
+func UpstreamCheck(breaker *service.CircuitBreaker, recent time.Duration, probe func(ctx context.Context) error) CheckFunc {
+	return func(ctx context.Context) (map[string]string, error) {
+		status := breaker.Status()
+		details := map[string]string{"breaker": status.State}
+		if !status.LastSuccess.IsZero() {
+			details["last_success"] = status.LastSuccess.UTC().Format(time.RFC3339)
+		}
+		if status.State == service.BreakerOpen {
+			return details, errors.New("circuit breaker open")
+		}
+		if !status.LastSuccess.IsZero() && time.Since(status.LastSuccess) < recent {
+			return details, nil
+		}
+
+		details["probe"] = "true"
+		err := probe(ctx)
+		if isUpstreamFailure(err) {
+			return details, err
+		}
+		return details, nil
+	}
+}
+
+// isUpstreamFailure separa as falhas do provedor (ou da chave configurada) das
+// respostas válidas, como "CEP não encontrado". Cota esgotada também não
+// reprova a instância: tirá-la do balanceamento não traria a cota de volta.
+func isUpstreamFailure(err error) bool {
+	return err != nil &&
+		!errors.Is(err, service.ErrCEPNotFound) &&
+		!errors.Is(err, service.ErrLocationUnknown) &&
+		!errors.Is(err, service.ErrUpstreamRateLimited)
+}
@@ -0,0 +1,163 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readyz(t *testing.T, c *Checker) (int, entity.ReadinessReport) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	c.ServeHTTP(rr, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var report entity.ReadinessReport
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	return rr.Code, report
+}
+
+func TestChecker(t *testing.T) {
+	t.Run("Ready", func(t *testing.T) {
+		c := NewChecker(time.Minute, time.Second)
+		c.Add("config", func(context.Context) (map[string]string, error) { return nil, nil })
+
+		code, report := readyz(t, c)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, StatusReady, report.Status)
+		assert.Equal(t, StatusPass, report.Checks["config"].Status)
+	})
+
+	t.Run("Unready With Breakdown", func(t *testing.T) {
+		c := NewChecker(time.Minute, time.Second)
+		c.Add("config", func(context.Context) (map[string]string, error) { return nil, nil })
+		c.Add("weatherapi", func(context.Context) (map[string]string, error) {
+			return map[string]string{"breaker": "open"}, errors.New("circuit breaker open")
+		})
+
+		code, report := readyz(t, c)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, StatusUnready, report.Status)
+		assert.Equal(t, StatusPass, report.Checks["config"].Status)
+		assert.Equal(t, entity.CheckResult{
+			Status: StatusFail, Error: "circuit breaker open", Details: map[string]string{"breaker": "open"}, CheckedAt: report.Checks["weatherapi"].CheckedAt,
+		}, report.Checks["weatherapi"])
+	})
+
+	t.Run("Results Are Cached", func(t *testing.T) {
+		now := time.Now()
+		c := NewChecker(10*time.Second, time.Second)
+		c.now = func() time.Time { return now }
+		calls := 0
+		c.Add("viacep", func(context.Context) (map[string]string, error) {
+			calls++
+			return nil, nil
+		})
+
+		c.Check(context.Background())
+		now = now.Add(5 * time.Second)
+		c.Check(context.Background())
+		assert.Equal(t, 1, calls)
+
+		now = now.Add(5 * time.Second)
+		c.Check(context.Background())
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		c := NewChecker(0, 10*time.Millisecond)
+		c.Add("slow", func(ctx context.Context) (map[string]string, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		assert.Equal(t, StatusFail, c.Check(context.Background()).Checks["slow"].Status)
+	})
+
+	t.Run("Timeout Changes Apply To The Next Checks", func(t *testing.T) {
+		c := NewChecker(0, time.Hour)
+		var deadline time.Duration
+		c.Add("viacep", func(ctx context.Context) (map[string]string, error) {
+			d, _ := ctx.Deadline()
+			deadline = time.Until(d)
+			return nil, nil
+		})
+		c.SetTimeout(time.Second)
+		c.Check(context.Background())
+		assert.LessOrEqual(t, deadline, time.Second)
+		assert.Equal(t, time.Second, c.Timeout())
+	})
+}
+
+func TestLive(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Live(rr, httptest.NewRequest("GET", "/livez", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status":"pass"}`, rr.Body.String())
+}
+
+func TestUpstreamCheck(t *testing.T) {
+	ctx := context.Background()
+	unavailable := &service.UpstreamError{Provider: service.ProviderViaCEP, Kind: service.ErrUpstreamUnavailable, Err: errors.New("status 503")}
+
+	tests := []struct {
+		name     string
+		probeErr error
+		wantErr  bool
+	}{
+		{"Probe Succeeds", nil, false},
+		{"Not Found Is An Answer", service.ErrCEPNotFound, false},
+		{"Quota Exhausted", &service.UpstreamError{Kind: service.ErrUpstreamRateLimited, Err: errors.New("429")}, false},
+		{"Unavailable", unavailable, true},
+		{"Bad Key", &service.UpstreamError{Kind: service.ErrUpstreamBadKey, Err: errors.New("401")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := service.NewCircuitBreaker(service.ProviderViaCEP, 5, time.Minute)
+			details, err := UpstreamCheck(b, time.Minute, func(context.Context) error { return tt.probeErr })(ctx)
+			assert.Equal(t, tt.wantErr, err != nil, "%v", err)
+			assert.Equal(t, "true", details["probe"])
+			assert.Equal(t, service.BreakerClosed, details["breaker"])
+		})
+	}
+
+	t.Run("Recent Success Skips The Probe", func(t *testing.T) {
+		b := service.NewCircuitBreaker(service.ProviderViaCEP, 5, time.Minute)
+		next := locationFunc(func(context.Context, string) (string, error) { return "São Paulo", nil })
+		_, err := service.NewBreakerLocationFinder(next, b).GetLocationByCEP(ctx, "01001000")
+		require.NoError(t, err)
+
+		details, err := UpstreamCheck(b, time.Minute, func(context.Context) error {
+			t.Fatal("probe should not run")
+			return nil
+		})(ctx)
+		assert.NoError(t, err)
+		assert.NotContains(t, details, "probe")
+		assert.NotEmpty(t, details["last_success"])
+	})
+
+	t.Run("Open Breaker Fails Without Probing", func(t *testing.T) {
+		b := service.NewCircuitBreaker(service.ProviderViaCEP, 5, time.Minute)
+		b.Trip()
+		details, err := UpstreamCheck(b, time.Minute, func(context.Context) error {
+			t.Fatal("probe should not run")
+			return nil
+		})(ctx)
+		assert.EqualError(t, err, "circuit breaker open")
+		assert.Equal(t, service.BreakerOpen, details["breaker"])
+	})
+}
+
+type locationFunc func(ctx context.Context, cep string) (string, error)
+
+func (f locationFunc) GetLocationByCEP(ctx context.Context, cep string) (string, error) {
+	return f(ctx, cep)
+}
@@ -0,0 +1,399 @@
+// Package rpc expõe a consulta de clima por CEP via gRPC (proto/weather/v1/weather.proto).
+package rpc
+
+//go:generate protoc -I ../../../proto --go_out=../../.. --go_opt=module=github.com/MchlAlex/fc-lab02 --go-grpc_out=../../.. --go-grpc_opt=module=github.com/MchlAlex/fc-lab02 weather/v1/weather.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/infra/rpc/weatherpb"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ErrorDomain identifica os erros desta API no google.rpc.ErrorInfo.
+const ErrorDomain = "fc-lab02"
+
+// Authenticator resolve a chave de API de cada chamada e confere o escopo, como
+// web.Authenticator faz nas rotas HTTP.
+type Authenticator interface {
+	Authenticate(ctx context.Context, raw, scope string) (*entity.APIKey, error)
+}
+
+// RateLimiter aplica o limite por cliente, como web.RateLimiter nas rotas HTTP.
+type RateLimiter interface {
+	Allow(ctx context.Context, key *entity.APIKey, ip netip.Addr) error
+}
+
+// WeatherServer implementa weatherpb.WeatherServiceServer com os mesmos
+// serviços usados pelas rotas HTTP.
+type WeatherServer struct {
+	weatherpb.UnimplementedWeatherServiceServer
+
+	LocationService service.LocationFinder
+	WeatherService  service.WeatherFinder
+	Converter       service.TemperatureConverter
+	Batch           *service.BatchLookup
+	BatchMaxSize    int
+	BatchTimeout    time.Duration
+	Authenticator   Authenticator // nil desliga a autenticação
+	RateLimiter     RateLimiter   // nil desliga o limite por cliente
+	ClientCert      bool          // mTLS: exige o certificado de cliente, que o handshake deixa opcional
+	Logger          *slog.Logger
+}
+
+// NewWeatherServer cria uma nova instância de WeatherServer. Limites do lote
+// não positivos usam os mesmos padrões da rota HTTP.
+func NewWeatherServer(loc service.LocationFinder, weather service.WeatherFinder, conv service.TemperatureConverter, batchConcurrency, batchMaxSize int, batchTimeout time.Duration) *WeatherServer {
+	if batchMaxSize <= 0 {
+		batchMaxSize = service.DefaultBatchMaxSize
+	}
+	if batchTimeout <= 0 {
+		batchTimeout = service.DefaultBatchTimeout
+	}
+	return &WeatherServer{
+		LocationService: loc,
+		WeatherService:  weather,
+		Converter:       conv,
+		Batch:           service.NewBatchLookup(loc, weather, batchConcurrency),
+		BatchMaxSize:    batchMaxSize,
+		BatchTimeout:    batchTimeout,
+		Logger:          slog.Default(),
+	}
+}
+
+// NewServer cria o servidor gRPC com o WeatherService, o health check padrão
+// (grpc.health.v1) e a reflection, para uso com grpcurl e afins. A chave de API
+// e o limite por cliente valem só para o WeatherService, como nas rotas HTTP.
+func NewServer(ws *WeatherServer, opts ...grpc.ServerOption) *grpc.Server {
+	logger := logging.Or(ws.Logger)
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(logRequests(logger), recoverPanics(logger), ws.guardUnary),
+		grpc.ChainStreamInterceptor(ws.guardStream))
+	s := grpc.NewServer(opts...)
+	weatherpb.RegisterWeatherServiceServer(s, ws)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(weatherpb.WeatherService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, healthServer)
+
+	reflection.Register(s)
+	return s
+}
+
+// GetByCEP retorna a temperatura atual da cidade do CEP.
+func (s *WeatherServer) GetByCEP(ctx context.Context, req *weatherpb.GetByCEPRequest) (*weatherpb.GetByCEPResponse, error) {
+	converter, err := s.converter(req.Precision)
+	if err != nil {
+		return nil, statusError(apperr.CodeInvalidPrecision, err.Error())
+	}
+	cep := req.GetCep()
+	if cep == "" {
+		return nil, statusError(apperr.CodeMissingZipcode, "CEP parameter is missing")
+	}
+
+	logging.Add(ctx, slog.String("cep", cep))
+	city, err := s.LocationService.GetLocationByCEP(ctx, cep)
+	if err != nil {
+		s.Logger.WarnContext(ctx, "error finding location", "error", err)
+		return nil, toStatus(err, apperr.CodeLocationLookupFailed, "error while fetching location")
+	}
+
+	logging.Add(ctx, slog.String("city", city))
+	weather, err := s.WeatherService.GetWeatherByCity(ctx, city)
+	if err != nil {
+		s.Logger.WarnContext(ctx, "error finding weather", "error", err)
+		return nil, toStatus(err, apperr.CodeWeatherLookupFailed, "error while fetching weather data")
+	}
+
+	return &weatherpb.GetByCEPResponse{Weather: toWeather(cep, city, weather, converter)}, nil
+}
+
+// BatchGetByCEP consulta vários CEPs; cada item traz o clima ou o seu erro.
+func (s *WeatherServer) BatchGetByCEP(ctx context.Context, req *weatherpb.BatchGetByCEPRequest) (*weatherpb.BatchGetByCEPResponse, error) {
+	converter, err := s.converter(req.Precision)
+	if err != nil {
+		return nil, statusError(apperr.CodeInvalidPrecision, err.Error())
+	}
+	ceps := req.GetCeps()
+	if len(ceps) == 0 {
+		return nil, statusError(apperr.CodeInvalidRequestBody, "at least one zipcode is required")
+	}
+	if len(ceps) > s.BatchMaxSize {
+		return nil, statusError(apperr.CodeBatchTooLarge, fmt.Sprintf("too many zipcodes (max %d)", s.BatchMaxSize))
+	}
+
+	logging.Add(ctx, slog.Int("batch_size", len(ceps)))
+	ctx, cancel := context.WithTimeout(ctx, s.BatchTimeout)
+	defer cancel()
+	results := s.Batch.Lookup(ctx, ceps)
+
+	items := make([]*weatherpb.BatchItem, len(results))
+	for i, res := range results {
+		items[i] = &weatherpb.BatchItem{Cep: res.CEP}
+		if res.Err != nil {
+			s.Logger.WarnContext(ctx, "error in batch lookup", "item_cep", res.CEP, "error", res.Err)
+			fallback, fallbackDetail := apperr.CodeWeatherLookupFailed, "error while fetching weather data"
+			if res.City == "" {
+				fallback, fallbackDetail = apperr.CodeLocationLookupFailed, "error while fetching location"
+			}
+			code := apperr.CodeFor(res.Err, fallback)
+			items[i].Outcome = &weatherpb.BatchItem_Error{Error: &weatherpb.Error{
+				Code:    int32(grpcCode(res.Err, code)),
+				Reason:  string(code),
+				Message: apperr.Detail(res.Err, fallbackDetail),
+			}}
+			continue
+		}
+		items[i].Outcome = &weatherpb.BatchItem_Weather{Weather: toWeather(res.CEP, res.City, res.Weather, converter)}
+	}
+	return &weatherpb.BatchGetByCEPResponse{Items: items}, nil
+}
+
+// converter aplica a precisão pedida, como o parâmetro ?precision= da API HTTP.
+func (s *WeatherServer) converter(precision *int32) (service.TemperatureConverter, error) {
+	if precision == nil {
+		return s.Converter, nil
+	}
+	if *precision < 0 || *precision > service.MaxPrecision {
+		return nil, fmt.Errorf("%w: %d (must be between 0 and %d)", service.ErrInvalidPrecision, *precision, service.MaxPrecision)
+	}
+	if pc, ok := s.Converter.(service.PrecisionConverter); ok {
+		return pc.WithPrecision(int(*precision)), nil
+	}
+	return s.Converter, nil
+}
+
+func toWeather(cep, city string, weather *entity.CurrentWeather, conv service.TemperatureConverter) *weatherpb.Weather {
+	out := conv.ConvertTemperatures(weather.TempC)
+	w := &weatherpb.Weather{
+		Cep:         cep,
+		City:        city,
+		Temperature: &weatherpb.Temperature{C: out.TempC, F: out.TempF, K: out.TempK},
+		Humidity:    weather.Humidity,
+		WindKph:     weather.WindKph,
+	}
+	if idx := conv.ThermalIndices(weather); idx != nil {
+		w.Indices = &weatherpb.ThermalIndices{
+			HeatIndex:           toTemperature(idx.HeatIndex),
+			WindChill:           toTemperature(idx.WindChill),
+			DewPoint:            toTemperature(idx.DewPoint),
+			Humidex:             toTemperature(idx.Humidex),
+			ApparentTemperature: toTemperature(idx.ApparentTemperature),
+		}
+	}
+	return w
+}
+
+func toTemperature(t entity.Temperature) *weatherpb.Temperature {
+	return &weatherpb.Temperature{C: t.C, F: t.F, K: t.K}
+}
+
+// grpcCodes associa os códigos do catálogo aos códigos gRPC; os ausentes viram Internal.
+var grpcCodes = map[apperr.Code]codes.Code{
+	apperr.CodeMissingZipcode:      codes.InvalidArgument,
+	apperr.CodeInvalidZipcode:      codes.InvalidArgument,
+	apperr.CodeInvalidPrecision:    codes.InvalidArgument,
+	apperr.CodeInvalidRequestBody:  codes.InvalidArgument,
+	apperr.CodeBatchTooLarge:       codes.InvalidArgument,
+	apperr.CodeZipcodeNotFound:     codes.NotFound,
+	apperr.CodeLocationUnknown:     codes.NotFound,
+	apperr.CodeTimeout:             codes.DeadlineExceeded,
+	apperr.CodeUpstreamTimeout:     codes.DeadlineExceeded,
+	apperr.CodeUpstreamUnavailable: codes.Unavailable,
+	apperr.CodeUpstreamRateLimited: codes.ResourceExhausted,
+	apperr.CodeUpstreamAuthFailed:  codes.Unavailable, // Falha de configuração do servidor, como o 502 no HTTP
+	apperr.CodeMissingAPIKey:       codes.Unauthenticated,
+	apperr.CodeInvalidAPIKey:       codes.Unauthenticated,
+	apperr.CodeClientCertRequired:  codes.Unauthenticated,
+	apperr.CodeInsufficientScope:   codes.PermissionDenied,
+	apperr.CodeRateLimitExceeded:   codes.ResourceExhausted,
+}
+
+// grpcCode traduz o código do catálogo. Cancelamentos do cliente viram Canceled,
+// e não DeadlineExceeded como no HTTP, já que o gRPC distingue os dois casos.
+func grpcCode(err error, code apperr.Code) codes.Code {
+	if errors.Is(err, context.Canceled) {
+		return codes.Canceled
+	}
+	if c, ok := grpcCodes[code]; ok {
+		return c
+	}
+	return codes.Internal
+}
+
+// toStatus converte um erro dos serviços no status gRPC correspondente.
+func toStatus(err error, fallback apperr.Code, fallbackDetail string) error {
+	code := apperr.CodeFor(err, fallback)
+	st := status.New(grpcCode(err, code), apperr.Detail(err, fallbackDetail))
+	return withDetails(st, code, apperr.RetryAfter(err, code))
+}
+
+func statusError(code apperr.Code, detail string) error {
+	return withDetails(status.New(grpcCode(nil, code), detail), code, 0)
+}
+
+// withDetails anexa o código do catálogo (ErrorInfo) e a espera sugerida (RetryInfo).
+func withDetails(st *status.Status, code apperr.Code, retryAfter time.Duration) error {
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: string(code), Domain: ErrorDomain}}
+	if retryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	}
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// logRequests registra cada chamada com os mesmos campos do log das rotas
+// HTTP. O ID da requisição vem do metadata x-request-id, se o cliente o enviar.
+func logRequests(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+		ctx = logging.WithFields(ctx, slog.String("rpc_method", info.FullMethod))
+		if ids := metadata.ValueFromIncomingContext(ctx, "x-request-id"); len(ids) > 0 {
+			logging.Add(ctx, slog.String("request_id", ids[0]))
+		}
+		start := time.Now()
+		resp, err := next(ctx, req)
+
+		code := status.Code(err)
+		outcome, level := "success", slog.LevelInfo
+		switch code {
+		case codes.OK:
+		case codes.Internal, codes.Unknown, codes.Unavailable, codes.DeadlineExceeded, codes.DataLoss, codes.Unimplemented:
+			outcome, level = "server_error", slog.LevelError
+		default:
+			outcome = "client_error"
+		}
+		if st, ok := status.FromError(err); ok && err != nil {
+			for _, d := range st.Details() {
+				if info, ok := d.(*errdetails.ErrorInfo); ok {
+					logging.Add(ctx, slog.String("error_code", info.Reason))
+				}
+			}
+		}
+		logger.LogAttrs(ctx, level, "grpc request",
+			slog.String("grpc_code", code.String()),
+			logging.Latency("duration", time.Since(start)),
+			slog.String("outcome", outcome))
+		return resp, err
+	}
+}
+
+// guardUnary exige a chave de API e aplica o limite por cliente às chamadas do WeatherService.
+func (s *WeatherServer) guardUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+	if err := s.guard(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return next(ctx, req)
+}
+
+// guardStream é o guardUnary dos streams.
+func (s *WeatherServer) guardStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, next grpc.StreamHandler) error {
+	if err := s.guard(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return next(srv, ss)
+}
+
+// guard exige, com mTLS, o certificado de cliente e lê a chave do metadata
+// x-api-key ou authorization (Bearer), com o mesmo Authenticator e o mesmo
+// RateLimiter das rotas HTTP. O health check, usado pelas sondas, fica de fora
+// de tudo; a reflection, só da chave de API.
+func (s *WeatherServer) guard(ctx context.Context, method string) error {
+	if strings.HasPrefix(method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+		return nil
+	}
+	if s.ClientCert && !hasClientCert(ctx) {
+		return statusError(apperr.CodeClientCertRequired, "this server requires a TLS client certificate")
+	}
+	if !strings.HasPrefix(method, "/"+weatherpb.WeatherService_ServiceDesc.ServiceName+"/") {
+		return nil
+	}
+	var key *entity.APIKey
+	if s.Authenticator != nil {
+		var err error
+		if key, err = s.Authenticator.Authenticate(ctx, metadataAPIKey(ctx), service.ScopeWeatherRead); err != nil {
+			return toStatus(err, apperr.CodeInvalidAPIKey, "")
+		}
+	}
+	if s.RateLimiter != nil {
+		if err := s.RateLimiter.Allow(ctx, key, peerAddr(ctx)); err != nil {
+			return toStatus(err, apperr.CodeRateLimitExceeded, "")
+		}
+	}
+	return nil
+}
+
+// metadataAPIKey extrai a chave de x-api-key ou, na falta dele, de authorization: Bearer.
+func metadataAPIKey(ctx context.Context) string {
+	if keys := metadata.ValueFromIncomingContext(ctx, "x-api-key"); len(keys) > 0 && keys[0] != "" {
+		return keys[0]
+	}
+	for _, auth := range metadata.ValueFromIncomingContext(ctx, "authorization") {
+		scheme, token, ok := strings.Cut(auth, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// hasClientCert informa se a conexão apresentou um certificado de cliente válido.
+func hasClientCert(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	return ok && len(info.State.PeerCertificates) > 0
+}
+
+// peerAddr é o IP de quem abriu a conexão, usado no limite das chamadas sem chave.
+func peerAddr(ctx context.Context) netip.Addr {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return netip.Addr{}
+	}
+	addrPort, err := netip.ParseAddrPort(p.Addr.String())
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addrPort.Addr().Unmap()
+}
+
+// recoverPanics converte panics dos handlers em codes.Internal, registrando o stack trace.
+func recoverPanics(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				logger.ErrorContext(ctx, "panic serving gRPC request", "panic", fmt.Sprint(rvr), "stack", string(debug.Stack()))
+				err = statusError(apperr.CodeInternalError, "")
+			}
+		}()
+		return next(ctx, req)
+	}
+}
@@ -0,0 +1,280 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/infra/rpc/weatherpb"
+	"github.com/MchlAlex/fc-lab02/internal/infra/web"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// dial sobe o servidor em memória (bufconn) e retorna uma conexão cliente.
+func dial(t *testing.T, ws *WeatherServer) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := NewServer(ws)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func newTestServer() (*WeatherServer, *mocks.LocationFinder, *mocks.WeatherFinder) {
+	loc := new(mocks.LocationFinder)
+	weather := new(mocks.WeatherFinder)
+	return NewWeatherServer(loc, weather, service.NewStandardTemperatureConverter(), 2, 3, time.Second), loc, weather
+}
+
+func TestWeatherServer_GetByCEP(t *testing.T) {
+	ws, loc, weather := newTestServer()
+	client := weatherpb.NewWeatherServiceClient(dial(t, ws))
+
+	loc.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+	weather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 25.35, Humidity: 60, WindKph: 10}, nil)
+
+	t.Run("Success", func(t *testing.T) {
+		resp, err := client.GetByCEP(context.Background(), &weatherpb.GetByCEPRequest{Cep: "01001000"})
+		require.NoError(t, err)
+
+		w := resp.GetWeather()
+		assert.Equal(t, "01001000", w.GetCep())
+		assert.Equal(t, "São Paulo", w.GetCity())
+		assert.Equal(t, 25.4, w.GetTemperature().GetC())
+		assert.Equal(t, 77.6, w.GetTemperature().GetF())
+		assert.Equal(t, 298.5, w.GetTemperature().GetK())
+		assert.Equal(t, 60.0, w.GetHumidity())
+		assert.NotNil(t, w.GetIndices().GetDewPoint())
+	})
+
+	t.Run("Precision", func(t *testing.T) {
+		resp, err := client.GetByCEP(context.Background(), &weatherpb.GetByCEPRequest{Cep: "01001000", Precision: proto.Int32(0)})
+		require.NoError(t, err)
+		assert.Equal(t, 25.0, resp.GetWeather().GetTemperature().GetC())
+
+		_, err = client.GetByCEP(context.Background(), &weatherpb.GetByCEPRequest{Cep: "01001000", Precision: proto.Int32(7)})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestWeatherServer_GetByCEP_Errors(t *testing.T) {
+	upstream := func(kind error, retryAfter time.Duration) error {
+		return &service.UpstreamError{Provider: service.ProviderViaCEP, Kind: kind, RetryAfter: retryAfter, Err: errors.New("upstream failure")}
+	}
+
+	tests := []struct {
+		name       string
+		cep        string
+		err        error
+		code       codes.Code
+		reason     string
+		retryDelay time.Duration
+	}{
+		{"Missing CEP", "", nil, codes.InvalidArgument, "missing_zipcode", 0},
+		{"Invalid CEP", "123", service.ErrInvalidCEPFormat, codes.InvalidArgument, "invalid_zipcode", 0},
+		{"CEP Not Found", "99999999", service.ErrCEPNotFound, codes.NotFound, "zipcode_not_found", 0},
+		{"Unavailable", "11111111", upstream(service.ErrUpstreamUnavailable, 0), codes.Unavailable, "upstream_unavailable", 30 * time.Second},
+		{"Rate Limited", "22222222", upstream(service.ErrUpstreamRateLimited, 5*time.Second), codes.ResourceExhausted, "upstream_rate_limited", 5 * time.Second},
+		{"Timeout", "33333333", upstream(service.ErrUpstreamTimeout, 0), codes.DeadlineExceeded, "upstream_timeout", 0},
+		{"Bad Key", "44444444", upstream(service.ErrUpstreamBadKey, 0), codes.Unavailable, "upstream_auth_failed", 0},
+		{"Unexpected", "55555555", errors.New("boom"), codes.Internal, "location_lookup_failed", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, loc, _ := newTestServer()
+			client := weatherpb.NewWeatherServiceClient(dial(t, ws))
+			if tt.err != nil {
+				loc.On("GetLocationByCEP", mock.Anything, tt.cep).Return("", tt.err).Once()
+			}
+
+			_, err := client.GetByCEP(context.Background(), &weatherpb.GetByCEPRequest{Cep: tt.cep})
+			st := status.Convert(err)
+			assert.Equal(t, tt.code, st.Code())
+
+			var reason string
+			var retryDelay time.Duration
+			for _, d := range st.Details() {
+				switch d := d.(type) {
+				case *errdetails.ErrorInfo:
+					reason = d.GetReason()
+					assert.Equal(t, ErrorDomain, d.GetDomain())
+				case *errdetails.RetryInfo:
+					retryDelay = d.GetRetryDelay().AsDuration()
+				}
+			}
+			assert.Equal(t, tt.reason, reason)
+			assert.Equal(t, tt.retryDelay, retryDelay)
+		})
+	}
+}
+
+func TestWeatherServer_BatchGetByCEP(t *testing.T) {
+	ws, loc, weather := newTestServer()
+	client := weatherpb.NewWeatherServiceClient(dial(t, ws))
+
+	loc.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+	loc.On("GetLocationByCEP", mock.Anything, "99999999").Return("", service.ErrCEPNotFound)
+	weather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 25}, nil)
+
+	t.Run("Mixed Results", func(t *testing.T) {
+		resp, err := client.BatchGetByCEP(context.Background(), &weatherpb.BatchGetByCEPRequest{Ceps: []string{"01001000", "99999999"}})
+		require.NoError(t, err)
+		require.Len(t, resp.GetItems(), 2)
+
+		assert.Equal(t, "São Paulo", resp.GetItems()[0].GetWeather().GetCity())
+		assert.Nil(t, resp.GetItems()[0].GetWeather().GetIndices())
+
+		failed := resp.GetItems()[1]
+		assert.Equal(t, "99999999", failed.GetCep())
+		assert.Equal(t, int32(codes.NotFound), failed.GetError().GetCode())
+		assert.Equal(t, "zipcode_not_found", failed.GetError().GetReason())
+		assert.Equal(t, "can not find zipcode", failed.GetError().GetMessage())
+	})
+
+	t.Run("Invalid Requests", func(t *testing.T) {
+		_, err := client.BatchGetByCEP(context.Background(), &weatherpb.BatchGetByCEPRequest{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.BatchGetByCEP(context.Background(), &weatherpb.BatchGetByCEPRequest{Ceps: []string{"1", "2", "3", "4"}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestNewServer_HealthAndReflection(t *testing.T) {
+	ws, _, _ := newTestServer()
+	conn := dial(t, ws)
+
+	for _, svc := range []string{"", weatherpb.WeatherService_ServiceDesc.ServiceName} {
+		resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: svc})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+	}
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+
+	var names []string
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		names = append(names, s.GetName())
+	}
+	assert.Contains(t, names, "weather.v1.WeatherService")
+	assert.Contains(t, names, "grpc.health.v1.Health")
+}
+
+func TestNewServer_Auth(t *testing.T) {
+	keys, err := service.NewFileAPIKeyStore("")
+	require.NoError(t, err)
+	for _, key := range []entity.APIKey{
+		{ID: "reader", Name: "reader", Scopes: []string{service.ScopeWeatherRead}},
+		{ID: "tiny", Name: "tiny", Scopes: []string{service.ScopeWeatherRead}, Quota: "1/1h"},
+		{ID: "subs", Name: "subs", Scopes: []string{service.ScopeSubscriptionsRead}},
+	} {
+		require.NoError(t, keys.CreateAPIKey(context.Background(), key, service.HashAPIKey(key.ID+"-secret")))
+	}
+	limiter, err := web.NewRateLimiter(service.NewMemoryRateLimitStore(), []string{"authenticated=1000/1m"}, nil)
+	require.NoError(t, err)
+
+	ws, loc, weather := newTestServer()
+	ws.Authenticator = &web.Authenticator{Store: keys, Required: true}
+	ws.RateLimiter = limiter
+	conn := dial(t, ws)
+	client := weatherpb.NewWeatherServiceClient(conn)
+	loc.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+	weather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 25}, nil)
+
+	call := func(md ...string) error {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), md...)
+		_, err := client.GetByCEP(ctx, &weatherpb.GetByCEPRequest{Cep: "01001000"})
+		return err
+	}
+	reason := func(err error) string {
+		for _, d := range status.Convert(err).Details() {
+			if info, ok := d.(*errdetails.ErrorInfo); ok {
+				return info.GetReason()
+			}
+		}
+		return ""
+	}
+
+	t.Run("Missing Key", func(t *testing.T) {
+		err := call()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, "missing_api_key", reason(err))
+	})
+
+	t.Run("Invalid Key", func(t *testing.T) {
+		err := call("x-api-key", "wk_unknown")
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, "invalid_api_key", reason(err))
+	})
+
+	t.Run("Insufficient Scope", func(t *testing.T) {
+		err := call("authorization", "Bearer subs-secret")
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Equal(t, "insufficient_scope", reason(err))
+	})
+
+	t.Run("Valid Key", func(t *testing.T) {
+		assert.NoError(t, call("x-api-key", "reader-secret"))
+		assert.NoError(t, call("authorization", "Bearer reader-secret"))
+	})
+
+	t.Run("Rate Limited", func(t *testing.T) {
+		require.NoError(t, call("x-api-key", "tiny-secret"))
+		err := call("x-api-key", "tiny-secret")
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, "rate_limit_exceeded", reason(err))
+	})
+
+	t.Run("Health Without Key", func(t *testing.T) {
+		_, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		assert.NoError(t, err)
+	})
+}
+
+func TestNewServer_ClientCert(t *testing.T) {
+	ws, _, _ := newTestServer()
+	ws.ClientCert = true
+	conn := dial(t, ws) // bufconn sem TLS: a conexão nunca traz certificado
+
+	_, err := weatherpb.NewWeatherServiceClient(conn).GetByCEP(context.Background(), &weatherpb.GetByCEPRequest{Cep: "01001000"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			assert.Equal(t, "client_cert_required", info.GetReason())
+		}
+	}
+
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err, "probes do not present a certificate")
+}
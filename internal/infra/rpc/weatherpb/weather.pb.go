@@ -0,0 +1,721 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: weather/v1/weather.proto
+
+// Serviço gRPC de clima por CEP, equivalente às rotas /v1/weather da API HTTP.
+
+package weatherpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetByCEPRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Cep   string                 `protobuf:"bytes,1,opt,name=cep,proto3" json:"cep,omitempty"`
+	// Casas decimais (0 a 6) aplicadas a todas as unidades. Ausente: configuração do servidor.
+	Precision     *int32 `protobuf:"varint,2,opt,name=precision,proto3,oneof" json:"precision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetByCEPRequest) Reset() {
+	*x = GetByCEPRequest{}
+	mi := &file_weather_v1_weather_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetByCEPRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetByCEPRequest) ProtoMessage() {}
+
+func (x *GetByCEPRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetByCEPRequest.ProtoReflect.Descriptor instead.
+func (*GetByCEPRequest) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetByCEPRequest) GetCep() string {
+	if x != nil {
+		return x.Cep
+	}
+	return ""
+}
+
+func (x *GetByCEPRequest) GetPrecision() int32 {
+	if x != nil && x.Precision != nil {
+		return *x.Precision
+	}
+	return 0
+}
+
+type GetByCEPResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Weather       *Weather               `protobuf:"bytes,1,opt,name=weather,proto3" json:"weather,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetByCEPResponse) Reset() {
+	*x = GetByCEPResponse{}
+	mi := &file_weather_v1_weather_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetByCEPResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetByCEPResponse) ProtoMessage() {}
+
+func (x *GetByCEPResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetByCEPResponse.ProtoReflect.Descriptor instead.
+func (*GetByCEPResponse) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetByCEPResponse) GetWeather() *Weather {
+	if x != nil {
+		return x.Weather
+	}
+	return nil
+}
+
+type BatchGetByCEPRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ceps          []string               `protobuf:"bytes,1,rep,name=ceps,proto3" json:"ceps,omitempty"`
+	Precision     *int32                 `protobuf:"varint,2,opt,name=precision,proto3,oneof" json:"precision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetByCEPRequest) Reset() {
+	*x = BatchGetByCEPRequest{}
+	mi := &file_weather_v1_weather_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetByCEPRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetByCEPRequest) ProtoMessage() {}
+
+func (x *BatchGetByCEPRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetByCEPRequest.ProtoReflect.Descriptor instead.
+func (*BatchGetByCEPRequest) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BatchGetByCEPRequest) GetCeps() []string {
+	if x != nil {
+		return x.Ceps
+	}
+	return nil
+}
+
+func (x *BatchGetByCEPRequest) GetPrecision() int32 {
+	if x != nil && x.Precision != nil {
+		return *x.Precision
+	}
+	return 0
+}
+
+type BatchGetByCEPResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Um item por CEP, na ordem do pedido.
+	Items         []*BatchItem `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetByCEPResponse) Reset() {
+	*x = BatchGetByCEPResponse{}
+	mi := &file_weather_v1_weather_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetByCEPResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetByCEPResponse) ProtoMessage() {}
+
+func (x *BatchGetByCEPResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetByCEPResponse.ProtoReflect.Descriptor instead.
+func (*BatchGetByCEPResponse) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchGetByCEPResponse) GetItems() []*BatchItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type BatchItem struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Cep   string                 `protobuf:"bytes,1,opt,name=cep,proto3" json:"cep,omitempty"`
+	// Types that are valid to be assigned to Outcome:
+	//
+	//	*BatchItem_Weather
+	//	*BatchItem_Error
+	Outcome       isBatchItem_Outcome `protobuf_oneof:"outcome"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchItem) Reset() {
+	*x = BatchItem{}
+	mi := &file_weather_v1_weather_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItem) ProtoMessage() {}
+
+func (x *BatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItem.ProtoReflect.Descriptor instead.
+func (*BatchItem) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchItem) GetCep() string {
+	if x != nil {
+		return x.Cep
+	}
+	return ""
+}
+
+func (x *BatchItem) GetOutcome() isBatchItem_Outcome {
+	if x != nil {
+		return x.Outcome
+	}
+	return nil
+}
+
+func (x *BatchItem) GetWeather() *Weather {
+	if x != nil {
+		if x, ok := x.Outcome.(*BatchItem_Weather); ok {
+			return x.Weather
+		}
+	}
+	return nil
+}
+
+func (x *BatchItem) GetError() *Error {
+	if x != nil {
+		if x, ok := x.Outcome.(*BatchItem_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+type isBatchItem_Outcome interface {
+	isBatchItem_Outcome()
+}
+
+type BatchItem_Weather struct {
+	Weather *Weather `protobuf:"bytes,2,opt,name=weather,proto3,oneof"`
+}
+
+type BatchItem_Error struct {
+	Error *Error `protobuf:"bytes,3,opt,name=error,proto3,oneof"`
+}
+
+func (*BatchItem_Weather) isBatchItem_Outcome() {}
+
+func (*BatchItem_Error) isBatchItem_Outcome() {}
+
+type Weather struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Cep         string                 `protobuf:"bytes,1,opt,name=cep,proto3" json:"cep,omitempty"`
+	City        string                 `protobuf:"bytes,2,opt,name=city,proto3" json:"city,omitempty"`
+	Temperature *Temperature           `protobuf:"bytes,3,opt,name=temperature,proto3" json:"temperature,omitempty"`
+	// Ausente quando o provedor não informa a umidade.
+	Indices       *ThermalIndices `protobuf:"bytes,4,opt,name=indices,proto3" json:"indices,omitempty"`
+	Humidity      float64         `protobuf:"fixed64,5,opt,name=humidity,proto3" json:"humidity,omitempty"`
+	WindKph       float64         `protobuf:"fixed64,6,opt,name=wind_kph,json=windKph,proto3" json:"wind_kph,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Weather) Reset() {
+	*x = Weather{}
+	mi := &file_weather_v1_weather_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Weather) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Weather) ProtoMessage() {}
+
+func (x *Weather) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Weather.ProtoReflect.Descriptor instead.
+func (*Weather) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Weather) GetCep() string {
+	if x != nil {
+		return x.Cep
+	}
+	return ""
+}
+
+func (x *Weather) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *Weather) GetTemperature() *Temperature {
+	if x != nil {
+		return x.Temperature
+	}
+	return nil
+}
+
+func (x *Weather) GetIndices() *ThermalIndices {
+	if x != nil {
+		return x.Indices
+	}
+	return nil
+}
+
+func (x *Weather) GetHumidity() float64 {
+	if x != nil {
+		return x.Humidity
+	}
+	return 0
+}
+
+func (x *Weather) GetWindKph() float64 {
+	if x != nil {
+		return x.WindKph
+	}
+	return 0
+}
+
+type Temperature struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	C             float64                `protobuf:"fixed64,1,opt,name=c,proto3" json:"c,omitempty"`
+	F             float64                `protobuf:"fixed64,2,opt,name=f,proto3" json:"f,omitempty"`
+	K             float64                `protobuf:"fixed64,3,opt,name=k,proto3" json:"k,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Temperature) Reset() {
+	*x = Temperature{}
+	mi := &file_weather_v1_weather_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Temperature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Temperature) ProtoMessage() {}
+
+func (x *Temperature) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Temperature.ProtoReflect.Descriptor instead.
+func (*Temperature) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Temperature) GetC() float64 {
+	if x != nil {
+		return x.C
+	}
+	return 0
+}
+
+func (x *Temperature) GetF() float64 {
+	if x != nil {
+		return x.F
+	}
+	return 0
+}
+
+func (x *Temperature) GetK() float64 {
+	if x != nil {
+		return x.K
+	}
+	return 0
+}
+
+type ThermalIndices struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	HeatIndex           *Temperature           `protobuf:"bytes,1,opt,name=heat_index,json=heatIndex,proto3" json:"heat_index,omitempty"`
+	WindChill           *Temperature           `protobuf:"bytes,2,opt,name=wind_chill,json=windChill,proto3" json:"wind_chill,omitempty"`
+	DewPoint            *Temperature           `protobuf:"bytes,3,opt,name=dew_point,json=dewPoint,proto3" json:"dew_point,omitempty"`
+	Humidex             *Temperature           `protobuf:"bytes,4,opt,name=humidex,proto3" json:"humidex,omitempty"`
+	ApparentTemperature *Temperature           `protobuf:"bytes,5,opt,name=apparent_temperature,json=apparentTemperature,proto3" json:"apparent_temperature,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *ThermalIndices) Reset() {
+	*x = ThermalIndices{}
+	mi := &file_weather_v1_weather_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ThermalIndices) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ThermalIndices) ProtoMessage() {}
+
+func (x *ThermalIndices) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ThermalIndices.ProtoReflect.Descriptor instead.
+func (*ThermalIndices) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ThermalIndices) GetHeatIndex() *Temperature {
+	if x != nil {
+		return x.HeatIndex
+	}
+	return nil
+}
+
+func (x *ThermalIndices) GetWindChill() *Temperature {
+	if x != nil {
+		return x.WindChill
+	}
+	return nil
+}
+
+func (x *ThermalIndices) GetDewPoint() *Temperature {
+	if x != nil {
+		return x.DewPoint
+	}
+	return nil
+}
+
+func (x *ThermalIndices) GetHumidex() *Temperature {
+	if x != nil {
+		return x.Humidex
+	}
+	return nil
+}
+
+func (x *ThermalIndices) GetApparentTemperature() *Temperature {
+	if x != nil {
+		return x.ApparentTemperature
+	}
+	return nil
+}
+
+// Error descreve a falha de um item do lote.
+type Error struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Código gRPC (google.rpc.Code) que GetByCEP retornaria para o CEP.
+	Code int32 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	// Código do catálogo de docs/errors.md (ex: "zipcode_not_found").
+	Reason        string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Message       string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_weather_v1_weather_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Error) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *Error) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+var File_weather_v1_weather_proto protoreflect.FileDescriptor
+
+const file_weather_v1_weather_proto_rawDesc = "" +
+	"\n" +
+	"\x18weather/v1/weather.proto\x12\n" +
+	"weather.v1\"T\n" +
+	"\x0fGetByCEPRequest\x12\x10\n" +
+	"\x03cep\x18\x01 \x01(\tR\x03cep\x12!\n" +
+	"\tprecision\x18\x02 \x01(\x05H\x00R\tprecision\x88\x01\x01B\f\n" +
+	"\n" +
+	"_precision\"A\n" +
+	"\x10GetByCEPResponse\x12-\n" +
+	"\aweather\x18\x01 \x01(\v2\x13.weather.v1.WeatherR\aweather\"[\n" +
+	"\x14BatchGetByCEPRequest\x12\x12\n" +
+	"\x04ceps\x18\x01 \x03(\tR\x04ceps\x12!\n" +
+	"\tprecision\x18\x02 \x01(\x05H\x00R\tprecision\x88\x01\x01B\f\n" +
+	"\n" +
+	"_precision\"D\n" +
+	"\x15BatchGetByCEPResponse\x12+\n" +
+	"\x05items\x18\x01 \x03(\v2\x15.weather.v1.BatchItemR\x05items\"\x84\x01\n" +
+	"\tBatchItem\x12\x10\n" +
+	"\x03cep\x18\x01 \x01(\tR\x03cep\x12/\n" +
+	"\aweather\x18\x02 \x01(\v2\x13.weather.v1.WeatherH\x00R\aweather\x12)\n" +
+	"\x05error\x18\x03 \x01(\v2\x11.weather.v1.ErrorH\x00R\x05errorB\t\n" +
+	"\aoutcome\"\xd7\x01\n" +
+	"\aWeather\x12\x10\n" +
+	"\x03cep\x18\x01 \x01(\tR\x03cep\x12\x12\n" +
+	"\x04city\x18\x02 \x01(\tR\x04city\x129\n" +
+	"\vtemperature\x18\x03 \x01(\v2\x17.weather.v1.TemperatureR\vtemperature\x124\n" +
+	"\aindices\x18\x04 \x01(\v2\x1a.weather.v1.ThermalIndicesR\aindices\x12\x1a\n" +
+	"\bhumidity\x18\x05 \x01(\x01R\bhumidity\x12\x19\n" +
+	"\bwind_kph\x18\x06 \x01(\x01R\awindKph\"7\n" +
+	"\vTemperature\x12\f\n" +
+	"\x01c\x18\x01 \x01(\x01R\x01c\x12\f\n" +
+	"\x01f\x18\x02 \x01(\x01R\x01f\x12\f\n" +
+	"\x01k\x18\x03 \x01(\x01R\x01k\"\xb5\x02\n" +
+	"\x0eThermalIndices\x126\n" +
+	"\n" +
+	"heat_index\x18\x01 \x01(\v2\x17.weather.v1.TemperatureR\theatIndex\x126\n" +
+	"\n" +
+	"wind_chill\x18\x02 \x01(\v2\x17.weather.v1.TemperatureR\twindChill\x124\n" +
+	"\tdew_point\x18\x03 \x01(\v2\x17.weather.v1.TemperatureR\bdewPoint\x121\n" +
+	"\ahumidex\x18\x04 \x01(\v2\x17.weather.v1.TemperatureR\ahumidex\x12J\n" +
+	"\x14apparent_temperature\x18\x05 \x01(\v2\x17.weather.v1.TemperatureR\x13apparentTemperature\"M\n" +
+	"\x05Error\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage2\xad\x01\n" +
+	"\x0eWeatherService\x12E\n" +
+	"\bGetByCEP\x12\x1b.weather.v1.GetByCEPRequest\x1a\x1c.weather.v1.GetByCEPResponse\x12T\n" +
+	"\rBatchGetByCEP\x12 .weather.v1.BatchGetByCEPRequest\x1a!.weather.v1.BatchGetByCEPResponseBEZCgithub.com/MchlAlex/fc-lab02/internal/infra/rpc/weatherpb;weatherpbb\x06proto3"
+
+var (
+	file_weather_v1_weather_proto_rawDescOnce sync.Once
+	file_weather_v1_weather_proto_rawDescData []byte
+)
+
+func file_weather_v1_weather_proto_rawDescGZIP() []byte {
+	file_weather_v1_weather_proto_rawDescOnce.Do(func() {
+		file_weather_v1_weather_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_weather_v1_weather_proto_rawDesc), len(file_weather_v1_weather_proto_rawDesc)))
+	})
+	return file_weather_v1_weather_proto_rawDescData
+}
+
+var file_weather_v1_weather_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_weather_v1_weather_proto_goTypes = []any{
+	(*GetByCEPRequest)(nil),       // 0: weather.v1.GetByCEPRequest
+	(*GetByCEPResponse)(nil),      // 1: weather.v1.GetByCEPResponse
+	(*BatchGetByCEPRequest)(nil),  // 2: weather.v1.BatchGetByCEPRequest
+	(*BatchGetByCEPResponse)(nil), // 3: weather.v1.BatchGetByCEPResponse
+	(*BatchItem)(nil),             // 4: weather.v1.BatchItem
+	(*Weather)(nil),               // 5: weather.v1.Weather
+	(*Temperature)(nil),           // 6: weather.v1.Temperature
+	(*ThermalIndices)(nil),        // 7: weather.v1.ThermalIndices
+	(*Error)(nil),                 // 8: weather.v1.Error
+}
+var file_weather_v1_weather_proto_depIdxs = []int32{
+	5,  // 0: weather.v1.GetByCEPResponse.weather:type_name -> weather.v1.Weather
+	4,  // 1: weather.v1.BatchGetByCEPResponse.items:type_name -> weather.v1.BatchItem
+	5,  // 2: weather.v1.BatchItem.weather:type_name -> weather.v1.Weather
+	8,  // 3: weather.v1.BatchItem.error:type_name -> weather.v1.Error
+	6,  // 4: weather.v1.Weather.temperature:type_name -> weather.v1.Temperature
+	7,  // 5: weather.v1.Weather.indices:type_name -> weather.v1.ThermalIndices
+	6,  // 6: weather.v1.ThermalIndices.heat_index:type_name -> weather.v1.Temperature
+	6,  // 7: weather.v1.ThermalIndices.wind_chill:type_name -> weather.v1.Temperature
+	6,  // 8: weather.v1.ThermalIndices.dew_point:type_name -> weather.v1.Temperature
+	6,  // 9: weather.v1.ThermalIndices.humidex:type_name -> weather.v1.Temperature
+	6,  // 10: weather.v1.ThermalIndices.apparent_temperature:type_name -> weather.v1.Temperature
+	0,  // 11: weather.v1.WeatherService.GetByCEP:input_type -> weather.v1.GetByCEPRequest
+	2,  // 12: weather.v1.WeatherService.BatchGetByCEP:input_type -> weather.v1.BatchGetByCEPRequest
+	1,  // 13: weather.v1.WeatherService.GetByCEP:output_type -> weather.v1.GetByCEPResponse
+	3,  // 14: weather.v1.WeatherService.BatchGetByCEP:output_type -> weather.v1.BatchGetByCEPResponse
+	13, // [13:15] is the sub-list for method output_type
+	11, // [11:13] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_weather_v1_weather_proto_init() }
+func file_weather_v1_weather_proto_init() {
+	if File_weather_v1_weather_proto != nil {
+		return
+	}
+	file_weather_v1_weather_proto_msgTypes[0].OneofWrappers = []any{}
+	file_weather_v1_weather_proto_msgTypes[2].OneofWrappers = []any{}
+	file_weather_v1_weather_proto_msgTypes[4].OneofWrappers = []any{
+		(*BatchItem_Weather)(nil),
+		(*BatchItem_Error)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_weather_v1_weather_proto_rawDesc), len(file_weather_v1_weather_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_weather_v1_weather_proto_goTypes,
+		DependencyIndexes: file_weather_v1_weather_proto_depIdxs,
+		MessageInfos:      file_weather_v1_weather_proto_msgTypes,
+	}.Build()
+	File_weather_v1_weather_proto = out.File
+	file_weather_v1_weather_proto_goTypes = nil
+	file_weather_v1_weather_proto_depIdxs = nil
+}
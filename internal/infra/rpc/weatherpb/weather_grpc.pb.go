@@ -0,0 +1,173 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: weather/v1/weather.proto
+
+// Serviço gRPC de clima por CEP, equivalente às rotas /v1/weather da API HTTP.
+
+package weatherpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	WeatherService_GetByCEP_FullMethodName      = "/weather.v1.WeatherService/GetByCEP"
+	WeatherService_BatchGetByCEP_FullMethodName = "/weather.v1.WeatherService/BatchGetByCEP"
+)
+
+// WeatherServiceClient is the client API for WeatherService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WeatherServiceClient interface {
+	// GetByCEP retorna a temperatura atual da cidade do CEP.
+	// Erros: INVALID_ARGUMENT (CEP ou precisão inválidos), NOT_FOUND (CEP ou cidade
+	// desconhecidos), RESOURCE_EXHAUSTED, UNAVAILABLE e DEADLINE_EXCEEDED (falhas dos
+	// provedores). O status traz um google.rpc.ErrorInfo com o código do catálogo
+	// de docs/errors.md em "reason" e, quando aplicável, um google.rpc.RetryInfo.
+	GetByCEP(ctx context.Context, in *GetByCEPRequest, opts ...grpc.CallOption) (*GetByCEPResponse, error)
+	// BatchGetByCEP consulta vários CEPs; cada item traz o clima ou o seu erro.
+	BatchGetByCEP(ctx context.Context, in *BatchGetByCEPRequest, opts ...grpc.CallOption) (*BatchGetByCEPResponse, error)
+}
+
+type weatherServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWeatherServiceClient(cc grpc.ClientConnInterface) WeatherServiceClient {
+	return &weatherServiceClient{cc}
+}
+
+func (c *weatherServiceClient) GetByCEP(ctx context.Context, in *GetByCEPRequest, opts ...grpc.CallOption) (*GetByCEPResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetByCEPResponse)
+	err := c.cc.Invoke(ctx, WeatherService_GetByCEP_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *weatherServiceClient) BatchGetByCEP(ctx context.Context, in *BatchGetByCEPRequest, opts ...grpc.CallOption) (*BatchGetByCEPResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchGetByCEPResponse)
+	err := c.cc.Invoke(ctx, WeatherService_BatchGetByCEP_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WeatherServiceServer is the server API for WeatherService service.
+// All implementations must embed UnimplementedWeatherServiceServer
+// for forward compatibility.
+type WeatherServiceServer interface {
+	// GetByCEP retorna a temperatura atual da cidade do CEP.
+	// Erros: INVALID_ARGUMENT (CEP ou precisão inválidos), NOT_FOUND (CEP ou cidade
+	// desconhecidos), RESOURCE_EXHAUSTED, UNAVAILABLE e DEADLINE_EXCEEDED (falhas dos
+	// provedores). O status traz um google.rpc.ErrorInfo com o código do catálogo
+	// de docs/errors.md em "reason" e, quando aplicável, um google.rpc.RetryInfo.
+	GetByCEP(context.Context, *GetByCEPRequest) (*GetByCEPResponse, error)
+	// BatchGetByCEP consulta vários CEPs; cada item traz o clima ou o seu erro.
+	BatchGetByCEP(context.Context, *BatchGetByCEPRequest) (*BatchGetByCEPResponse, error)
+	mustEmbedUnimplementedWeatherServiceServer()
+}
+
+// UnimplementedWeatherServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedWeatherServiceServer struct{}
+
+func (UnimplementedWeatherServiceServer) GetByCEP(context.Context, *GetByCEPRequest) (*GetByCEPResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetByCEP not implemented")
+}
+func (UnimplementedWeatherServiceServer) BatchGetByCEP(context.Context, *BatchGetByCEPRequest) (*BatchGetByCEPResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetByCEP not implemented")
+}
+func (UnimplementedWeatherServiceServer) mustEmbedUnimplementedWeatherServiceServer() {}
+func (UnimplementedWeatherServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeWeatherServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WeatherServiceServer will
+// result in compilation errors.
+type UnsafeWeatherServiceServer interface {
+	mustEmbedUnimplementedWeatherServiceServer()
+}
+
+func RegisterWeatherServiceServer(s grpc.ServiceRegistrar, srv WeatherServiceServer) {
+	// If the following call pancis, it indicates UnimplementedWeatherServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&WeatherService_ServiceDesc, srv)
+}
+
+func _WeatherService_GetByCEP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetByCEPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WeatherServiceServer).GetByCEP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WeatherService_GetByCEP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WeatherServiceServer).GetByCEP(ctx, req.(*GetByCEPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WeatherService_BatchGetByCEP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetByCEPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WeatherServiceServer).BatchGetByCEP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WeatherService_BatchGetByCEP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WeatherServiceServer).BatchGetByCEP(ctx, req.(*BatchGetByCEPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WeatherService_ServiceDesc is the grpc.ServiceDesc for WeatherService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WeatherService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "weather.v1.WeatherService",
+	HandlerType: (*WeatherServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetByCEP",
+			Handler:    _WeatherService_GetByCEP_Handler,
+		},
+		{
+			MethodName: "BatchGetByCEP",
+			Handler:    _WeatherService_BatchGetByCEP_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "weather/v1/weather.proto",
+}
@@ -0,0 +1,46 @@
+package web
+
+import (
+	"github.com/MchlAlex/fc-lab02/config"
+	"github.com/MchlAlex/fc-lab02/handler"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// NewAdminRouter monta as rotas do listener de administração: pprof,
+// configuração efetiva, caches, circuit breakers e versão. Elas não existem no
+// roteador público. Com ADMIN_TOKEN, todas as rotas exigem o token; sem ele,
+// quem as protege é o endereço do listener (ADMIN_ADDR), que a validação só
+// aceita em loopback.
+func NewAdminRouter(cfg *config.Config, deps Dependencies) *chi.Mux {
+	logger := logging.Or(deps.Logger)
+	current := func() map[string]any { return cfg.Redacted() }
+	if deps.current != nil {
+		current = func() map[string]any { return deps.current.Load().Redacted() } // Reflete as recargas
+	}
+	adminHandler := handler.NewAdminHandler(current, deps.Caches, deps.Breakers)
+	adminHandler.Logger = logger
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(requestLogger(logger))
+	r.Use(recoverer(logger))
+	if cfg.AdminToken != "" {
+		r.Use(adminOnly(cfg.AdminToken))
+	}
+	r.NotFound(handler.NotFound)
+	r.MethodNotAllowed(methodNotAllowed(r))
+
+	r.Get("/config", adminHandler.GetConfig)
+	r.Get("/buildinfo", adminHandler.GetBuildInfo)
+	r.Get("/caches", adminHandler.ListCaches)
+	r.Get("/breakers", adminHandler.ListBreakers)
+	r.Mount("/debug", middleware.Profiler()) // /debug/pprof/ e /debug/vars
+	r.Post("/caches/{name}/flush", adminHandler.FlushCache)
+	r.Delete("/caches/{name}/keys/{key}", adminHandler.EvictCacheKey)
+	r.Post("/breakers/{provider}/open", adminHandler.OpenBreaker)
+	r.Post("/breakers/{provider}/close", adminHandler.CloseBreaker)
+	return r
+}
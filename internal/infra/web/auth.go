@@ -0,0 +1,181 @@
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/MchlAlex/fc-lab02/handler"
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+)
+
+// APIKeyHeader é o header com a chave de API do cliente.
+const APIKeyHeader = "X-API-Key"
+
+// authRealm é o realm informado no header WWW-Authenticate.
+const authRealm = "fc-lab02"
+
+// Authenticator identifica o consumidor de cada requisição pela chave de API,
+// enviada em X-API-Key ou em Authorization: Bearer.
+type Authenticator struct {
+	Store    service.APIKeyStore
+	Required bool // Recusa requisições sem chave; sem ele, só chaves inválidas são recusadas
+	Logger   *slog.Logger
+}
+
+type authContextKey struct{}
+
+// authResult é o que Identify descobriu sobre a requisição.
+type authResult struct {
+	key *entity.APIKey
+	err error // Chave enviada, mas desconhecida ou revogada
+}
+
+// ConsumerFromContext retorna a chave de API autenticada na requisição, se houver.
+func ConsumerFromContext(ctx context.Context) (*entity.APIKey, bool) {
+	res, _ := ctx.Value(authContextKey{}).(*authResult)
+	if res == nil || res.key == nil {
+		return nil, false
+	}
+	return res.key, true
+}
+
+// consumerID retorna o ID da chave de API autenticada na requisição, ou vazio.
+func consumerID(r *http.Request) string {
+	if key, ok := ConsumerFromContext(r.Context()); ok {
+		return key.ID
+	}
+	return ""
+}
+
+// requestAPIKey extrai a chave de X-API-Key ou, na falta dele, de Authorization: Bearer.
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// Identify resolve a chave de API e guarda o consumidor no contexto, para o
+// limite por cliente, e nos campos de log da requisição (consumer e
+// api_key_id). Não recusa nada: quem exige a chave é Require. Um autenticador
+// nil não identifica ninguém.
+func (a *Authenticator) Identify(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := a.lookup(r.Context(), requestAPIKey(r))
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authContextKey{}, res)))
+	})
+}
+
+// Require exige uma chave válida com o escopo informado. Sem Required, as
+// requisições sem chave passam, mas uma chave inválida ainda é recusada.
+func (a *Authenticator) Require(scope string) func(http.Handler) http.Handler {
+	return a.require(scope, false)
+}
+
+// RequireKey é Require para os recursos que pertencem a uma chave, como as
+// assinaturas: a chave é exigida mesmo sem Required, já que sem ela todos os
+// anônimos dividiriam o mesmo dono.
+func (a *Authenticator) RequireKey(scope string) func(http.Handler) http.Handler {
+	return a.require(scope, true)
+}
+
+func (a *Authenticator) require(scope string, always bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if a == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, _ := r.Context().Value(authContextKey{}).(*authResult)
+			if err := a.authorize(res, scope, always || a.Required); err != nil {
+				switch err.Code {
+				case apperr.CodeMissingAPIKey:
+					w.Header().Set("WWW-Authenticate", `Bearer realm="`+authRealm+`"`)
+				case apperr.CodeInvalidAPIKey:
+					w.Header().Set("WWW-Authenticate", `Bearer realm="`+authRealm+`", error="invalid_token"`)
+				case apperr.CodeInsufficientScope:
+					w.Header().Set("WWW-Authenticate", `Bearer realm="`+authRealm+`", error="insufficient_scope", scope="`+scope+`"`)
+				}
+				handler.WriteProblem(w, r, err.Code, err.Detail)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Authenticate faz, fora do HTTP (ex: no gRPC), o que Identify e Require fazem
+// com uma requisição: resolve a chave raw e confere o escopo. A chave é nil
+// quando nenhuma foi enviada e ela não é exigida. Um autenticador nil aceita tudo.
+func (a *Authenticator) Authenticate(ctx context.Context, raw, scope string) (*entity.APIKey, error) {
+	if a == nil {
+		return nil, nil
+	}
+	res := a.lookup(ctx, raw)
+	if err := a.authorize(res, scope, a.Required); err != nil {
+		return nil, err
+	}
+	return res.key, nil
+}
+
+// lookup resolve a chave enviada (raw vazia quando não há nenhuma) e a registra
+// nos campos de log.
+func (a *Authenticator) lookup(ctx context.Context, raw string) *authResult {
+	res := &authResult{}
+	if raw == "" {
+		return res
+	}
+	res.key, res.err = a.Store.FindAPIKeyByHash(ctx, service.HashAPIKey(raw))
+	if res.err != nil && !errors.Is(res.err, service.ErrAPIKeyNotFound) {
+		logging.Or(a.Logger).ErrorContext(ctx, "error looking up API key", "error", res.err)
+	}
+	if res.key != nil {
+		logging.Add(ctx, slog.String("consumer", res.key.Name), slog.String("api_key_id", res.key.ID))
+	}
+	return res
+}
+
+// authorize decide se o resultado de lookup dá acesso ao escopo informado;
+// required recusa também as requisições sem chave.
+func (a *Authenticator) authorize(res *authResult, scope string, required bool) *apperr.Error {
+	switch {
+	case res == nil || (res.key == nil && res.err == nil):
+		if required {
+			return &apperr.Error{Code: apperr.CodeMissingAPIKey, Detail: "send an API key in the X-API-Key header or as a Bearer token"}
+		}
+	case res.key == nil:
+		return &apperr.Error{Code: apperr.CodeInvalidAPIKey, Detail: "the API key is unknown or was revoked"}
+	case !service.HasScope(res.key, scope):
+		return &apperr.Error{Code: apperr.CodeInsufficientScope, Detail: "the API key does not grant the " + scope + " scope"}
+	}
+	return nil
+}
+
+// adminOnly protege as rotas de administração com o token ADMIN_TOKEN, enviado
+// como Authorization: Bearer.
+func adminOnly(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, given, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(given)), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+authRealm+` admin"`)
+				handler.WriteProblem(w, r, apperr.CodeInvalidAPIKey, "admin routes require the admin token as a Bearer token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
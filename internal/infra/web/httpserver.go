@@ -0,0 +1,82 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/config"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+)
+
+// defaultPort é a porta HTTP quando WEB_SERVER_PORT não está definida.
+const defaultPort = "8080"
+
+// NewHTTPServer cria o servidor HTTP com os limites da configuração. Erros do
+// próprio servidor (ex: cabeçalhos inválidos) saem no logger como avisos.
+func NewHTTPServer(cfg *config.Config, handler http.Handler, logger *slog.Logger) *http.Server {
+	port := cfg.WebServerPort
+	if port == "" {
+		port = defaultPort
+	}
+	return &http.Server{
+		Addr:              ":" + port,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+		MaxHeaderBytes:    cfg.HTTPMaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(logging.Or(logger).Handler(), slog.LevelWarn),
+	}
+}
+
+// NewAdminServer cria o servidor do listener de administração. Não há limite
+// de escrita: os perfis do pprof duram o que o parâmetro seconds pedir.
+func NewAdminServer(cfg *config.Config, handler http.Handler, logger *slog.Logger) *http.Server {
+	return &http.Server{
+		Addr:              cfg.AdminAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+		MaxHeaderBytes:    cfg.HTTPMaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(logging.Or(logger).Handler(), slog.LevelWarn),
+	}
+}
+
+// Serve atende em ln até ctx ser cancelado e então para de aceitar conexões e
+// espera as requisições em andamento por até timeout. As que não terminam no
+// prazo têm a conexão fechada e Serve retorna o erro do prazo. Funções
+// registradas com srv.RegisterOnShutdown (ex: encerrar streams) rodam no
+// início da espera. Com srv.TLSConfig, atende HTTPS.
+func Serve(ctx context.Context, srv *http.Server, ln net.Listener, timeout time.Duration) error {
+	errc := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			errc <- srv.ServeTLS(ln, "", "") // O certificado vem de TLSConfig
+			return
+		}
+		errc <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err // Falhou antes de um pedido de desligamento
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("could not drain HTTP connections: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
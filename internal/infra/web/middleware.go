@@ -0,0 +1,131 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/handler"
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recoverer recupera panics, registra o stack trace e responde com um
+// problem+json 500 no lugar da resposta vazia do middleware.Recoverer do chi.
+func recoverer(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rvr := recover()
+				if rvr == nil {
+					return
+				}
+				if rvr == http.ErrAbortHandler {
+					// Não recuperamos http.ErrAbortHandler para que a resposta seja abortada
+					panic(rvr)
+				}
+				logger.ErrorContext(r.Context(), "panic serving request", "panic", fmt.Sprint(rvr), "stack", string(debug.Stack()))
+				if r.Header.Get("Connection") != "Upgrade" {
+					handler.WriteProblem(w, r, apperr.CodeInternalError, "")
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// methodNotAllowed responde com problem+json 405 e o header Allow com os métodos aceitos pela rota.
+func methodNotAllowed(router chi.Routes) http.HandlerFunc {
+	methods := []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, m := range methods {
+			if router.Match(chi.NewRouteContext(), m, r.URL.Path) {
+				w.Header().Add("Allow", m)
+			}
+		}
+		handler.WriteProblem(w, r, apperr.CodeMethodNotAllowed, r.Method+" is not supported on "+r.URL.Path)
+	}
+}
+
+// deprecated marca as respostas de uma rota obsoleta com os headers Deprecation
+// (RFC 9745), Sunset (RFC 8594) e o Link para a rota equivalente da versão
+// sucessora, obtida prefixando o caminho com successorPrefix.
+func deprecated(deprecatedAt, sunset time.Time, successorPrefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(deprecatedAt.Unix(), 10))
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			w.Header().Add("Link", "<"+successorPrefix+r.URL.Path+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// probePaths são as rotas atendidas sem certificado de cliente com mTLS: as
+// sondas httpGet do Kubernetes e do Cloud Run não apresentam certificado.
+var probePaths = map[string]bool{"/livez": true, "/readyz": true}
+
+// requireClientCert exige, com mTLS, o certificado de cliente que o handshake
+// deixou opcional (tls.VerifyClientCertIfGiven), exceto nas sondas. Conexões
+// sem TLS, como nos testes, passam.
+func requireClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.PeerCertificates) == 0 && !probePaths[r.URL.Path] {
+			handler.WriteProblem(w, r, apperr.CodeClientCertRequired, "this server requires a TLS client certificate")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestLogger abre os campos da requisição no contexto (ID, método e
+// caminho) e, ao fim, registra uma linha com o status, a duração, o consumidor
+// e tudo o que os handlers e serviços acrescentaram (CEP, cidade, provedor,
+// latências e código de erro). Precisa rodar depois de middleware.RequestID e
+// do middleware de tracing, de onde vem o trace_id.
+func requestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := logging.WithFields(r.Context(),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("consumer", "anonymous"), // Authenticator.Identify substitui pelo nome da chave
+			)
+			if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+				logging.Add(ctx, slog.String("trace_id", sc.TraceID().String()))
+			}
+			if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 { // Cliente identificado pelo mTLS
+				logging.Add(ctx, slog.String("client_cert", r.TLS.PeerCertificates[0].Subject.CommonName))
+			}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK // Nada escrito, como em um handler vazio
+			}
+			outcome, level := "success", slog.LevelInfo
+			switch {
+			case status >= 500:
+				outcome, level = "server_error", slog.LevelError
+			case status >= 400:
+				outcome = "client_error"
+			}
+			logger.LogAttrs(ctx, level, "http request",
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				logging.Latency("duration", time.Since(start)),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("outcome", outcome))
+		})
+	}
+}
@@ -0,0 +1,864 @@
+package web
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/handler"
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// serveOpenAPI é o handler para a rota GET /openapi.json.
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(OpenAPISpec())
+}
+
+// serveDocs é o handler para a rota GET /docs (página estática, sem dependências externas).
+func serveDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(docsPage)
+}
+
+// OpenAPISpec gera o documento OpenAPI 3.1 da API.
+//
+// Os schemas são derivados por reflexão dos tipos de entity (pelas tags json),
+// de modo que um campo novo aparece na especificação sem edição manual. Os
+// códigos de erro vêm do catálogo de apperr.Catalog.
+func OpenAPISpec() map[string]any {
+	g := &schemaGenerator{schemas: map[string]any{}}
+	weather := g.schema(reflect.TypeOf(entity.WeatherOutput{}))
+	weatherV2 := g.schema(reflect.TypeOf(entity.WeatherOutputV2{}))
+	batchItems := map[string]any{"type": "array", "items": g.schema(reflect.TypeOf(entity.BatchItem{}))}
+	subscription := g.schema(reflect.TypeOf(entity.Subscription{}))
+	subscriptionInput := g.schema(reflect.TypeOf(entity.SubscriptionInput{}))
+	subscriptionUpdate := g.schema(reflect.TypeOf(entity.SubscriptionUpdate{}))
+	deadLetters := map[string]any{"type": "array", "items": g.schema(reflect.TypeOf(entity.DeadLetter{}))}
+	apiKey := g.schema(reflect.TypeOf(entity.APIKey{}))
+	apiKeyInput := g.schema(reflect.TypeOf(entity.APIKeyInput{}))
+	readiness := g.schema(reflect.TypeOf(entity.ReadinessReport{}))
+	g.schema(reflect.TypeOf(entity.Problem{}))
+	g.schemas["Problem"].(map[string]any)["properties"].(map[string]any)["code"].(map[string]any)["enum"] = errorCodes()
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "fc-lab02 Weather API",
+			"version":     "2.0.0",
+			"description": "Temperatura atual (°C, °F e K) e índices térmicos da cidade correspondente a um CEP brasileiro.",
+		},
+		"paths": withRateLimits(withAuth(map[string]any{
+			"/v1/weather/{cep}":        map[string]any{"get": weatherOperation("getWeatherByCEPV1", weather)},
+			"/v1/weather/batch":        map[string]any{"post": batchOperation("getWeatherBatchV1", batchItems)},
+			"/v2/weather/{cep}":        map[string]any{"get": weatherOperation("getWeatherByCEPV2", weatherV2)},
+			"/v1/weather/city/{name}":  map[string]any{"get": cityOperation("getWeatherByCityV1", weather)},
+			"/v2/weather/city/{name}":  map[string]any{"get": cityOperation("getWeatherByCityV2", weatherV2)},
+			"/v1/weather/coords":       map[string]any{"get": coordsOperation("getWeatherByCoordsV1", weather)},
+			"/v2/weather/coords":       map[string]any{"get": coordsOperation("getWeatherByCoordsV2", weatherV2)},
+			"/v1/weather/me":           map[string]any{"get": clientIPOperation("getWeatherByClientIPV1", weather)},
+			"/v2/weather/me":           map[string]any{"get": clientIPOperation("getWeatherByClientIPV2", weatherV2)},
+			"/v1/weather/{cep}/stream": map[string]any{"get": streamOperation("streamWeatherByCEPV1", weather)},
+			"/v2/weather/{cep}/stream": map[string]any{"get": streamOperation("streamWeatherByCEPV2", weatherV2)},
+			"/v1/weather/{cep}/ws":     map[string]any{"get": webSocketOperation("watchWeatherByCEPV1", weather)},
+			"/v2/weather/{cep}/ws":     map[string]any{"get": webSocketOperation("watchWeatherByCEPV2", weatherV2)},
+			"/weather/{cep}/stream":    map[string]any{"get": deprecatedOperation(streamOperation("streamWeatherByCEP", weather), "/v1/weather/{cep}/stream")},
+			"/weather/{cep}/ws":        map[string]any{"get": deprecatedOperation(webSocketOperation("watchWeatherByCEP", weather), "/v1/weather/{cep}/ws")},
+			"/weather/{cep}":           map[string]any{"get": deprecatedOperation(weatherOperation("getWeatherByCEP", weather), "/v1/weather/{cep}")},
+			"/weather/batch":           map[string]any{"post": deprecatedOperation(batchOperation("getWeatherBatch", batchItems), "/v1/weather/batch")},
+			"/v2/subscriptions": map[string]any{
+				"post": createSubscriptionOperation(subscription, subscriptionInput),
+				"get":  subscriptionOperation("listSubscriptions", "Lista as assinaturas", map[string]any{"type": "array", "items": subscription}, false),
+			},
+			"/v2/subscriptions/{id}": map[string]any{
+				"get":    subscriptionOperation("getSubscription", "Consulta uma assinatura", subscription, true),
+				"patch":  updateSubscriptionOperation(subscription, subscriptionUpdate),
+				"delete": deleteSubscriptionOperation(),
+			},
+			"/v2/subscriptions/dead-letters": map[string]any{
+				"get": subscriptionOperation("listDeadLetters", "Avisos não entregues após todas as tentativas", deadLetters, false),
+			},
+			"/admin/keys": map[string]any{
+				"post": createAPIKeyOperation(apiKey, apiKeyInput),
+				"get":  apiKeyOperation("listAPIKeys", "Lista as chaves de API, inclusive as revogadas", map[string]any{"type": "array", "items": apiKey}, false),
+			},
+			"/admin/keys/{id}": map[string]any{
+				"get":    apiKeyOperation("getAPIKey", "Consulta uma chave de API", apiKey, true),
+				"delete": revokeAPIKeyOperation(),
+			},
+			"/admin/keys/{id}/rotate": map[string]any{
+				"post": rotateAPIKeyOperation(apiKey),
+			},
+			"/health": map[string]any{
+				"get": map[string]any{
+					"operationId": "health",
+					"summary":     "Health check",
+					"responses": map[string]any{
+						"200": map[string]any{"description": "Servidor no ar", "content": textContent()},
+					},
+				},
+			},
+			"/livez": map[string]any{
+				"get": map[string]any{
+					"operationId": "livez",
+					"summary":     "Liveness: o processo está atendendo requisições",
+					"responses": map[string]any{
+						"200": map[string]any{
+							"description": "Processo no ar; não depende dos provedores",
+							"content": map[string]any{"application/json": map[string]any{"schema": map[string]any{
+								"type": "object", "properties": map[string]any{"status": map[string]any{"const": "pass"}}, "required": []string{"status"},
+							}}},
+						},
+					},
+				},
+			},
+			"/readyz": map[string]any{
+				"get": map[string]any{
+					"operationId": "readyz",
+					"summary":     "Readiness: configuração, provedores e circuit breakers",
+					"responses": map[string]any{
+						"200": map[string]any{
+							"description": "Pronta para receber tráfego",
+							"content":     map[string]any{"application/json": map[string]any{"schema": readiness}},
+						},
+						"503": map[string]any{
+							"description": "Alguma verificação falhou; o detalhamento por dependência vem no corpo",
+							"content":     map[string]any{"application/json": map[string]any{"schema": readiness}},
+						},
+					},
+				},
+			},
+			"/metrics": map[string]any{
+				"get": map[string]any{
+					"operationId": "metrics",
+					"summary":     "Métricas no formato de exposição do Prometheus",
+					"responses": map[string]any{
+						"200": map[string]any{"description": "Requisições, chamadas aos provedores e caches", "content": textContent()},
+					},
+				},
+			},
+			"/openapi.json": map[string]any{
+				"get": map[string]any{
+					"operationId": "openapi",
+					"summary":     "Esta especificação OpenAPI",
+					"responses": map[string]any{
+						"200": map[string]any{
+							"description": "Documento OpenAPI 3.1",
+							"content":     map[string]any{"application/json": map[string]any{"schema": map[string]any{"type": "object"}}},
+						},
+					},
+				},
+			},
+			"/docs": map[string]any{
+				"get": map[string]any{
+					"operationId": "docs",
+					"summary":     "Documentação interativa",
+					"responses": map[string]any{
+						"200": map[string]any{
+							"description": "Página HTML que consome /openapi.json",
+							"content":     map[string]any{"text/html": map[string]any{"schema": map[string]any{"type": "string"}}},
+						},
+					},
+				},
+			},
+		})),
+		"components": map[string]any{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{
+					"type": "apiKey", "in": "header", "name": APIKeyHeader,
+					"description": "Chave de API do consumidor, criada em POST /admin/keys.",
+				},
+				"bearer": map[string]any{
+					"type": "http", "scheme": "bearer",
+					"description": "A mesma chave de API, enviada como `Authorization: Bearer <chave>`.",
+				},
+				"adminToken": map[string]any{
+					"type": "http", "scheme": "bearer",
+					"description": "Token ADMIN_TOKEN das rotas de administração.",
+				},
+			},
+			"parameters": map[string]any{
+				"cep": map[string]any{
+					"name": "cep", "in": "path", "required": true,
+					"description": "CEP de 8 dígitos, sem hífen. Outros formatos retornam 422.",
+					"schema":      map[string]any{"type": "string", "examples": []any{"01001000"}},
+				},
+				"precision": map[string]any{
+					"name": "precision", "in": "query", "required": false,
+					"description": "Casas decimais aplicadas a todas as unidades, sobrescrevendo a configuração do servidor.",
+					"schema":      map[string]any{"type": "integer", "minimum": 0, "maximum": service.MaxPrecision},
+				},
+				"ifNoneMatch": map[string]any{
+					"name": "If-None-Match", "in": "header", "required": false,
+					"description": "ETag de uma resposta anterior; se ainda for o atual, a resposta é 304 sem corpo.",
+					"schema":      map[string]any{"type": "string"},
+				},
+				"ifModifiedSince": map[string]any{
+					"name": "If-Modified-Since", "in": "header", "required": false,
+					"description": "Data HTTP; se a observação não mudou desde então, a resposta é 304. Ignorado quando há If-None-Match.",
+					"schema":      map[string]any{"type": "string"},
+				},
+				"subscriptionId": map[string]any{
+					"name": "id", "in": "path", "required": true,
+					"description": "ID da assinatura, retornado na criação.",
+					"schema":      map[string]any{"type": "string"},
+				},
+				"apiKeyId": map[string]any{
+					"name": "id", "in": "path", "required": true,
+					"description": "ID da chave de API, retornado na criação.",
+					"schema":      map[string]any{"type": "string"},
+				},
+				"format": map[string]any{
+					"name": "format", "in": "query", "required": false,
+					"description": "Formato da resposta. Tem precedência sobre o header Accept.",
+					"schema":      map[string]any{"type": "string", "enum": formats()},
+				},
+			},
+		},
+	}
+}
+
+func weatherOperation(id string, schema map[string]any) map[string]any {
+	responses := weatherResponses(schema)
+	responses["404"] = problemResponse("CEP não encontrado (`zipcode_not_found`) ou cidade desconhecida pela WeatherAPI (`location_unknown`)")
+	responses["422"] = problemResponse("CEP com formato inválido")
+	return map[string]any{
+		"operationId": id,
+		"summary":     "Temperatura atual da cidade de um CEP",
+		"parameters": []any{
+			map[string]any{"$ref": "#/components/parameters/cep"},
+			map[string]any{"$ref": "#/components/parameters/precision"},
+			map[string]any{"$ref": "#/components/parameters/format"},
+			map[string]any{"$ref": "#/components/parameters/ifNoneMatch"},
+			map[string]any{"$ref": "#/components/parameters/ifModifiedSince"},
+		},
+		"responses": responses,
+	}
+}
+
+func cityOperation(id string, schema map[string]any) map[string]any {
+	responses := weatherResponses(schema)
+	responses["400"] = problemResponse("UF (`invalid_state`) ou parâmetro `precision`/`format` inválido")
+	responses["404"] = problemResponse("Cidade desconhecida pela WeatherAPI")
+	responses["422"] = problemResponse("Nome de cidade inválido")
+	return map[string]any{
+		"operationId": id,
+		"summary":     "Temperatura atual de uma cidade brasileira pelo nome",
+		"parameters": []any{
+			map[string]any{
+				"name": "name", "in": "path", "required": true,
+				"description": "Nome da cidade (até 100 caracteres, sem vírgulas).",
+				"schema":      map[string]any{"type": "string", "examples": []any{"Campinas"}},
+			},
+			map[string]any{
+				"name": "uf", "in": "query", "required": false,
+				"description": "Sigla do estado, para desambiguar cidades homônimas.",
+				"schema":      map[string]any{"type": "string", "examples": []any{"SP"}},
+			},
+			map[string]any{"$ref": "#/components/parameters/precision"},
+			map[string]any{"$ref": "#/components/parameters/format"},
+			map[string]any{"$ref": "#/components/parameters/ifNoneMatch"},
+			map[string]any{"$ref": "#/components/parameters/ifModifiedSince"},
+		},
+		"responses": responses,
+	}
+}
+
+func coordsOperation(id string, schema map[string]any) map[string]any {
+	responses := weatherResponses(schema)
+	responses["400"] = problemResponse("Coordenadas (`invalid_coordinates`) ou parâmetro `precision`/`format` inválido")
+	responses["404"] = problemResponse("A WeatherAPI não conhece o local")
+	return map[string]any{
+		"operationId": id,
+		"summary":     "Temperatura atual em coordenadas geográficas",
+		"parameters": []any{
+			map[string]any{
+				"name": "lat", "in": "query", "required": true,
+				"description": "Latitude em graus decimais.",
+				"schema":      map[string]any{"type": "number", "minimum": -90, "maximum": 90, "examples": []any{-23.55}},
+			},
+			map[string]any{
+				"name": "lon", "in": "query", "required": true,
+				"description": "Longitude em graus decimais.",
+				"schema":      map[string]any{"type": "number", "minimum": -180, "maximum": 180, "examples": []any{-46.63}},
+			},
+			map[string]any{"$ref": "#/components/parameters/precision"},
+			map[string]any{"$ref": "#/components/parameters/format"},
+			map[string]any{"$ref": "#/components/parameters/ifNoneMatch"},
+			map[string]any{"$ref": "#/components/parameters/ifModifiedSince"},
+		},
+		"responses": responses,
+	}
+}
+
+func clientIPOperation(id string, schema map[string]any) map[string]any {
+	responses := weatherResponses(schema)
+	responses["404"] = problemResponse("O IP do cliente não pode ser localizado (IP privado ou desconhecido pela WeatherAPI)")
+	return map[string]any{
+		"operationId": id,
+		"summary":     "Temperatura atual no local do IP do cliente",
+		"description": "O IP é o da conexão; `X-Forwarded-For` só é considerado quando a conexão vem de um proxy em TRUSTED_PROXIES.",
+		"parameters": []any{
+			map[string]any{"$ref": "#/components/parameters/precision"},
+			map[string]any{"$ref": "#/components/parameters/format"},
+			map[string]any{"$ref": "#/components/parameters/ifNoneMatch"},
+			map[string]any{"$ref": "#/components/parameters/ifModifiedSince"},
+		},
+		"responses": responses,
+	}
+}
+
+// weatherResponses são as respostas comuns às rotas de clima; cada rota
+// completa as de 404 e 422 conforme a forma de localizar.
+func weatherResponses(schema map[string]any) map[string]any {
+	return map[string]any{
+		"200": map[string]any{"description": "Temperaturas do local", "content": negotiatedContent(schema), "headers": cacheHeaders()},
+		"304": map[string]any{"description": "A representação em cache do cliente ainda é a atual (If-None-Match ou If-Modified-Since)", "headers": cacheHeaders()},
+		"400": problemResponse("Parâmetro `precision` ou `format` inválido"),
+		"406": problemResponse("Nenhum formato do header Accept é suportado"),
+		"429": retryableProblemResponse("Limite ou cota do provedor esgotado"),
+		"500": problemResponse("Falha inesperada ao consultar a ViaCEP ou a WeatherAPI"),
+		"502": problemResponse("A WeatherAPI recusou a chave do servidor"),
+		"503": retryableProblemResponse("ViaCEP ou WeatherAPI indisponível"),
+		"504": problemResponse("ViaCEP ou WeatherAPI não respondeu a tempo"),
+	}
+}
+
+// cacheHeaders documenta os headers de cache das respostas de clima. Last-Modified
+// (momento da observação) só é enviado quando a WeatherAPI o informa.
+func cacheHeaders() map[string]any {
+	return map[string]any{
+		"ETag": map[string]any{
+			"description": "Validador da representação: forte na v1, fraco (`W/`) na v2, cujos metadados mudam a cada requisição",
+			"schema":      map[string]any{"type": "string"},
+		},
+		"Cache-Control": map[string]any{
+			"description": "`public, max-age=N`, com N igual ao tempo até a próxima atualização esperada da observação (CACHE_MAX_AGE); `private` nas requisições com chave de API e em /weather/me",
+			"schema":      map[string]any{"type": "string", "examples": []any{"public, max-age=540"}},
+		},
+	}
+}
+
+func batchOperation(id string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"operationId": id,
+		"summary":     "Temperatura atual de vários CEPs",
+		"description": "Consulta os CEPs em paralelo, com uma única chamada à WeatherAPI por cidade. Cada item traz `result` ou `error`.",
+		"parameters": []any{
+			map[string]any{"$ref": "#/components/parameters/precision"},
+			map[string]any{"$ref": "#/components/parameters/format"},
+		},
+		"requestBody": map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{
+					"schema": map[string]any{
+						"type":     "array",
+						"minItems": 1,
+						"items":    map[string]any{"type": "string", "examples": []any{"01001000"}},
+					},
+				},
+			},
+		},
+		"responses": map[string]any{
+			"200": map[string]any{"description": "Um item por CEP, na ordem do pedido", "content": negotiatedContent(schema)},
+			"400": problemResponse("Corpo inválido ou parâmetro `precision`/`format` inválido"),
+			"406": problemResponse("Nenhum formato do header Accept é suportado"),
+			"413": problemResponse("Lote maior que BATCH_MAX_SIZE ou corpo acima do limite de bytes"),
+		},
+	}
+}
+
+func createSubscriptionOperation(schema, input map[string]any) map[string]any {
+	return map[string]any{
+		"operationId": "createSubscription",
+		"summary":     "Cadastra um webhook avisado quando a temperatura de um CEP cruza um limite",
+		"description": "A condição é verificada a cada SUBSCRIPTION_CHECK_INTERVAL; quando passa de falsa para verdadeira, o callback recebe um POST " +
+			"assinado (header `X-Webhook-Signature: t=<unix>,v1=<HMAC-SHA256 de \"<t>.<corpo>\" com o `secret`>`). O `secret` só é exibido nesta resposta.",
+		"parameters": []any{map[string]any{"$ref": "#/components/parameters/format"}},
+		"requestBody": map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": input}},
+		},
+		"responses": map[string]any{
+			"201": map[string]any{
+				"description": "Assinatura criada",
+				"content":     structuredContent(schema),
+				"headers": map[string]any{
+					"Location": map[string]any{"description": "URL da assinatura criada", "schema": map[string]any{"type": "string"}},
+				},
+			},
+			"400": problemResponse("Corpo inválido, CEP ausente ou parâmetro `format` inválido"),
+			"404": problemResponse("CEP não encontrado"),
+			"406": problemResponse("Nenhum formato do header Accept é suportado"),
+			"422": problemResponse("CEP, condição (`invalid_condition`) ou URL de callback (`invalid_callback_url`) inválidos"),
+			"429": retryableProblemResponse("Limite ou cota da ViaCEP esgotado"),
+			"500": problemResponse("Falha ao consultar a ViaCEP ou ao gravar a assinatura"),
+			"503": retryableProblemResponse("ViaCEP indisponível"),
+			"504": problemResponse("ViaCEP não respondeu a tempo"),
+		},
+	}
+}
+
+func subscriptionOperation(id, summary string, schema map[string]any, byID bool) map[string]any {
+	params := []any{map[string]any{"$ref": "#/components/parameters/format"}}
+	responses := map[string]any{
+		"200": map[string]any{"description": summary, "content": structuredContent(schema)},
+		"400": problemResponse("Parâmetro `format` inválido"),
+		"406": problemResponse("Nenhum formato do header Accept é suportado"),
+	}
+	if byID {
+		params = append([]any{map[string]any{"$ref": "#/components/parameters/subscriptionId"}}, params...)
+		responses["404"] = problemResponse("Assinatura não encontrada")
+	}
+	return map[string]any{"operationId": id, "summary": summary, "parameters": params, "responses": responses}
+}
+
+func updateSubscriptionOperation(schema, input map[string]any) map[string]any {
+	return map[string]any{
+		"operationId": "updateSubscription",
+		"summary":     "Altera o CEP, a condição ou a URL de callback de uma assinatura",
+		"description": "Os campos omitidos mantêm o valor atual. Mudar o CEP ou a condição zera o estado da última verificação, " +
+			"então o novo limite é avisado assim que for cruzado. O `secret` não muda.",
+		"parameters": []any{
+			map[string]any{"$ref": "#/components/parameters/subscriptionId"},
+			map[string]any{"$ref": "#/components/parameters/format"},
+		},
+		"requestBody": map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": input}},
+		},
+		"responses": map[string]any{
+			"200": map[string]any{"description": "Assinatura alterada", "content": structuredContent(schema)},
+			"400": problemResponse("Corpo inválido ou sem alterações, ou parâmetro `format` inválido"),
+			"404": problemResponse("Assinatura ou CEP não encontrados"),
+			"406": problemResponse("Nenhum formato do header Accept é suportado"),
+			"422": problemResponse("CEP, condição (`invalid_condition`) ou URL de callback (`invalid_callback_url`) inválidos"),
+			"429": retryableProblemResponse("Limite ou cota da ViaCEP esgotado"),
+			"500": problemResponse("Falha ao consultar a ViaCEP ou ao gravar a assinatura"),
+			"503": retryableProblemResponse("ViaCEP indisponível"),
+			"504": problemResponse("ViaCEP não respondeu a tempo"),
+		},
+	}
+}
+
+func deleteSubscriptionOperation() map[string]any {
+	return map[string]any{
+		"operationId": "deleteSubscription",
+		"summary":     "Remove uma assinatura",
+		"parameters":  []any{map[string]any{"$ref": "#/components/parameters/subscriptionId"}},
+		"responses": map[string]any{
+			"204": map[string]any{"description": "Assinatura removida"},
+			"404": problemResponse("Assinatura não encontrada"),
+		},
+	}
+}
+
+func createAPIKeyOperation(schema, input map[string]any) map[string]any {
+	return adminOperation(map[string]any{
+		"operationId": "createAPIKey",
+		"summary":     "Cria uma chave de API para um consumidor",
+		"description": "Sem `scopes`, a chave recebe só `weather:read`; sem `quota` (`limite/janela[:rajada]`), usa o tier `authenticated` de RATE_LIMIT_TIERS. " +
+			"O valor da chave (`key`) só é exibido nesta resposta e na rotação; o servidor guarda apenas o hash.",
+		"parameters": []any{map[string]any{"$ref": "#/components/parameters/format"}},
+		"requestBody": map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": input}},
+		},
+		"responses": map[string]any{
+			"201": map[string]any{
+				"description": "Chave criada",
+				"content":     structuredContent(schema),
+				"headers": map[string]any{
+					"Location": map[string]any{"description": "URL da chave criada", "schema": map[string]any{"type": "string"}},
+				},
+			},
+			"400": problemResponse("Corpo inválido, nome ausente ou parâmetro `format` inválido"),
+			"406": problemResponse("Nenhum formato do header Accept é suportado"),
+			"422": problemResponse("Escopo (`invalid_scope`) ou cota (`invalid_quota`) inválidos"),
+			"500": problemResponse("Falha ao gravar a chave"),
+		},
+	})
+}
+
+func apiKeyOperation(id, summary string, schema map[string]any, byID bool) map[string]any {
+	params := []any{map[string]any{"$ref": "#/components/parameters/format"}}
+	responses := map[string]any{
+		"200": map[string]any{"description": summary, "content": structuredContent(schema)},
+		"400": problemResponse("Parâmetro `format` inválido"),
+		"406": problemResponse("Nenhum formato do header Accept é suportado"),
+	}
+	if byID {
+		params = append([]any{map[string]any{"$ref": "#/components/parameters/apiKeyId"}}, params...)
+		responses["404"] = problemResponse("Chave não encontrada")
+	}
+	return adminOperation(map[string]any{"operationId": id, "summary": summary, "parameters": params, "responses": responses})
+}
+
+func rotateAPIKeyOperation(schema map[string]any) map[string]any {
+	return adminOperation(map[string]any{
+		"operationId": "rotateAPIKey",
+		"summary":     "Gera um novo valor para a chave, mantendo ID, escopos e cota",
+		"description": "O valor anterior deixa de autenticar imediatamente. O novo valor (`key`) só é exibido nesta resposta.",
+		"parameters": []any{
+			map[string]any{"$ref": "#/components/parameters/apiKeyId"},
+			map[string]any{"$ref": "#/components/parameters/format"},
+		},
+		"responses": map[string]any{
+			"200": map[string]any{"description": "Chave com o novo valor", "content": structuredContent(schema)},
+			"400": problemResponse("Parâmetro `format` inválido"),
+			"404": problemResponse("Chave não encontrada ou revogada"),
+			"406": problemResponse("Nenhum formato do header Accept é suportado"),
+		},
+	})
+}
+
+func revokeAPIKeyOperation() map[string]any {
+	return adminOperation(map[string]any{
+		"operationId": "revokeAPIKey",
+		"summary":     "Revoga uma chave de API",
+		"description": "A chave continua listada, com `revoked_at`, mas deixa de autenticar.",
+		"parameters":  []any{map[string]any{"$ref": "#/components/parameters/apiKeyId"}},
+		"responses": map[string]any{
+			"204": map[string]any{"description": "Chave revogada"},
+			"404": problemResponse("Chave não encontrada ou já revogada"),
+		},
+	})
+}
+
+// adminOperation documenta o token ADMIN_TOKEN exigido pelas rotas de administração.
+func adminOperation(op map[string]any) map[string]any {
+	op["security"] = []any{map[string]any{"adminToken": []any{}}}
+	op["responses"].(map[string]any)["401"] = authProblemResponse("Token de administração ausente ou incorreto (`invalid_api_key`)")
+	return op
+}
+
+// streamErrorResponses são as respostas de erro das rotas de streaming, sempre em problem+json.
+func streamErrorResponses() map[string]any {
+	return map[string]any{
+		"400": jsonProblemResponse("Parâmetro `precision` inválido"),
+		"404": jsonProblemResponse("CEP não encontrado"),
+		"422": jsonProblemResponse("CEP com formato inválido"),
+		"429": retryable(jsonProblemResponse("Limite ou cota da ViaCEP esgotado")),
+		"500": jsonProblemResponse("Falha inesperada ao consultar a ViaCEP"),
+		"503": retryable(jsonProblemResponse("ViaCEP indisponível (`upstream_unavailable`) ou limite de assinantes atingido (`stream_limit_reached`)")),
+		"504": jsonProblemResponse("ViaCEP não respondeu a tempo"),
+	}
+}
+
+func streamOperation(id string, schema map[string]any) map[string]any {
+	responses := streamErrorResponses()
+	responses["200"] = map[string]any{
+		"description": "Stream `text/event-stream`. Cada mudança da observação gera um evento com `id` e `data` no formato abaixo; " +
+			"falhas da WeatherAPI geram eventos `problem` com um Problem. Comentários `: heartbeat` mantêm a conexão aberta.",
+		"content": map[string]any{
+			"text/event-stream": map[string]any{"schema": map[string]any{"type": "string"}, "x-event-data": schema},
+		},
+	}
+	return map[string]any{
+		"operationId": id,
+		"summary":     "Leituras em tempo real da cidade de um CEP (Server-Sent Events)",
+		"parameters": []any{
+			map[string]any{"$ref": "#/components/parameters/cep"},
+			map[string]any{"$ref": "#/components/parameters/precision"},
+			map[string]any{
+				"name": "Last-Event-ID", "in": "header", "required": false,
+				"description": "ID do último evento recebido. Na reconexão, leituras já recebidas não são reenviadas.",
+				"schema":      map[string]any{"type": "string"},
+			},
+		},
+		"responses": responses,
+	}
+}
+
+func webSocketOperation(id string, schema map[string]any) map[string]any {
+	responses := streamErrorResponses()
+	responses["101"] = map[string]any{
+		"description": "Conexão WebSocket. Cada mensagem é um JSON `{\"type\": \"weather\", \"id\": ..., \"data\": ...}` com `data` no formato de `x-message-data`, " +
+			"ou `{\"type\": \"problem\", \"data\": Problem}` quando a consulta à WeatherAPI falha. O servidor envia pings periódicos.",
+		"x-message-data": schema,
+	}
+	responses["426"] = map[string]any{"description": "A requisição não é um handshake WebSocket", "content": textContent()}
+	return map[string]any{
+		"operationId": id,
+		"summary":     "Leituras em tempo real da cidade de um CEP (WebSocket)",
+		"parameters": []any{
+			map[string]any{"$ref": "#/components/parameters/cep"},
+			map[string]any{"$ref": "#/components/parameters/precision"},
+			map[string]any{
+				"name": "last_event_id", "in": "query", "required": false,
+				"description": "ID da última leitura recebida. Na reconexão, leituras já recebidas não são reenviadas.",
+				"schema":      map[string]any{"type": "integer", "minimum": 0},
+			},
+		},
+		"responses": responses,
+	}
+}
+
+// withRateLimits documenta o limite por cliente (RateLimiter) nas operações da
+// API: os headers RateLimit-* em todas as respostas e o 429 rate_limit_exceeded.
+func withRateLimits(paths map[string]any) map[string]any {
+	for path, item := range paths {
+		if !strings.HasPrefix(path, "/v1/") && !strings.HasPrefix(path, "/v2/") && !strings.HasPrefix(path, "/weather/") {
+			continue
+		}
+		for _, op := range item.(map[string]any) {
+			responses := op.(map[string]any)["responses"].(map[string]any)
+			if resp, ok := responses["429"].(map[string]any); ok {
+				resp["description"] = "Limite de requisições do cliente (`rate_limit_exceeded`) ou cota do provedor (`upstream_rate_limited`) esgotados"
+			} else {
+				responses["429"] = retryableProblemResponse("Limite de requisições do cliente esgotado (`rate_limit_exceeded`)")
+			}
+			for _, resp := range responses {
+				resp := resp.(map[string]any)
+				headers, _ := resp["headers"].(map[string]any)
+				if headers == nil {
+					headers = map[string]any{}
+					resp["headers"] = headers
+				}
+				for name, description := range rateLimitHeaders {
+					headers[name] = map[string]any{"description": description, "schema": map[string]any{"type": "string"}}
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// withAuth documenta a chave de API exigida (Authenticator.Require) nas
+// operações da API, com o escopo de cada uma e as respostas 401 e 403.
+func withAuth(paths map[string]any) map[string]any {
+	for path, item := range paths {
+		if !strings.HasPrefix(path, "/v1/") && !strings.HasPrefix(path, "/v2/") && !strings.HasPrefix(path, "/weather/") {
+			continue
+		}
+		for method, op := range item.(map[string]any) {
+			scope := service.ScopeWeatherRead
+			missing := "Chave de API ausente (`missing_api_key`, só com AUTH_REQUIRED) ou desconhecida/revogada (`invalid_api_key`)"
+			if strings.HasPrefix(path, "/v2/subscriptions") {
+				scope = service.ScopeSubscriptionsRead
+				if method != "get" {
+					scope = service.ScopeSubscriptionsWrite
+				}
+				missing = "Chave de API ausente (`missing_api_key`, exigida nas assinaturas mesmo sem AUTH_REQUIRED) ou desconhecida/revogada (`invalid_api_key`)"
+			}
+			op := op.(map[string]any)
+			op["security"] = []any{
+				map[string]any{"apiKey": []any{scope}},
+				map[string]any{"bearer": []any{scope}},
+			}
+			responses := op["responses"].(map[string]any)
+			responses["401"] = authProblemResponse(missing)
+			responses["403"] = authProblemResponse("A chave não concede o escopo `" + scope + "` (`insufficient_scope`)")
+		}
+	}
+	return paths
+}
+
+// authProblemResponse documenta o header WWW-Authenticate das recusas de autenticação.
+func authProblemResponse(description string) map[string]any {
+	resp := problemResponse(description)
+	resp["headers"] = map[string]any{
+		"WWW-Authenticate": map[string]any{
+			"description": "Esquema Bearer, com o erro e o escopo exigido (RFC 6750)",
+			"schema":      map[string]any{"type": "string"},
+		},
+	}
+	return resp
+}
+
+// rateLimitHeaders são os headers enviados pelo RateLimiter quando o limite está ativo.
+var rateLimitHeaders = map[string]string{
+	"RateLimit-Limit":     "Capacidade do balde de tokens do cliente (rajada máxima)",
+	"RateLimit-Remaining": "Requisições restantes antes do 429",
+	"RateLimit-Reset":     "Segundos até a cota se renovar por completo",
+	"RateLimit-Policy":    "Política do tier: `limite;w=janela em segundos;burst=rajada`",
+}
+
+// deprecatedOperation marca a operação como obsoleta e documenta os headers
+// que o middleware deprecated acrescenta a todas as suas respostas.
+func deprecatedOperation(op map[string]any, successor string) map[string]any {
+	op["deprecated"] = true
+	op["description"] = "Alias obsoleto de `" + successor + "`, removido em " + UnversionedSunset.Format(time.DateOnly) + "."
+	for _, resp := range op["responses"].(map[string]any) {
+		resp := resp.(map[string]any)
+		headers, _ := resp["headers"].(map[string]any)
+		if headers == nil {
+			headers = map[string]any{}
+			resp["headers"] = headers
+		}
+		headers["Deprecation"] = map[string]any{
+			"description": "Data em que a rota ficou obsoleta (RFC 9745)",
+			"schema":      map[string]any{"type": "string", "examples": []any{"@" + strconv.FormatInt(UnversionedDeprecatedAt.Unix(), 10)}},
+		}
+		headers["Sunset"] = map[string]any{
+			"description": "Data de remoção da rota (RFC 8594)",
+			"schema":      map[string]any{"type": "string", "examples": []any{UnversionedSunset.Format(http.TimeFormat)}},
+		}
+		headers["Link"] = map[string]any{
+			"description": "Rota equivalente na versão sucessora (rel=\"successor-version\")",
+			"schema":      map[string]any{"type": "string"},
+		}
+	}
+	return op
+}
+
+// negotiatedContent descreve os formatos de handler.NegotiateFormat para o schema informado.
+func negotiatedContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schema},
+		"application/xml":  map[string]any{"schema": schema},
+		"application/yaml": map[string]any{"schema": schema},
+		"text/csv":         map[string]any{"schema": map[string]any{"type": "string"}},
+		"text/plain":       map[string]any{"schema": map[string]any{"type": "string"}},
+	}
+}
+
+// structuredContent descreve os formatos que não têm representação em CSV e texto.
+func structuredContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schema},
+		"application/xml":  map[string]any{"schema": schema},
+		"application/yaml": map[string]any{"schema": schema},
+	}
+}
+
+func textContent() map[string]any {
+	return map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}}
+}
+
+func problemResponse(description string) map[string]any {
+	problem := map[string]any{"$ref": "#/components/schemas/Problem"}
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/problem+json": map[string]any{"schema": problem},
+			"application/problem+xml":  map[string]any{"schema": problem},
+			"application/yaml":         map[string]any{"schema": problem},
+			"text/csv":                 map[string]any{"schema": map[string]any{"type": "string"}},
+			"text/plain":               map[string]any{"schema": map[string]any{"type": "string"}},
+		},
+	}
+}
+
+func jsonProblemResponse(description string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/problem+json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Problem"}},
+		},
+	}
+}
+
+func retryableProblemResponse(description string) map[string]any {
+	return retryable(problemResponse(description))
+}
+
+// retryable documenta o header Retry-After das falhas temporárias.
+func retryable(resp map[string]any) map[string]any {
+	resp["headers"] = map[string]any{
+		"Retry-After": map[string]any{
+			"description": "Segundos a esperar antes de repetir a requisição",
+			"schema":      map[string]any{"type": "integer", "minimum": 1},
+		},
+	}
+	return resp
+}
+
+func errorCodes() []any {
+	var codes []string
+	for _, pt := range apperr.Catalog() {
+		codes = append(codes, string(pt.Code))
+	}
+	sort.Strings(codes)
+	out := make([]any, len(codes))
+	for i, c := range codes {
+		out[i] = c
+	}
+	return out
+}
+
+func formats() []any {
+	return []any{string(handler.FormatJSON), string(handler.FormatXML), string(handler.FormatCSV), string(handler.FormatYAML), string(handler.FormatText)}
+}
+
+// schemaGenerator converte tipos Go em JSON Schema (dialeto do OpenAPI 3.1),
+// registrando cada struct em components/schemas.
+type schemaGenerator struct {
+	schemas map[string]any
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Struct:
+		if t == reflect.TypeOf(time.Time{}) {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		return g.object(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+func (g *schemaGenerator) object(t reflect.Type) map[string]any {
+	ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	if _, ok := g.schemas[t.Name()]; ok {
+		return ref
+	}
+	g.schemas[t.Name()] = nil // marca antes de descer, para tipos recursivos
+
+	properties := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	s := map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	g.schemas[t.Name()] = s
+	return ref
+}
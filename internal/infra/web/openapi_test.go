@@ -0,0 +1,274 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/health"
+	"github.com/MchlAlex/fc-lab02/internal/metrics"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// specDocument devolve a especificação como o cliente a recebe (JSON decodificado).
+func specDocument(t *testing.T) map[string]any {
+	t.Helper()
+	raw, err := json.Marshal(OpenAPISpec())
+	require.NoError(t, err)
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	require.NoError(t, err)
+	return doc.(map[string]any)
+}
+
+// specOperation encontra o template da especificação e a operação que atendem
+// method + path. Um caminho literal tem precedência sobre templates, como no chi.
+func specOperation(t *testing.T, spec map[string]any, method, path string) (string, map[string]any) {
+	t.Helper()
+	paths := spec["paths"].(map[string]any)
+	if item, ok := paths[path]; ok {
+		if op, ok := item.(map[string]any)[strings.ToLower(method)]; ok {
+			return path, op.(map[string]any)
+		}
+	}
+	for template, item := range paths {
+		pattern := "^" + regexp.MustCompile(`\\\{[^}]+\\\}`).ReplaceAllString(regexp.QuoteMeta(template), `[^/]+`) + "$"
+		if !regexp.MustCompile(pattern).MatchString(path) {
+			continue
+		}
+		if op, ok := item.(map[string]any)[strings.ToLower(method)]; ok {
+			return template, op.(map[string]any)
+		}
+	}
+	t.Fatalf("%s %s is not described in the spec", method, path)
+	return "", nil
+}
+
+func TestOpenAPISpec_CoversRoutes(t *testing.T) {
+	spec := specDocument(t)
+	r := NewRouter(testConfig(), Dependencies{Metrics: metrics.New()})
+
+	err := chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		item, ok := spec["paths"].(map[string]any)[route]
+		if assert.True(t, ok, "route %s missing from spec", route) {
+			assert.Contains(t, item, strings.ToLower(method), "operation %s %s missing from spec", method, route)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestOpenAPISpec_MatchesResponses(t *testing.T) {
+	spec := specDocument(t)
+	compiler := jsonschema.NewCompiler()
+	require.NoError(t, compiler.AddResource("openapi.json", spec))
+
+	mockLocation := new(mocks.LocationFinder)
+	mockWeather := new(mocks.WeatherFinder)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "99999999").Return("", service.ErrCEPNotFound)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "123").Return("", service.ErrInvalidCEPFormat)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "22222222").Return("", &service.UpstreamError{
+		Provider: service.ProviderViaCEP, Kind: service.ErrUpstreamUnavailable, Err: service.ErrUpstreamUnavailable,
+	})
+	mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 25, Humidity: 60, WindKph: 10}, nil)
+	located := &entity.CurrentWeather{TempC: 28, Humidity: 70, WindKph: 5, Place: "Campinas", Region: "Sao Paulo", Country: "Brazil"}
+	mockWeather.On("GetWeatherByCity", mock.Anything, "Campinas, São Paulo, Brazil").Return(located, nil)
+	mockWeather.On("GetWeatherByCity", mock.Anything, "-22.9,-47.06").Return(located, nil)
+	mockWeather.On("GetWeatherByCity", mock.Anything, "192.0.2.1").Return(located, nil)
+
+	subscriptions, err := service.NewFileSubscriptionStore("")
+	require.NoError(t, err)
+
+	limiter, err := NewRateLimiter(service.NewMemoryRateLimitStore(), []string{"anonymous=1000/1m", "authenticated=1000/1m"}, nil)
+	require.NoError(t, err)
+	apiKeys, err := service.NewFileAPIKeyStore("")
+	require.NoError(t, err)
+	for _, key := range []entity.APIKey{
+		{ID: "tiny", Name: "tiny", Scopes: []string{service.ScopeWeatherRead}, Quota: "1/1h"},
+		{ID: "reader", Name: "reader", Scopes: []string{service.ScopeSubscriptionsRead}},
+		{ID: "k1", Name: "managed", Scopes: []string{service.ScopeWeatherRead}},
+		{ID: "owner", Name: "owner", Scopes: []string{service.ScopeSubscriptionsRead, service.ScopeSubscriptionsWrite}},
+	} {
+		require.NoError(t, apiKeys.CreateAPIKey(context.Background(), key, service.HashAPIKey(key.ID+"-secret")))
+	}
+
+	readiness := health.NewChecker(time.Minute, time.Second)
+	readiness.Add("config", func(context.Context) (map[string]string, error) { return map[string]string{"source": "test"}, nil })
+
+	cfg := testConfig()
+	cfg.BatchMaxSize = 3
+	cfg.BatchConcurrency = 2
+	cfg.AdminToken = "admin-secret"
+	r := NewRouter(cfg, Dependencies{
+		LocationService: mockLocation,
+		WeatherService:  mockWeather,
+		Converter:       service.NewStandardTemperatureConverter(),
+		Subscriptions:   subscriptions,
+		RateLimiter:     limiter,
+		APIKeys:         apiKeys,
+		Authenticator:   &Authenticator{Store: apiKeys},
+		Metrics:         metrics.New(),
+		Readiness:       readiness,
+	})
+	require.NoError(t, subscriptions.CreateSubscription(context.Background(), entity.Subscription{
+		ID: "abc123", APIKeyID: "owner", CEP: "01001000", City: "São Paulo", Condition: "temp_C > 35", CallbackURL: "https://example.com/hook", Secret: "whsec_test",
+	}))
+	require.NoError(t, subscriptions.AddDeadLetter(context.Background(), entity.DeadLetter{
+		Event: entity.SubscriptionEvent{ID: "evt1", Type: service.EventThresholdCrossed, SubscriptionID: "abc123"}, APIKeyID: "owner", CallbackURL: "https://example.com/hook", Attempts: 5, LastError: "callback responded with status 500",
+	}))
+
+	check := func(t *testing.T, req *http.Request, want int) {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		require.Equal(t, want, rr.Code, rr.Body.String())
+
+		template, op := specOperation(t, spec, req.Method, req.URL.Path)
+		resp, ok := op["responses"].(map[string]any)[strconv.Itoa(rr.Code)].(map[string]any)
+		require.True(t, ok, "status %d not documented", rr.Code)
+		if headers, ok := resp["headers"].(map[string]any); ok {
+			for name := range headers {
+				assert.NotEmpty(t, rr.Header().Get(name), "documented header %s missing", name)
+			}
+		}
+
+		if _, ok := resp["content"]; !ok {
+			assert.Empty(t, rr.Body.String(), "undocumented body for %d", rr.Code)
+			return
+		}
+		mediaType, _, err := mime.ParseMediaType(rr.Header().Get("Content-Type"))
+		require.NoError(t, err)
+		_, ok = resp["content"].(map[string]any)[mediaType]
+		require.True(t, ok, "content type %s not documented for %d", mediaType, rr.Code)
+		if mediaType != "application/json" && mediaType != "application/problem+json" {
+			return
+		}
+
+		status := strconv.Itoa(rr.Code)
+		schema, err := compiler.Compile("openapi.json" + pointer("paths", template, strings.ToLower(req.Method), "responses", status, "content", mediaType, "schema"))
+		require.NoError(t, err)
+		body, err := jsonschema.UnmarshalJSON(bytes.NewReader(rr.Body.Bytes()))
+		require.NoError(t, err)
+		assert.NoError(t, schema.Validate(body))
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		accept string
+		body   string
+		status int
+	}{
+		{"Weather", "GET", "/weather/01001000", "", "", http.StatusOK},
+		{"Weather V1", "GET", "/v1/weather/01001000", "", "", http.StatusOK},
+		{"Weather V2", "GET", "/v2/weather/01001000", "", "", http.StatusOK},
+		{"Weather V2 Not Found", "GET", "/v2/weather/99999999", "", "", http.StatusNotFound},
+		{"Weather XML", "GET", "/weather/01001000", "application/xml", "", http.StatusOK},
+		{"Weather CSV", "GET", "/weather/01001000?format=csv", "", "", http.StatusOK},
+		{"Invalid Precision", "GET", "/weather/01001000?precision=9", "", "", http.StatusBadRequest},
+		{"Invalid Format", "GET", "/weather/01001000?format=pdf", "", "", http.StatusBadRequest},
+		{"Not Acceptable", "GET", "/weather/01001000", "image/png", "", http.StatusNotAcceptable},
+		{"Invalid Zipcode", "GET", "/weather/123", "", "", http.StatusUnprocessableEntity},
+		{"Zipcode Not Found", "GET", "/weather/99999999", "", "", http.StatusNotFound},
+		{"Upstream Unavailable", "GET", "/weather/22222222", "", "", http.StatusServiceUnavailable},
+		{"Batch", "POST", "/weather/batch", "", `["01001000","99999999","123"]`, http.StatusOK},
+		{"Batch V1", "POST", "/v1/weather/batch", "", `["01001000","22222222"]`, http.StatusOK},
+		{"Batch Invalid Body", "POST", "/weather/batch", "", `{}`, http.StatusBadRequest},
+		{"Batch Too Large", "POST", "/weather/batch", "", `["1","2","3","4"]`, http.StatusRequestEntityTooLarge},
+		{"City", "GET", "/v1/weather/city/Campinas?uf=SP", "", "", http.StatusOK},
+		{"City V2", "GET", "/v2/weather/city/Campinas?uf=sp", "", "", http.StatusOK},
+		{"Invalid State", "GET", "/v2/weather/city/Campinas?uf=XX", "", "", http.StatusBadRequest},
+		{"Coordinates", "GET", "/v1/weather/coords?lat=-22.9&lon=-47.06", "", "", http.StatusOK},
+		{"Coordinates V2", "GET", "/v2/weather/coords?lat=-22.9&lon=-47.06", "", "", http.StatusOK},
+		{"Invalid Coordinates", "GET", "/v2/weather/coords?lat=91&lon=0", "", "", http.StatusBadRequest},
+		{"Client IP", "GET", "/v1/weather/me", "", "", http.StatusOK},
+		{"Client IP V2", "GET", "/v2/weather/me", "", "", http.StatusOK},
+		{"Health", "GET", "/health", "", "", http.StatusOK},
+		{"Livez", "GET", "/livez", "", "", http.StatusOK},
+		{"Readyz", "GET", "/readyz", "", "", http.StatusOK},
+		{"Metrics", "GET", "/metrics", "", "", http.StatusOK},
+		{"OpenAPI", "GET", "/openapi.json", "", "", http.StatusOK},
+		{"Docs", "GET", "/docs", "", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			check(t, req, tt.status)
+		})
+	}
+
+	// Requisições condicionais, com chave de API ou com o token de administração
+	owner := map[string]string{"X-API-Key": "owner-secret"}
+	withHeaders := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		header map[string]string
+		status int
+	}{
+		{"Weather Not Modified", "GET", "/v1/weather/01001000", "", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"Weather V2 Not Modified", "GET", "/v2/weather/01001000", "", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"Rate Limit Allowed", "GET", "/v2/weather/01001000", "", map[string]string{"X-API-Key": "tiny-secret"}, http.StatusOK},
+		{"Rate Limited", "GET", "/v2/weather/01001000", "", map[string]string{"X-API-Key": "tiny-secret"}, http.StatusTooManyRequests},
+		{"Invalid API Key", "GET", "/v1/weather/01001000", "", map[string]string{"X-API-Key": "wk_unknown"}, http.StatusUnauthorized},
+		{"Insufficient Scope", "GET", "/v1/weather/01001000", "", map[string]string{"Authorization": "Bearer reader-secret"}, http.StatusForbidden},
+		{"Create Subscription", "POST", "/v2/subscriptions", `{"cep":"01001000","condition":"> 35","callback_url":"https://example.com/hook"}`, owner, http.StatusCreated},
+		{"Invalid Condition", "POST", "/v2/subscriptions", `{"cep":"01001000","condition":"hot","callback_url":"https://example.com/hook"}`, owner, http.StatusUnprocessableEntity},
+		{"Subscription Without API Key", "POST", "/v2/subscriptions", `{"cep":"01001000","condition":"> 35","callback_url":"https://example.com/hook"}`, nil, http.StatusUnauthorized},
+		{"List Subscriptions", "GET", "/v2/subscriptions", "", owner, http.StatusOK},
+		{"Get Subscription", "GET", "/v2/subscriptions/abc123", "", owner, http.StatusOK},
+		{"Subscription Not Found", "GET", "/v2/subscriptions/missing", "", owner, http.StatusNotFound},
+		{"Dead Letters", "GET", "/v2/subscriptions/dead-letters", "", owner, http.StatusOK},
+		{"Update Subscription", "PATCH", "/v2/subscriptions/abc123", `{"condition":"< 5"}`, owner, http.StatusOK},
+		{"Empty Update", "PATCH", "/v2/subscriptions/abc123", `{}`, owner, http.StatusBadRequest},
+		{"Delete Subscription", "DELETE", "/v2/subscriptions/abc123", "", owner, http.StatusNoContent},
+		{"Create API Key", "POST", "/admin/keys", `{"name":"acme","scopes":["weather:read"],"quota":"600/1m:60"}`, map[string]string{"Authorization": "Bearer admin-secret"}, http.StatusCreated},
+		{"Invalid Scope", "POST", "/admin/keys", `{"name":"acme","scopes":["weather:write"]}`, map[string]string{"Authorization": "Bearer admin-secret"}, http.StatusUnprocessableEntity},
+		{"Admin Unauthorized", "GET", "/admin/keys", "", map[string]string{"Authorization": "Bearer wrong"}, http.StatusUnauthorized},
+		{"List API Keys", "GET", "/admin/keys", "", map[string]string{"Authorization": "Bearer admin-secret"}, http.StatusOK},
+		{"Get API Key", "GET", "/admin/keys/k1", "", map[string]string{"Authorization": "Bearer admin-secret"}, http.StatusOK},
+		{"Rotate API Key", "POST", "/admin/keys/k1/rotate", "", map[string]string{"Authorization": "Bearer admin-secret"}, http.StatusOK},
+		{"Revoke API Key", "DELETE", "/admin/keys/k1", "", map[string]string{"Authorization": "Bearer admin-secret"}, http.StatusNoContent},
+		{"API Key Not Found", "DELETE", "/admin/keys/k1", "", map[string]string{"Authorization": "Bearer admin-secret"}, http.StatusNotFound},
+	}
+
+	for _, tt := range withHeaders {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			check(t, req, tt.status)
+		})
+	}
+}
+
+// pointer monta um JSON Pointer (RFC 6901) a partir dos segmentos.
+func pointer(segments ...string) string {
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	for i, s := range segments {
+		segments[i] = escaper.Replace(s)
+	}
+	return "#/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,185 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/handler"
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+)
+
+// RateLimiter limita as requisições de cada cliente com um balde de tokens.
+// Consumidores autenticados (Authenticator.Identify) usam o balde da chave, com
+// a cota dela ou o tier "authenticated"; os demais são identificados pelo IP e
+// usam o tier "anonymous".
+type RateLimiter struct {
+	Store    service.RateLimitStore
+	ClientIP *handler.ClientIPResolver
+	Logger   *slog.Logger
+
+	mu    sync.RWMutex
+	tiers map[string]service.RateLimitTier
+}
+
+// NewRateLimiter monta o limitador a partir dos tiers ("nome=limite/janela[:rajada]").
+// Sem tiers, retorna nil: o limite fica desligado.
+func NewRateLimiter(store service.RateLimitStore, tiers []string, clientIP *handler.ClientIPResolver) (*RateLimiter, error) {
+	l := &RateLimiter{Store: store, ClientIP: clientIP}
+	if err := l.SetTiers(tiers); err != nil {
+		return nil, err
+	}
+	if len(l.tiers) == 0 {
+		return nil, nil
+	}
+	return l, nil
+}
+
+// SetTiers troca os tiers, com o limitador em uso. Os baldes existentes são
+// mantidos e passam a encher no ritmo do novo tier; com um erro, os tiers
+// anteriores continuam valendo.
+func (l *RateLimiter) SetTiers(specs []string) error {
+	tiers, err := parseTiers(specs)
+	if err != nil {
+		return err
+	}
+	l.setTiers(tiers)
+	return nil
+}
+
+// parseTiers interpreta os tiers de RATE_LIMIT_TIERS, indexados pelo nome.
+func parseTiers(specs []string) (map[string]service.RateLimitTier, error) {
+	tiers := map[string]service.RateLimitTier{}
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		tier, err := service.ParseRateLimitTier(spec)
+		if err != nil {
+			return nil, err
+		}
+		tiers[tier.Name] = tier
+	}
+	return tiers, nil
+}
+
+func (l *RateLimiter) setTiers(tiers map[string]service.RateLimitTier) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tiers = tiers
+}
+
+// tier retorna o tier pelo nome.
+func (l *RateLimiter) tier(name string) (service.RateLimitTier, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	tier, ok := l.tiers[name]
+	return tier, ok
+}
+
+// client identifica o balde e o tier da requisição; ok=false quando não há
+// tier aplicável e a requisição não é limitada.
+func (l *RateLimiter) client(r *http.Request) (string, service.RateLimitTier, bool) {
+	key, _ := ConsumerFromContext(r.Context())
+	return l.bucket(key, l.ClientIP.ClientIP(r))
+}
+
+// bucket escolhe o balde da chave de API ou, sem ela, o do IP do cliente.
+func (l *RateLimiter) bucket(key *entity.APIKey, ip netip.Addr) (string, service.RateLimitTier, bool) {
+	if key != nil {
+		if key.Quota != "" {
+			if tier, err := service.ParseQuota(key.Quota); err == nil {
+				tier.Name = "key " + key.ID
+				return "key:" + key.ID, tier, true
+			}
+		}
+		tier, ok := l.tier(service.AuthenticatedTier)
+		return "key:" + key.ID, tier, ok
+	}
+	tier, ok := l.tier(service.AnonymousTier)
+	return "ip:" + ip.String(), tier, ok
+}
+
+// take consome um token do balde. Uma falha do store não deve derrubar a API:
+// é registrada e a requisição segue sem limite (ok=false).
+func (l *RateLimiter) take(ctx context.Context, bucket string, tier service.RateLimitTier) (service.RateLimitResult, bool) {
+	res, err := l.Store.Take(ctx, bucket, tier)
+	if err != nil {
+		logging.Or(l.Logger).ErrorContext(ctx, "error checking rate limit", "bucket", bucket, "error", err)
+		return res, false
+	}
+	return res, true
+}
+
+// exceeded descreve a recusa de uma requisição acima do limite.
+func exceeded(tier service.RateLimitTier, res service.RateLimitResult) *apperr.Error {
+	return &apperr.Error{
+		Code:       apperr.CodeRateLimitExceeded,
+		Detail:     fmt.Sprintf("rate limit exceeded for %s: %d requests per %s", tier.Name, tier.Limit, tier.Window),
+		RetryAfter: time.Duration(max(ceilSeconds(res.RetryAfter), 1)) * time.Second,
+	}
+}
+
+// Middleware aplica o limite e informa a cota nos headers RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset e RateLimit-Policy (draft IETF
+// httpapi-ratelimit-headers). Um limitador nil não limita nada.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bucket, tier, ok := l.client(r)
+		if !ok {
+			// Ex: sem tier anônimo configurado, só as chaves de API são limitadas
+			next.ServeHTTP(w, r)
+			return
+		}
+		res, ok := l.take(r.Context(), bucket, tier)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(tier.Capacity()))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", tier.Limit, ceilSeconds(tier.Window), tier.Capacity()))
+		if !res.Allowed {
+			err := exceeded(tier, res)
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(err.RetryAfter)))
+			handler.WriteProblem(w, r, err.Code, err.Detail)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Allow aplica o limite fora do HTTP (ex: no gRPC) ao consumidor autenticado
+// ou, sem chave, ao IP do cliente. Um limitador nil não limita nada.
+func (l *RateLimiter) Allow(ctx context.Context, key *entity.APIKey, ip netip.Addr) error {
+	if l == nil {
+		return nil
+	}
+	bucket, tier, ok := l.bucket(key, ip)
+	if !ok {
+		return nil
+	}
+	if res, ok := l.take(ctx, bucket, tier); ok && !res.Allowed {
+		return exceeded(tier, res)
+	}
+	return nil
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -1,40 +1,401 @@
 package web
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/MchlAlex/fc-lab02/config"
 	"github.com/MchlAlex/fc-lab02/handler"
+	"github.com/MchlAlex/fc-lab02/internal/health"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+	"github.com/MchlAlex/fc-lab02/internal/metrics"
 	"github.com/MchlAlex/fc-lab02/internal/service"
+	"github.com/MchlAlex/fc-lab02/internal/tracing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Datas de obsolescência e de remoção das rotas sem versão (/weather/...).
+var (
+	UnversionedDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	UnversionedSunset       = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// Sondas de readiness: uma resposta do provedor no último readinessRecentSuccess
+// dispensa a sonda, que consulta um CEP e uma cidade conhecidos.
+const (
+	readinessRecentSuccess = time.Minute
+	readinessProbeCEP      = "01001000"
+	readinessProbeCity     = "São Paulo"
+)
+
+// Dependencies reúne os serviços usados pelas rotas.
+type Dependencies struct {
+	LocationService service.LocationFinder
+	WeatherService  service.WeatherFinder
+	Converter       service.TemperatureConverter
+	Subscriptions   service.SubscriptionStore
+	ClientIP        *handler.ClientIPResolver
+	RateLimiter     *RateLimiter // nil desliga o limite por cliente
+	APIKeys         service.APIKeyStore
+	Authenticator   *Authenticator         // nil desliga a autenticação
+	Logger          *slog.Logger           // nil usa slog.Default
+	Metrics         *metrics.Metrics       // nil desliga a rota /metrics
+	TracerProvider  trace.TracerProvider   // nil usa o provider global do OpenTelemetry
+	Readiness       *health.Checker        // nil deixa /readyz sempre pronto
+	Poller          *service.WeatherPoller // nil cria um a partir de WeatherService
+	Caches          map[string]service.Cache
+	Breakers        []*service.CircuitBreaker
+
+	upstream   *http.Client // Cliente dos provedores, cujas conexões Close libera
+	timeout    *service.TimeoutTransport
+	weatherAPI *service.WeatherAPIService
+	logLevel   *slog.LevelVar
+	current    *atomic.Pointer[config.Config] // Configuração em uso, exibida em /config
+}
+
 // SetupServer configura e retorna o roteador HTTP.
-func SetupServer(cfg *config.Config) *chi.Mux {
+func SetupServer(cfg *config.Config) (*chi.Mux, error) {
+	deps, err := NewDependencies(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewRouter(cfg, deps), nil
+}
+
+// NewDependencies cria os serviços a partir da configuração. O servidor gRPC
+// usa as mesmas instâncias.
+func NewDependencies(cfg *config.Config) (Dependencies, error) {
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return Dependencies{}, err
+	}
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(level)
+	logger, err := logging.NewWithLevel(os.Stdout, cfg.LogFormat, logLevel)
+	if err != nil {
+		return Dependencies{}, err
+	}
+
+	// Monta a política de arredondamento a partir da configuração
+	policy, err := service.NewRoundingPolicy(cfg.TempPrecisionC, cfg.TempPrecisionF, cfg.TempPrecisionK, cfg.TempRoundingMode)
+	if err != nil {
+		return Dependencies{}, fmt.Errorf("invalid rounding configuration: %w", err)
+	}
+
+	tracerProvider, err := tracing.New(context.Background(), cfg.OTLPEndpoint, cfg.ServiceName)
+	if err != nil {
+		return Dependencies{}, err
+	}
+
 	// Inicializa os serviços com suas dependências
-	locationService := service.NewViaCEPService(nil)                       // Usa http.DefaultClient
-	weatherService := service.NewWeatherAPIService(cfg.WeatherAPIKey, nil) // Usa http.DefaultClient
-	converter := service.NewStandardTemperatureConverter()
+	// O timeout do transporte faz chamadas lentas virarem service.ErrUpstreamTimeout (504)
+	timeout := service.NewTimeoutTransport(tracing.Transport(tracerProvider, nil), cfg.UpstreamTimeout)
+	client := &http.Client{Transport: timeout}
+	subscriptions, err := service.NewFileSubscriptionStore(cfg.SubscriptionsFile)
+	if err != nil {
+		return Dependencies{}, err
+	}
+	clientIP, err := handler.NewClientIPResolver(cfg.TrustedProxies)
+	if err != nil {
+		return Dependencies{}, err
+	}
+	rateLimiter, err := NewRateLimiter(service.NewMemoryRateLimitStore(), cfg.RateLimitTiers, clientIP)
+	if err != nil {
+		return Dependencies{}, err
+	}
+	if rateLimiter != nil {
+		rateLimiter.Logger = logger
+	}
+	apiKeys, err := service.NewAPIKeyStore(cfg.APIKeysStore, cfg.APIKeysPath)
+	if err != nil {
+		return Dependencies{}, err
+	}
+
+	// Camadas de cada provedor, de dentro para fora: métricas (só chamadas que
+	// chegam de fato ao provedor), circuit breaker, cache (responde mesmo com o
+	// circuito aberto) e trace (o span da consulta registra se veio do cache)
+	m := metrics.New()
+	viaCEPBreaker := service.NewCircuitBreaker(service.ProviderViaCEP, cfg.BreakerFailureThreshold, cfg.BreakerCooldown)
+	weatherAPIBreaker := service.NewCircuitBreaker(service.ProviderWeatherAPI, cfg.BreakerFailureThreshold, cfg.BreakerCooldown)
+	m.RegisterBreaker(service.ProviderViaCEP, viaCEPBreaker.Status)
+	m.RegisterBreaker(service.ProviderWeatherAPI, weatherAPIBreaker.Status)
+
+	var location service.LocationFinder = service.NewBreakerLocationFinder(
+		m.InstrumentLocation(service.ProviderViaCEP, service.NewViaCEPService(client)), viaCEPBreaker)
+	weatherAPI := service.NewWeatherAPIService(cfg.WeatherAPIKey, client)
+	var weather service.WeatherFinder = service.NewBreakerWeatherFinder(
+		m.InstrumentWeather(service.ProviderWeatherAPI, weatherAPI), weatherAPIBreaker)
+
+	current := new(atomic.Pointer[config.Config])
+	current.Store(cfg)
+
+	// As sondas de readiness passam pelo circuit breaker, mas não pelo cache. A
+	// verificação da configuração olha a vigente, com as recargas
+	readiness := health.NewChecker(cfg.ReadinessCacheTTL, cfg.UpstreamTimeout)
+	readiness.Add("config", func(context.Context) (map[string]string, error) {
+		return nil, current.Load().Validate()
+	})
+	probeLocation, probeWeather := location, weather
+	readiness.Add(service.ProviderViaCEP, health.UpstreamCheck(viaCEPBreaker, readinessRecentSuccess, func(ctx context.Context) error {
+		_, err := probeLocation.GetLocationByCEP(ctx, readinessProbeCEP)
+		return err
+	}))
+	readiness.Add(service.ProviderWeatherAPI, health.UpstreamCheck(weatherAPIBreaker, readinessRecentSuccess, func(ctx context.Context) error {
+		_, err := probeWeather.GetWeatherByCity(ctx, readinessProbeCity)
+		return err
+	}))
+
+	caches := map[string]service.Cache{}
+	if cfg.LocationCacheTTL > 0 {
+		cached := service.NewCachedLocationFinder(location, cfg.LocationCacheTTL)
+		m.RegisterCache(service.CacheLocation, cached.Stats)
+		caches[service.CacheLocation] = cached
+		location = cached
+	}
+	if cfg.WeatherCacheTTL > 0 {
+		cached := service.NewCachedWeatherFinder(weather, cfg.WeatherCacheTTL)
+		m.RegisterCache(service.CacheWeather, cached.Stats)
+		caches[service.CacheWeather] = cached
+		weather = cached
+	}
+	location = tracing.TraceLocation(tracerProvider, location)
+	weather = tracing.TraceWeather(tracerProvider, weather)
 
-	// Inicializa o handler com os serviços
-	weatherHandler := handler.NewWeatherHandler(locationService, weatherService, converter)
+	poller := service.NewWeatherPoller(weather, cfg.StreamPollInterval, cfg.StreamMaxSubscribers)
+	poller.Logger = logger
+
+	return Dependencies{
+		LocationService: location,
+		WeatherService:  weather,
+		Converter:       service.NewStandardTemperatureConverterWithPolicy(policy),
+		Subscriptions:   subscriptions,
+		ClientIP:        clientIP,
+		RateLimiter:     rateLimiter,
+		APIKeys:         apiKeys,
+		Authenticator:   &Authenticator{Store: apiKeys, Required: cfg.AuthRequired, Logger: logger},
+		Logger:          logger,
+		Metrics:         m,
+		TracerProvider:  tracerProvider,
+		Readiness:       readiness,
+		Poller:          poller,
+		Caches:          caches,
+		Breakers:        []*service.CircuitBreaker{viaCEPBreaker, weatherAPIBreaker},
+		upstream:        client,
+		timeout:         timeout,
+		weatherAPI:      weatherAPI,
+		logLevel:        logLevel,
+		current:         current,
+	}, nil
+}
+
+// Reload aplica os campos recarregáveis de cfg (veja config.Config.Reload):
+// chave da WeatherAPI, timeout dos provedores e das sondas de readiness, TTL
+// dos caches, tiers do limite por cliente e nível de log. Os valores que podem
+// ser inválidos são conferidos antes de qualquer troca, então um erro não
+// aplica nada. Cada valor é trocado de uma vez; as requisições em andamento
+// terminam com os valores anteriores.
+func (d Dependencies) Reload(cfg *config.Config) error {
+	// 1. Conferir tudo o que pode falhar
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	tiers, err := parseTiers(cfg.RateLimitTiers)
+	if err != nil {
+		return err
+	}
+
+	// 2. Trocar os valores, que a partir daqui não falham
+	if d.RateLimiter != nil {
+		d.RateLimiter.setTiers(tiers)
+	}
+	if d.logLevel != nil {
+		d.logLevel.Set(level)
+	}
+	if d.weatherAPI != nil {
+		d.weatherAPI.SetAPIKey(cfg.WeatherAPIKey)
+	}
+	if d.timeout != nil {
+		d.timeout.SetTimeout(cfg.UpstreamTimeout)
+	}
+	if d.Readiness != nil {
+		d.Readiness.SetTimeout(cfg.UpstreamTimeout)
+	}
+	if c, ok := d.Caches[service.CacheLocation]; ok {
+		c.SetTTL(cfg.LocationCacheTTL)
+	}
+	if c, ok := d.Caches[service.CacheWeather]; ok {
+		c.SetTTL(cfg.WeatherCacheTTL)
+	}
+	if d.current != nil {
+		d.current.Store(cfg)
+	}
+	return nil
+}
+
+// Close libera os recursos criados por NewDependencies, depois que os
+// servidores pararam: encerra o poller, envia os spans pendentes, fecha o banco
+// das chaves de API e as conexões ociosas com os provedores.
+func (d Dependencies) Close(ctx context.Context) error {
+	if d.Poller != nil {
+		d.Poller.Close()
+	}
+	var errs []error
+	if err := tracing.Shutdown(ctx, d.TracerProvider); err != nil {
+		errs = append(errs, fmt.Errorf("could not flush traces: %w", err))
+	}
+	if c, ok := d.APIKeys.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("could not close API key store: %w", err))
+		}
+	}
+	if d.upstream != nil {
+		d.upstream.CloseIdleConnections()
+	}
+	return errors.Join(errs...)
+}
+
+// NewRouter monta o roteador com os serviços informados.
+func NewRouter(cfg *config.Config, deps Dependencies) *chi.Mux {
+	// Inicializa os handlers com os serviços
+	logger := logging.Or(deps.Logger)
+	weatherHandler := handler.NewWeatherHandler(deps.LocationService, deps.WeatherService, deps.Converter)
+	weatherHandler.ClientIP = deps.ClientIP
+	weatherHandler.CacheMaxAge = cfg.CacheMaxAge
+	weatherHandler.Logger = logger
+	batchLookup := service.NewBatchLookup(deps.LocationService, deps.WeatherService, cfg.BatchConcurrency)
+	batchHandler := handler.NewBatchHandler(batchLookup, deps.Converter, cfg.BatchMaxSize, cfg.BatchTimeout)
+	batchHandler.Logger = logger
+	poller := deps.Poller
+	if poller == nil {
+		poller = service.NewWeatherPoller(deps.WeatherService, cfg.StreamPollInterval, cfg.StreamMaxSubscribers)
+		poller.Logger = logger
+	}
+	streamHandler := handler.NewStreamHandler(deps.LocationService, poller, deps.Converter, cfg.StreamHeartbeat, cfg.StreamAllowedOrigins)
+	streamHandler.Logger = logger
+	subscriptionHandler := handler.NewSubscriptionHandler(deps.LocationService, deps.Subscriptions)
+	subscriptionHandler.Owner = consumerID
+	subscriptionHandler.Logger = logger
+	apiKeyHandler := handler.NewAPIKeyHandler(deps.APIKeys)
+	apiKeyHandler.Logger = logger
+	auth := deps.Authenticator
 
 	// Configura o roteador Chi
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)    // Log das requisições
-	r.Use(middleware.Recoverer) // Recupera de panics
+	r.Use(middleware.RequestID)                    // Gera o ID usado nos logs e nas respostas de erro
+	r.Use(tracing.Middleware(deps.TracerProvider)) // Span da requisição, filho do traceparent recebido
+	if deps.Metrics != nil {
+		r.Use(deps.Metrics.Middleware) // Contagem e duração das requisições por rota e status
+	}
+	r.Use(requestLogger(logger)) // Campos de log da requisição e uma linha ao fim de cada uma
+	if cfg.TLSClientCAFile != "" {
+		r.Use(requireClientCert) // mTLS: o handshake aceita conexões sem certificado por causa das sondas
+	}
+	r.Use(auth.Identify)     // Identifica o consumidor pela chave de API; quem a exige é auth.Require
+	r.Use(recoverer(logger)) // Recupera de panics com uma resposta problem+json
+
+	// Rotas e métodos inexistentes também respondem com problem+json
+	r.NotFound(handler.NotFound)
+	r.MethodNotAllowed(methodNotAllowed(r))
 
-	// Define a rota principal
-	r.Get("/weather/{cep}", weatherHandler.GetWeatherByCEP)
+	// /v1 mantém o formato original da resposta; /v2 traz objetos aninhados.
+	// O limite por cliente e a chave de API valem para as rotas da API; health
+	// check e documentação ficam de fora
+	r.Route("/v1", func(r chi.Router) {
+		r.Use(deps.RateLimiter.Middleware)
+		r.Use(auth.Require(service.ScopeWeatherRead))
+		r.Get("/weather/{cep}", weatherHandler.GetWeatherByCEP)
+		r.Get("/weather/city/{name}", weatherHandler.GetWeatherByCity)
+		r.Get("/weather/coords", weatherHandler.GetWeatherByCoords)
+		r.Get("/weather/me", weatherHandler.GetWeatherByClientIP)
+		r.Get("/weather/{cep}/stream", streamHandler.StreamWeatherByCEP)
+		r.Get("/weather/{cep}/ws", streamHandler.WatchWeatherByCEP)
+		r.Post("/weather/batch", batchHandler.GetWeatherBatch)
+	})
+	r.Route("/v2", func(r chi.Router) {
+		r.Use(deps.RateLimiter.Middleware)
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Require(service.ScopeWeatherRead))
+			r.Get("/weather/{cep}", weatherHandler.GetWeatherByCEPV2)
+			r.Get("/weather/city/{name}", weatherHandler.GetWeatherByCityV2)
+			r.Get("/weather/coords", weatherHandler.GetWeatherByCoordsV2)
+			r.Get("/weather/me", weatherHandler.GetWeatherByClientIPV2)
+			r.Get("/weather/{cep}/stream", streamHandler.StreamWeatherByCEPV2)
+			r.Get("/weather/{cep}/ws", streamHandler.WatchWeatherByCEPV2)
+		})
+
+		// Assinaturas existem só a partir da v2; as rotas sem versão são aliases da v1.
+		// Elas pertencem à chave que as criou, então a chave é sempre exigida
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireKey(service.ScopeSubscriptionsRead))
+			r.Get("/subscriptions", subscriptionHandler.ListSubscriptions)
+			r.Get("/subscriptions/dead-letters", subscriptionHandler.ListDeadLetters)
+			r.Get("/subscriptions/{id}", subscriptionHandler.GetSubscription)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireKey(service.ScopeSubscriptionsWrite))
+			r.Post("/subscriptions", subscriptionHandler.CreateSubscription)
+			r.Patch("/subscriptions/{id}", subscriptionHandler.UpdateSubscription)
+			r.Delete("/subscriptions/{id}", subscriptionHandler.DeleteSubscription)
+		})
+	})
+
+	// Rotas sem versão: aliases obsoletos da /v1, mantidos até UnversionedSunset
+	r.Group(func(r chi.Router) {
+		r.Use(deprecated(UnversionedDeprecatedAt, UnversionedSunset, "/v1"))
+		r.Use(deps.RateLimiter.Middleware)
+		r.Use(auth.Require(service.ScopeWeatherRead))
+		r.Get("/weather/{cep}", weatherHandler.GetWeatherByCEP)
+		r.Get("/weather/{cep}/stream", streamHandler.StreamWeatherByCEP)
+		r.Get("/weather/{cep}/ws", streamHandler.WatchWeatherByCEP)
+		r.Post("/weather/batch", batchHandler.GetWeatherBatch)
+	})
+
+	// Administração das chaves de API, só com ADMIN_TOKEN configurado
+	if cfg.AdminToken != "" && deps.APIKeys != nil {
+		r.Group(func(r chi.Router) {
+			r.Use(adminOnly(cfg.AdminToken))
+			r.Post("/admin/keys", apiKeyHandler.CreateAPIKey)
+			r.Get("/admin/keys", apiKeyHandler.ListAPIKeys)
+			r.Get("/admin/keys/{id}", apiKeyHandler.GetAPIKey)
+			r.Post("/admin/keys/{id}/rotate", apiKeyHandler.RotateAPIKey)
+			r.Delete("/admin/keys/{id}", apiKeyHandler.RevokeAPIKey)
+		})
+	}
 
 	// Rota de health check (opcional, mas boa prática)
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprintln(w, "OK")
 	})
 
+	// Sondas de liveness e readiness, para Cloud Run e Kubernetes
+	readiness := deps.Readiness
+	if readiness == nil {
+		readiness = health.NewChecker(0, 0)
+	}
+	r.Get("/livez", health.Live)
+	r.Method(http.MethodGet, "/readyz", readiness)
+
+	// Métricas no formato do Prometheus, fora da autenticação como o health check
+	if deps.Metrics != nil {
+		r.Method(http.MethodGet, "/metrics", deps.Metrics.Handler())
+	}
+
+	// Documentação da API: especificação OpenAPI e página de consulta
+	r.Get("/openapi.json", serveOpenAPI)
+	r.Get("/docs", serveDocs)
+
 	return r
 }
@@ -0,0 +1,541 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/config"
+	"github.com/MchlAlex/fc-lab02/handler"
+	"github.com/MchlAlex/fc-lab02/internal/apperr"
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+	"github.com/MchlAlex/fc-lab02/internal/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{TempPrecisionC: 1, TempPrecisionF: 1, TempPrecisionK: 2, TempRoundingMode: "half-even", CacheMaxAge: handler.DefaultCacheMaxAge}
+}
+
+func decodeProblem(t *testing.T, rr *httptest.ResponseRecorder) entity.Problem {
+	t.Helper()
+	assert.Equal(t, handler.ProblemContentType, rr.Header().Get("Content-Type"))
+	var problem entity.Problem
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	return problem
+}
+
+func TestSetupServer_Problems(t *testing.T) {
+	r, err := SetupServer(testConfig())
+	assert.NoError(t, err)
+
+	t.Run("Route Not Found", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/nope", nil))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		problem := decodeProblem(t, rr)
+		assert.Equal(t, "route_not_found", problem.Code)
+		assert.NotEmpty(t, problem.RequestID)
+	})
+
+	t.Run("Method Not Allowed", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("DELETE", "/weather/01001000", nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+		assert.Equal(t, []string{"GET"}, rr.Header().Values("Allow"))
+		assert.Equal(t, "method_not_allowed", decodeProblem(t, rr).Code)
+	})
+}
+
+func TestNewRouter_Versions(t *testing.T) {
+	r := NewRouter(testConfig(), Dependencies{})
+
+	t.Run("Unversioned Alias Is Deprecated", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/weather/123?format=pdf", nil))
+
+		assert.Equal(t, "@1792281600", rr.Header().Get("Deprecation"))
+		assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", rr.Header().Get("Sunset"))
+		assert.Equal(t, `</v1/weather/123>; rel="successor-version"`, rr.Header().Get("Link"))
+	})
+
+	t.Run("Versioned Routes Are Not Deprecated", func(t *testing.T) {
+		for _, path := range []string{"/v1/weather/123?format=pdf", "/v2/weather/123?format=pdf"} {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Empty(t, rr.Header().Get("Deprecation"), path)
+			assert.Empty(t, rr.Header().Get("Sunset"), path)
+		}
+	})
+}
+
+func TestNewRouter_ClientCert(t *testing.T) {
+	cfg := testConfig()
+	cfg.TLSClientCAFile = "ca.crt"
+	r := NewRouter(cfg, Dependencies{})
+
+	get := func(path string, state *tls.ConnectionState) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.TLS = state
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	withCert := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "billing"}}}}
+
+	t.Run("Probes Without Certificate", func(t *testing.T) {
+		for _, path := range []string{"/livez", "/readyz"} {
+			assert.Equal(t, http.StatusOK, get(path, &tls.ConnectionState{}).Code, path)
+		}
+	})
+
+	t.Run("API Without Certificate", func(t *testing.T) {
+		rr := get("/v1/weather/123?precision=9", &tls.ConnectionState{})
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, "client_cert_required", decodeProblem(t, rr).Code)
+		assert.Equal(t, http.StatusUnauthorized, get("/metrics", &tls.ConnectionState{}).Code)
+	})
+
+	t.Run("API With Certificate", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("/v1/weather/123?precision=9", withCert).Code)
+	})
+}
+
+func TestRecoverer(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(recoverer(slog.New(slog.DiscardHandler)))
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	problem := decodeProblem(t, rr)
+	assert.Equal(t, "internal_error", problem.Code)
+	assert.NotEmpty(t, problem.RequestID)
+}
+
+func TestRateLimiter(t *testing.T) {
+	clientIP, err := handler.NewClientIPResolver([]string{"10.0.0.1"})
+	require.NoError(t, err)
+	limiter, err := NewRateLimiter(service.NewMemoryRateLimitStore(), []string{"anonymous=2/1m", "authenticated=100/1m:5"}, clientIP)
+	require.NoError(t, err)
+	keys, err := service.NewFileAPIKeyStore("")
+	require.NoError(t, err)
+	require.NoError(t, keys.CreateAPIKey(context.Background(), entity.APIKey{ID: "k1", Name: "acme", Scopes: []string{service.ScopeWeatherRead}}, service.HashAPIKey("standard-key")))
+	require.NoError(t, keys.CreateAPIKey(context.Background(), entity.APIKey{ID: "k2", Name: "partner", Scopes: []string{service.ScopeWeatherRead}, Quota: "1000/1m:50"}, service.HashAPIKey("partner-key")))
+	auth := &Authenticator{Store: keys}
+	h := auth.Identify(limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })))
+
+	get := func(remoteAddr string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/weather/01001000", nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("192.0.2.1:1234", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", rr.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60;burst=2", rr.Header().Get("RateLimit-Policy"))
+
+	// Mesmo cliente atrás do proxy confiável e com uma chave desconhecida: mesmo balde
+	assert.Equal(t, http.StatusOK, get("10.0.0.1:1234", map[string]string{"X-Forwarded-For": "192.0.2.1", APIKeyHeader: "made-up"}).Code)
+	rr = get("192.0.2.1:1234", nil)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "rate_limit_exceeded", decodeProblem(t, rr).Code)
+
+	assert.Equal(t, http.StatusOK, get("192.0.2.2:1234", nil).Code, "other IPs have their own bucket")
+	rr = get("192.0.2.1:1234", map[string]string{APIKeyHeader: "standard-key"})
+	assert.Equal(t, http.StatusOK, rr.Code, "API keys have their own bucket")
+	assert.Equal(t, "5", rr.Header().Get("RateLimit-Limit"), "keys without a quota use the authenticated tier")
+	rr = get("192.0.2.1:1234", map[string]string{"Authorization": "Bearer partner-key"})
+	assert.Equal(t, "1000;w=60;burst=50", rr.Header().Get("RateLimit-Policy"), "per-key quota")
+
+	t.Run("Configuration", func(t *testing.T) {
+		disabled, err := NewRateLimiter(service.NewMemoryRateLimitStore(), nil, nil)
+		assert.NoError(t, err)
+		assert.Nil(t, disabled)
+
+		_, err = NewRateLimiter(service.NewMemoryRateLimitStore(), []string{"anonymous=60"}, nil)
+		assert.ErrorIs(t, err, service.ErrInvalidRateLimitTier)
+	})
+}
+
+func TestAuthenticator(t *testing.T) {
+	keys, err := service.NewFileAPIKeyStore("")
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, keys.CreateAPIKey(ctx, entity.APIKey{ID: "k1", Name: "acme", Scopes: []string{service.ScopeWeatherRead}}, service.HashAPIKey("weather-key")))
+	require.NoError(t, keys.CreateAPIKey(ctx, entity.APIKey{ID: "k2", Name: "old", Scopes: []string{service.ScopeWeatherRead}}, service.HashAPIKey("revoked-key")))
+	require.NoError(t, keys.RevokeAPIKey(ctx, "k2", time.Now()))
+
+	var logs bytes.Buffer
+	logger, err := logging.New(&logs, "json", "info")
+	require.NoError(t, err)
+	newRouter := func(required bool) http.Handler {
+		auth := &Authenticator{Store: keys, Required: required}
+		r := chi.NewRouter()
+		r.Use(requestLogger(logger))
+		r.Use(auth.Identify)
+		r.With(auth.Require(service.ScopeWeatherRead)).Get("/weather", func(w http.ResponseWriter, r *http.Request) {})
+		r.With(auth.RequireKey(service.ScopeSubscriptionsWrite)).Post("/subscriptions", func(w http.ResponseWriter, r *http.Request) {})
+		return r
+	}
+	do := func(h http.Handler, method, target string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name     string
+		required bool
+		method   string
+		header   map[string]string
+		status   int
+		code     string
+	}{
+		{"Missing Key", true, "GET", nil, http.StatusUnauthorized, "missing_api_key"},
+		{"Optional Key", false, "GET", nil, http.StatusOK, ""},
+		{"Header Key", true, "GET", map[string]string{APIKeyHeader: "weather-key"}, http.StatusOK, ""},
+		{"Bearer Token", true, "GET", map[string]string{"Authorization": "Bearer weather-key"}, http.StatusOK, ""},
+		{"Unknown Key", false, "GET", map[string]string{APIKeyHeader: "made-up"}, http.StatusUnauthorized, "invalid_api_key"},
+		{"Revoked Key", true, "GET", map[string]string{APIKeyHeader: "revoked-key"}, http.StatusUnauthorized, "invalid_api_key"},
+		{"Insufficient Scope", true, "POST", map[string]string{APIKeyHeader: "weather-key"}, http.StatusForbidden, "insufficient_scope"},
+		{"Key Always Required For Owned Resources", false, "POST", nil, http.StatusUnauthorized, "missing_api_key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/weather"
+			if tt.method == "POST" {
+				target = "/subscriptions"
+			}
+			rr := do(newRouter(tt.required), tt.method, target, tt.header)
+			assert.Equal(t, tt.status, rr.Code)
+			if tt.code != "" {
+				assert.Equal(t, tt.code, decodeProblem(t, rr).Code)
+				assert.Contains(t, rr.Header().Get("WWW-Authenticate"), `Bearer realm="fc-lab02"`)
+			}
+		})
+	}
+
+	t.Run("Logs Consumer", func(t *testing.T) {
+		logs.Reset()
+		do(newRouter(true), "GET", "/weather", map[string]string{APIKeyHeader: "weather-key"})
+		assert.Contains(t, logs.String(), `"consumer":"acme","api_key_id":"k1"`)
+
+		logs.Reset()
+		do(newRouter(false), "GET", "/weather", nil)
+		assert.Contains(t, logs.String(), `"consumer":"anonymous"`)
+	})
+
+	t.Run("Admin Token", func(t *testing.T) {
+		h := adminOnly("admin-secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		assert.Equal(t, http.StatusOK, do(h, "GET", "/admin/keys", map[string]string{"Authorization": "Bearer admin-secret"}).Code)
+		rr := do(h, "GET", "/admin/keys", map[string]string{APIKeyHeader: "admin-secret"})
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, "the admin token is only accepted as a Bearer token")
+		assert.Equal(t, "invalid_api_key", decodeProblem(t, rr).Code)
+	})
+}
+
+func TestRequestLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger, err := logging.New(&logs, "json", "info")
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(tracing.Middleware(sdktrace.NewTracerProvider()))
+	r.Use(requestLogger(logger))
+	r.Get("/weather/{cep}", func(w http.ResponseWriter, r *http.Request) {
+		// Os handlers e serviços acrescentam os campos da consulta
+		logging.Add(r.Context(), slog.String("cep", chi.URLParam(r, "cep")), slog.String("city", "São Paulo"), slog.String("provider", service.ProviderWeatherAPI))
+		logger.WarnContext(r.Context(), "error finding weather")
+		handler.WriteProblem(w, r, apperr.CodeUpstreamTimeout, "")
+	})
+
+	req := httptest.NewRequest("GET", "/weather/01001000", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "billing"}}}}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	dec := json.NewDecoder(&logs)
+	var warning, access map[string]any
+	require.NoError(t, dec.Decode(&warning))
+	require.NoError(t, dec.Decode(&access))
+
+	assert.Equal(t, "error finding weather", warning["msg"])
+	assert.Equal(t, "01001000", warning["cep"], "every line carries the request fields")
+	assert.NotEmpty(t, warning["request_id"])
+
+	assert.Equal(t, "http request", access["msg"])
+	assert.Equal(t, "ERROR", access["level"])
+	assert.Equal(t, warning["request_id"], access["request_id"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", access["trace_id"], "logs correlate with the trace")
+	assert.Equal(t, "billing", access["client_cert"], "mTLS client identified")
+	assert.Equal(t, "GET", access["method"])
+	assert.Equal(t, "/weather/01001000", access["path"])
+	assert.Equal(t, "São Paulo", access["city"])
+	assert.Equal(t, "weatherapi", access["provider"])
+	assert.Equal(t, "upstream_timeout", access["error_code"])
+	assert.Equal(t, "server_error", access["outcome"])
+	assert.Equal(t, float64(http.StatusGatewayTimeout), access["status"])
+	assert.Contains(t, access, "duration_ms")
+}
+
+func TestServe(t *testing.T) {
+	// start atende em uma porta livre até cancel; o canal recebe o retorno de Serve
+	start := func(t *testing.T, srv *http.Server, timeout time.Duration) (string, context.CancelFunc, <-chan error) {
+		t.Helper()
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		served := make(chan error, 1)
+		go func() { served <- Serve(ctx, srv, ln, timeout) }()
+		return ln.Addr().String(), cancel, served
+	}
+
+	t.Run("In-Flight Requests Finish", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		mux := http.NewServeMux()
+		mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.Write([]byte("done"))
+		})
+		addr, cancel, served := start(t, NewHTTPServer(testConfig(), mux, nil), 5*time.Second)
+
+		type result struct {
+			status int
+			body   string
+			err    error
+		}
+		results := make(chan result, 1)
+		go func() {
+			resp, err := http.Get("http://" + addr + "/slow")
+			if err != nil {
+				results <- result{err: err}
+				return
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			results <- result{resp.StatusCode, string(body), err}
+		}()
+		<-started
+		cancel()
+
+		// Novas conexões são recusadas enquanto a requisição termina
+		require.Eventually(t, func() bool {
+			conn, err := net.Dial("tcp", addr)
+			if err == nil {
+				conn.Close()
+			}
+			return err != nil
+		}, time.Second, 10*time.Millisecond)
+		select {
+		case err := <-served:
+			t.Fatalf("Serve returned before the request finished: %v", err)
+		default:
+		}
+
+		close(release)
+		res := <-results
+		require.NoError(t, res.err)
+		assert.Equal(t, http.StatusOK, res.status)
+		assert.Equal(t, "done", res.body)
+		assert.NoError(t, <-served)
+	})
+
+	t.Run("Deadline Closes Stuck Connections", func(t *testing.T) {
+		started := make(chan struct{})
+		mux := http.NewServeMux()
+		mux.HandleFunc("/stuck", func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-r.Context().Done()
+		})
+		addr, cancel, served := start(t, NewHTTPServer(testConfig(), mux, nil), 50*time.Millisecond)
+
+		failed := make(chan error, 1)
+		go func() {
+			_, err := http.Get("http://" + addr + "/stuck")
+			failed <- err
+		}()
+		<-started
+		cancel()
+
+		assert.ErrorIs(t, <-served, context.DeadlineExceeded)
+		assert.Error(t, <-failed)
+	})
+
+	t.Run("Streams End On Shutdown", func(t *testing.T) {
+		mockLocation := new(mocks.LocationFinder)
+		mockWeather := new(mocks.WeatherFinder)
+		mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 25}, nil)
+		poller := service.NewWeatherPoller(mockWeather, time.Hour, 10)
+		router := NewRouter(testConfig(), Dependencies{LocationService: mockLocation, WeatherService: mockWeather, Converter: service.NewStandardTemperatureConverter(), Poller: poller})
+
+		srv := NewHTTPServer(testConfig(), router, nil)
+		srv.RegisterOnShutdown(poller.Close)
+		addr, cancel, served := start(t, srv, 5*time.Second)
+
+		resp, err := http.Get("http://" + addr + "/v1/weather/01001000/stream")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		cancel()
+		_, err = io.ReadAll(resp.Body)
+		assert.NoError(t, err, "the stream ends instead of being cut")
+		assert.NoError(t, <-served)
+	})
+}
+
+func TestNewAdminRouter(t *testing.T) {
+	cfg := testConfig()
+	cfg.WeatherAPIKey = "secret-key"
+	deps, err := NewDependencies(cfg)
+	require.NoError(t, err)
+	admin, public := NewAdminRouter(cfg, deps), NewRouter(cfg, deps)
+
+	get := func(r http.Handler, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr
+	}
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine?debug=1", "/config", "/buildinfo", "/caches", "/breakers"} {
+		assert.Equal(t, http.StatusOK, get(admin, path).Code, path)
+		assert.Equal(t, http.StatusNotFound, get(public, path).Code, "%s is not reachable on the public port", path)
+	}
+
+	rr := get(admin, "/config")
+	assert.NotContains(t, rr.Body.String(), "secret-key")
+	var config map[string]any
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &config))
+	assert.Equal(t, "[REDACTED]", config["WEATHER_API_KEY"])
+	assert.Equal(t, "", config["ADMIN_TOKEN"], "unset secrets stay empty")
+	assert.Equal(t, "15m0s", config["CACHE_MAX_AGE"])
+
+	var breakers []entity.BreakerInfo
+	require.NoError(t, json.Unmarshal(get(admin, "/breakers").Body.Bytes(), &breakers))
+	require.Len(t, breakers, 2)
+	assert.Equal(t, service.ProviderViaCEP, breakers[0].Provider)
+	assert.Equal(t, service.ProviderWeatherAPI, breakers[1].Provider)
+
+	t.Run("Admin Token", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.AdminToken = "admin-secret"
+		admin := NewAdminRouter(cfg, deps)
+		do := func(method, path, token string) int {
+			req := httptest.NewRequest(method, path, nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rr := httptest.NewRecorder()
+			admin.ServeHTTP(rr, req)
+			return rr.Code
+		}
+
+		for _, route := range [][2]string{
+			{"GET", "/config"},
+			{"GET", "/buildinfo"},
+			{"GET", "/caches"},
+			{"GET", "/breakers"},
+			{"GET", "/debug/pprof/"},
+			{"POST", "/caches/weather/flush"},
+			{"DELETE", "/caches/weather/keys/x"},
+			{"POST", "/breakers/viacep/open"},
+			{"POST", "/breakers/viacep/close"},
+		} {
+			assert.Equal(t, http.StatusUnauthorized, do(route[0], route[1], ""), "%s %s", route[0], route[1])
+			assert.Equal(t, http.StatusUnauthorized, do(route[0], route[1], "wrong"), "%s %s", route[0], route[1])
+		}
+		assert.Equal(t, http.StatusOK, do("GET", "/config", "admin-secret"))
+		assert.Equal(t, http.StatusOK, do("GET", "/debug/pprof/", "admin-secret"))
+		assert.Equal(t, http.StatusOK, do("POST", "/breakers/viacep/close", "admin-secret"))
+	})
+}
+
+func TestDependencies_Reload(t *testing.T) {
+	cfg := testConfig()
+	cfg.WeatherAPIKey = "old-key"
+	cfg.LogLevel = "info"
+	cfg.UpstreamTimeout = 5 * time.Second
+	cfg.RateLimitTiers = []string{"anonymous=2/1m"}
+	cfg.WeatherCacheTTL = time.Minute
+	deps, err := NewDependencies(cfg)
+	require.NoError(t, err)
+	admin := NewAdminRouter(cfg, deps)
+
+	next := *cfg
+	next.WeatherAPIKey = "new-key"
+	next.LogLevel = "debug"
+	next.UpstreamTimeout = 2 * time.Second
+	next.RateLimitTiers = []string{"anonymous=100/1m"}
+	require.NoError(t, deps.Reload(&next))
+
+	assert.True(t, deps.Logger.Enabled(context.Background(), slog.LevelDebug))
+	assert.Equal(t, 2*time.Second, deps.timeout.Timeout())
+	assert.Equal(t, 2*time.Second, deps.Readiness.Timeout(), "readiness probes follow the upstream timeout")
+	tier, _ := deps.RateLimiter.tier(service.AnonymousTier)
+	assert.Equal(t, 100, tier.Limit)
+
+	rr := httptest.NewRecorder()
+	admin.ServeHTTP(rr, httptest.NewRequest("GET", "/config", nil))
+	var shown map[string]any
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &shown))
+	assert.Equal(t, "2s", shown["UPSTREAM_TIMEOUT"], "/config shows the reloaded values")
+
+	t.Run("Invalid Tiers Keep The Previous Values", func(t *testing.T) {
+		bad := next
+		bad.RateLimitTiers = []string{"anonymous=60"}
+		bad.LogLevel = "error"
+		bad.UpstreamTimeout = time.Second
+		assert.ErrorIs(t, deps.Reload(&bad), service.ErrInvalidRateLimitTier)
+		tier, _ := deps.RateLimiter.tier(service.AnonymousTier)
+		assert.Equal(t, 100, tier.Limit)
+		assert.True(t, deps.Logger.Enabled(context.Background(), slog.LevelDebug))
+		assert.Equal(t, 2*time.Second, deps.timeout.Timeout(), "nothing is applied")
+		assert.Equal(t, "2s", deps.current.Load().UpstreamTimeout.String())
+	})
+}
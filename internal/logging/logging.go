@@ -0,0 +1,155 @@
+// Package logging monta o logger da aplicação (log/slog) e guarda os campos de
+// cada requisição no contexto, para que todas as linhas registradas durante a
+// requisição tragam o ID, o CEP, a cidade, o provedor e as latências.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// New cria o logger no formato informado ("json", o padrão, ou "text") e com
+// o nível mínimo informado ("debug", "info", o padrão, "warn" ou "error").
+// Os campos guardados no contexto (WithFields/Add) entram em todas as linhas
+// registradas com os métodos *Context do logger.
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithLevel(w, format, lvl)
+}
+
+// NewWithLevel é como New, mas com o nível mínimo lido de level a cada linha.
+// Com um *slog.LevelVar, o nível pode ser trocado com o logger em uso.
+func NewWithLevel(w io.Writer, format string, level slog.Leveler) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "", "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q: expected json or text", format)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+// ParseLevel converte o nome de um nível ("debug", "info", "warn" ou "error")
+// em slog.Level. Vazio equivale a "info".
+func ParseLevel(level string) (slog.Level, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return 0, fmt.Errorf("invalid log level %q: expected debug, info, warn or error", level)
+		}
+	}
+	return lvl, nil
+}
+
+// Or retorna l ou, se for nil, o logger padrão (slog.Default).
+func Or(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return slog.Default()
+	}
+	return l
+}
+
+// contextHandler acrescenta a cada registro os campos guardados no contexto.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.AddAttrs(Attrs(ctx)...)
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+type fieldsKey struct{}
+
+// fields são os campos de uma requisição (ou de um item de lote). Os campos
+// do pai valem também para o filho, mas o que o filho acrescenta não sobe.
+type fields struct {
+	parent *fields
+
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// WithFields abre um novo conjunto de campos no contexto, com os campos
+// informados e os do conjunto que já estava no contexto, se houver.
+func WithFields(ctx context.Context, attrs ...slog.Attr) context.Context {
+	parent, _ := ctx.Value(fieldsKey{}).(*fields)
+	f := &fields{parent: parent}
+	f.add(attrs)
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+// Add acrescenta campos ao conjunto do contexto, substituindo os de mesmo
+// nome. Sem WithFields antes, não faz nada.
+func Add(ctx context.Context, attrs ...slog.Attr) {
+	if f, ok := ctx.Value(fieldsKey{}).(*fields); ok {
+		f.add(attrs)
+	}
+}
+
+// Attrs retorna os campos do contexto, dos mais antigos aos mais novos.
+func Attrs(ctx context.Context) []slog.Attr {
+	f, _ := ctx.Value(fieldsKey{}).(*fields)
+	return f.all()
+}
+
+// Latency é o campo com a duração de uma chamada, em milissegundos (ex: viacep_ms).
+func Latency(name string, d time.Duration) slog.Attr {
+	return slog.Float64(name+"_ms", float64(d.Microseconds())/1000)
+}
+
+func (f *fields) add(attrs []slog.Attr) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+next:
+	for _, a := range attrs {
+		for i := range f.attrs {
+			if f.attrs[i].Key == a.Key {
+				f.attrs[i] = a
+				continue next
+			}
+		}
+		f.attrs = append(f.attrs, a)
+	}
+}
+
+func (f *fields) all() []slog.Attr {
+	if f == nil {
+		return nil
+	}
+	out := f.parent.all()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+next:
+	for _, a := range f.attrs {
+		for i := range out {
+			if out[i].Key == a.Key {
+				out[i] = a
+				continue next
+			}
+		}
+		out = append(out, a)
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "warn")
+	require.NoError(t, err)
+
+	logger.Info("dropped")
+	logger.Warn("kept", "cep", "01001000")
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "kept", line["msg"])
+	assert.Equal(t, "WARN", line["level"])
+	assert.Equal(t, "01001000", line["cep"])
+
+	buf.Reset()
+	logger, err = New(&buf, "text", "")
+	require.NoError(t, err)
+	logger.Info("hello", "city", "São Paulo")
+	assert.Contains(t, buf.String(), `msg=hello city="São Paulo"`)
+
+	_, err = New(&buf, "xml", "info")
+	assert.Error(t, err)
+	_, err = New(&buf, "json", "loud")
+	assert.Error(t, err)
+
+	// Com um LevelVar, o nível muda com o logger em uso
+	buf.Reset()
+	var level slog.LevelVar
+	logger, err = NewWithLevel(&buf, "text", &level)
+	require.NoError(t, err)
+	logger.Debug("dropped")
+	level.Set(slog.LevelDebug)
+	logger.Debug("kept")
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.Contains(t, buf.String(), "msg=kept")
+}
+
+func TestFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "debug")
+	require.NoError(t, err)
+
+	ctx := WithFields(context.Background(), slog.String("request_id", "req-1"))
+	Add(ctx, slog.String("cep", "01001000"), Latency("viacep", 1500*time.Microsecond))
+	Add(ctx, slog.String("cep", "20040002")) // substitui o anterior
+
+	// Os campos de um item valem só para ele
+	item := WithFields(ctx, slog.String("city", "Rio de Janeiro"))
+	Add(item, slog.String("provider", "weatherapi"))
+	logger.InfoContext(item, "item")
+	logger.InfoContext(ctx, "request")
+
+	dec := json.NewDecoder(&buf)
+	var itemLine, requestLine map[string]any
+	require.NoError(t, dec.Decode(&itemLine))
+	require.NoError(t, dec.Decode(&requestLine))
+
+	assert.Equal(t, "req-1", itemLine["request_id"])
+	assert.Equal(t, "20040002", itemLine["cep"])
+	assert.Equal(t, "Rio de Janeiro", itemLine["city"])
+	assert.Equal(t, "weatherapi", itemLine["provider"])
+	assert.Equal(t, 1.5, requestLine["viacep_ms"])
+	assert.NotContains(t, requestLine, "city")
+	assert.NotContains(t, requestLine, "provider")
+
+	// Sem WithFields, Add não faz nada
+	Add(context.Background(), slog.String("cep", "x"))
+	assert.Empty(t, Attrs(context.Background()))
+}
@@ -0,0 +1,229 @@
+// Package metrics expõe as métricas da aplicação no formato do Prometheus:
+// requisições HTTP por rota e status, chamadas aos provedores externos e uso
+// dos caches. A instrumentação é feita por decoradores e por um middleware,
+// sem alterar os serviços.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute é o rótulo route das requisições que não casaram com nenhuma
+// rota, para que caminhos arbitrários não criem séries novas.
+const unmatchedRoute = "unmatched"
+
+// otherMethod é o rótulo method dos métodos fora do padrão HTTP, pelo mesmo motivo.
+const otherMethod = "OTHER"
+
+// standardMethods são os métodos que viram rótulo como vieram na requisição.
+var standardMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodConnect: true,
+	http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// Metrics reúne os coletores em um registro próprio, servido por Handler.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+
+	upstreamRequests *prometheus.CounterVec
+	upstreamDuration *prometheus.HistogramVec
+	upstreamErrors   *prometheus.CounterVec
+}
+
+// New cria as métricas, já com as do runtime do Go e do processo.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by method, route pattern and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests currently being served, including open streams.",
+		}),
+		upstreamRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "upstream_requests_total",
+			Help: "Calls to external providers by provider and outcome.",
+		}, []string{"provider", "outcome"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "upstream_request_duration_seconds",
+			Help:    "Latency of the calls to external providers.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"provider"}),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "upstream_errors_total",
+			Help: "Failed calls to external providers by provider and kind.",
+		}, []string{"provider", "kind"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.duration, m.inFlight,
+		m.upstreamRequests, m.upstreamDuration, m.upstreamErrors,
+	)
+	return m
+}
+
+// Handler serve as métricas no formato de exposição do Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware conta as requisições e mede a sua duração pelo padrão da rota do
+// chi (ex: /v1/weather/{cep}), e não pelo caminho, para limitar as séries.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		method := r.Method
+		if !standardMethods[method] {
+			method = otherMethod
+		}
+		labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+		m.requests.With(labels).Inc()
+		m.duration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// RegisterCache expõe os acertos, as faltas e o tamanho de um cache, lidos de
+// stats a cada coleta. A taxa de acerto é hits / (hits + misses).
+func (m *Metrics) RegisterCache(name string, stats func() service.CacheStats) {
+	labels := prometheus.Labels{"cache": name}
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "cache_hits_total", Help: "Lookups answered by the cache.", ConstLabels: labels,
+		}, func() float64 { return float64(stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "cache_misses_total", Help: "Lookups that went to the provider.", ConstLabels: labels,
+		}, func() float64 { return float64(stats().Misses) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "cache_entries", Help: "Entries currently stored in the cache.", ConstLabels: labels,
+		}, func() float64 { return float64(stats().Entries) }),
+	)
+}
+
+// RegisterBreaker expõe o estado do circuit breaker de um provedor, lido de
+// status a cada coleta: 1 na série do estado atual e 0 nas demais.
+func (m *Metrics) RegisterBreaker(provider string, status func() service.BreakerStatus) {
+	for _, state := range []string{service.BreakerClosed, service.BreakerOpen, service.BreakerHalfOpen} {
+		m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "circuit_breaker_state",
+			Help:        "Current circuit breaker state of each provider.",
+			ConstLabels: prometheus.Labels{"provider": provider, "state": state},
+		}, func() float64 {
+			if status().State == state {
+				return 1
+			}
+			return 0
+		}))
+	}
+}
+
+// InstrumentLocation decora um LocationFinder com as métricas do provedor.
+func (m *Metrics) InstrumentLocation(provider string, next service.LocationFinder) service.LocationFinder {
+	return &instrumentedLocationFinder{metrics: m, provider: provider, next: next}
+}
+
+// InstrumentWeather decora um WeatherFinder com as métricas do provedor.
+func (m *Metrics) InstrumentWeather(provider string, next service.WeatherFinder) service.WeatherFinder {
+	return &instrumentedWeatherFinder{metrics: m, provider: provider, next: next}
+}
+
+type instrumentedLocationFinder struct {
+	metrics  *Metrics
+	provider string
+	next     service.LocationFinder
+}
+
+func (f *instrumentedLocationFinder) GetLocationByCEP(ctx context.Context, cep string) (string, error) {
+	start := time.Now()
+	city, err := f.next.GetLocationByCEP(ctx, cep)
+	if !errors.Is(err, service.ErrInvalidCEPFormat) { // CEP inválido nem chega à ViaCEP
+		f.metrics.observeUpstream(f.provider, time.Since(start), err)
+	}
+	return city, err
+}
+
+type instrumentedWeatherFinder struct {
+	metrics  *Metrics
+	provider string
+	next     service.WeatherFinder
+}
+
+func (f *instrumentedWeatherFinder) GetWeatherByCity(ctx context.Context, city string) (*entity.CurrentWeather, error) {
+	start := time.Now()
+	weather, err := f.next.GetWeatherByCity(ctx, city)
+	f.metrics.observeUpstream(f.provider, time.Since(start), err)
+	return weather, err
+}
+
+// observeUpstream registra uma chamada a um provedor. "Não encontrado" é uma
+// resposta válida do provedor e não conta como erro.
+func (m *Metrics) observeUpstream(provider string, d time.Duration, err error) {
+	outcome := upstreamOutcome(err)
+	m.upstreamRequests.WithLabelValues(provider, outcome).Inc()
+	m.upstreamDuration.WithLabelValues(provider).Observe(d.Seconds())
+	if outcome != "success" && outcome != "not_found" {
+		m.upstreamErrors.WithLabelValues(provider, outcome).Inc()
+	}
+}
+
+// upstreamOutcome classifica o resultado de uma chamada pelas categorias de
+// service.UpstreamError.
+func upstreamOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, service.ErrCEPNotFound), errors.Is(err, service.ErrLocationUnknown):
+		return "not_found"
+	case errors.Is(err, service.ErrUpstreamTimeout):
+		return "timeout"
+	case errors.Is(err, service.ErrUpstreamUnavailable):
+		return "unavailable"
+	case errors.Is(err, service.ErrUpstreamRateLimited):
+		return "rate_limited"
+	case errors.Is(err, service.ErrUpstreamBadKey):
+		return "bad_key"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type locationFunc func(ctx context.Context, cep string) (string, error)
+
+func (f locationFunc) GetLocationByCEP(ctx context.Context, cep string) (string, error) {
+	return f(ctx, cep)
+}
+
+type weatherFunc func(ctx context.Context, city string) (*entity.CurrentWeather, error)
+
+func (f weatherFunc) GetWeatherByCity(ctx context.Context, city string) (*entity.CurrentWeather, error) {
+	return f(ctx, city)
+}
+
+func TestMiddleware(t *testing.T) {
+	m := New()
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Route("/v1", func(r chi.Router) {
+		r.Get("/weather/{cep}", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, 1.0, testutil.ToFloat64(m.inFlight), "request in flight while served")
+			w.WriteHeader(http.StatusNotFound)
+		})
+	})
+
+	for _, path := range []string{"/v1/weather/01001000", "/v1/weather/99999999", "/nope", "/other"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	for _, method := range []string{"FOO", "BAR"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/nope", nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/v1/weather/{cep}", "404")), "grouped by route pattern")
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", unmatchedRoute, "404")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues(otherMethod, unmatchedRoute, "405")), "non-standard methods share one label")
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.inFlight))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.duration))
+}
+
+func TestInstrumentedFinders(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+	location := m.InstrumentLocation(service.ProviderViaCEP, locationFunc(func(_ context.Context, cep string) (string, error) {
+		switch cep {
+		case "01001000":
+			return "São Paulo", nil
+		case "99999999":
+			return "", service.ErrCEPNotFound
+		default:
+			return "", service.ErrInvalidCEPFormat
+		}
+	}))
+	weather := m.InstrumentWeather(service.ProviderWeatherAPI, weatherFunc(func(context.Context, string) (*entity.CurrentWeather, error) {
+		return nil, &service.UpstreamError{Provider: service.ProviderWeatherAPI, Kind: service.ErrUpstreamTimeout, Err: errors.New("slow")}
+	}))
+
+	for _, cep := range []string{"01001000", "99999999", "123"} {
+		_, _ = location.GetLocationByCEP(ctx, cep)
+	}
+	_, err := weather.GetWeatherByCity(ctx, "São Paulo")
+	assert.ErrorIs(t, err, service.ErrUpstreamTimeout, "errors pass through")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.upstreamRequests.WithLabelValues("viacep", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.upstreamRequests.WithLabelValues("viacep", "not_found")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.upstreamRequests.WithLabelValues("weatherapi", "timeout")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.upstreamErrors.WithLabelValues("weatherapi", "timeout")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.upstreamErrors), "not found is not an error")
+	assert.Equal(t, 2, testutil.CollectAndCount(m.upstreamDuration), "invalid CEPs never reach the provider")
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.RegisterCache(service.CacheLocation, func() service.CacheStats {
+		return service.CacheStats{Hits: 3, Misses: 1, Entries: 1}
+	})
+	m.upstreamRequests.WithLabelValues("viacep", "success").Inc()
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, strings.HasPrefix(rr.Header().Get("Content-Type"), "text/plain"))
+
+	body := rr.Body.String()
+	for _, line := range []string{
+		`cache_hits_total{cache="location"} 3`,
+		`cache_misses_total{cache="location"} 1`,
+		`cache_entries{cache="location"} 1`,
+		`upstream_requests_total{outcome="success",provider="viacep"} 1`,
+		"go_goroutines",
+	} {
+		assert.Contains(t, body, line)
+	}
+}
@@ -0,0 +1,51 @@
+// Package mocks reúne os mocks (testify) das interfaces de service usados nos
+// testes dos pacotes handler, service, web e rpc. Só depende de entity, então
+// pode ser importado pelos testes do próprio pacote service.
+package mocks
+
+import (
+	"context"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// LocationFinder é um mock para service.LocationFinder.
+type LocationFinder struct {
+	mock.Mock
+}
+
+func (m *LocationFinder) GetLocationByCEP(ctx context.Context, cep string) (string, error) {
+	args := m.Called(ctx, cep)
+	return args.String(0), args.Error(1)
+}
+
+// WeatherFinder é um mock para service.WeatherFinder. Um retorno nil (cenários
+// de erro) não causa panic.
+type WeatherFinder struct {
+	mock.Mock
+}
+
+func (m *WeatherFinder) GetWeatherByCity(ctx context.Context, city string) (*entity.CurrentWeather, error) {
+	args := m.Called(ctx, city)
+	val, _ := args.Get(0).(*entity.CurrentWeather)
+	return val, args.Error(1)
+}
+
+// TemperatureConverter é um mock para service.TemperatureConverter.
+type TemperatureConverter struct {
+	mock.Mock
+}
+
+func (m *TemperatureConverter) ConvertTemperatures(tempC float64) *entity.WeatherOutput {
+	args := m.Called(tempC)
+	output, _ := args.Get(0).(*entity.WeatherOutput)
+	return output
+}
+
+func (m *TemperatureConverter) ThermalIndices(weather *entity.CurrentWeather) *entity.ThermalIndices {
+	args := m.Called(weather)
+	indices, _ := args.Get(0).(*entity.ThermalIndices)
+	return indices
+}
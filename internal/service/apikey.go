@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+)
+
+// Escopos concedidos às chaves de API.
+const (
+	ScopeWeatherRead        = "weather:read"        // Rotas de clima, lote e streaming
+	ScopeSubscriptionsRead  = "subscriptions:read"  // Consulta das assinaturas e dos avisos não entregues
+	ScopeSubscriptionsWrite = "subscriptions:write" // Criação e remoção de assinaturas
+)
+
+// AuthenticatedTier é o tier de limite das chaves sem cota própria.
+const AuthenticatedTier = "authenticated"
+
+// apiKeyPrefix identifica as chaves desta API (ex: em varreduras de segredos vazados).
+const apiKeyPrefix = "wk_"
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidScope   = errors.New("invalid scope")
+	ErrInvalidQuota   = errors.New("invalid quota")
+)
+
+// APIKeyStore guarda as chaves de API. O valor das chaves nunca chega ao
+// store: só o hash (HashAPIKey), usado para encontrá-las na autenticação.
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, key entity.APIKey, hash string) error
+	GetAPIKey(ctx context.Context, id string) (*entity.APIKey, error)
+	// FindAPIKeyByHash retorna a chave ativa com o hash informado; chaves
+	// revogadas resultam em ErrAPIKeyNotFound.
+	FindAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]entity.APIKey, error)
+	// RotateAPIKey troca o hash e o prefixo de uma chave ativa, invalidando o valor anterior.
+	RotateAPIKey(ctx context.Context, id, hash, prefix string, at time.Time) (*entity.APIKey, error)
+	// RevokeAPIKey revoga uma chave ativa; ela continua listada, com RevokedAt.
+	RevokeAPIKey(ctx context.Context, id string, at time.Time) error
+}
+
+// NewAPIKeyStore cria o store do backend informado: "file" (JSON, o padrão) ou
+// "sqlite". Com o backend "file" e sem caminho, as chaves ficam só em memória.
+func NewAPIKeyStore(backend, path string) (APIKeyStore, error) {
+	switch backend {
+	case "", "file":
+		return NewFileAPIKeyStore(path)
+	case "sqlite":
+		return NewSQLiteAPIKeyStore(path)
+	default:
+		return nil, fmt.Errorf("unknown API key store %q: expected file or sqlite", backend)
+	}
+}
+
+// Scopes lista os escopos existentes.
+func Scopes() []string {
+	return []string{ScopeWeatherRead, ScopeSubscriptionsRead, ScopeSubscriptionsWrite}
+}
+
+// NormalizeScopes valida os escopos pedidos, remove repetições e os ordena.
+// Sem escopos, a chave recebe só weather:read.
+func NormalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return []string{ScopeWeatherRead}, nil
+	}
+	out := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if !slices.Contains(Scopes(), s) {
+			return nil, fmt.Errorf("%w %q: expected one of %s", ErrInvalidScope, s, strings.Join(Scopes(), ", "))
+		}
+		out = append(out, s)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// ParseQuota interpreta a cota de uma chave ("limite/janela[:rajada]") como um tier.
+func ParseQuota(quota string) (RateLimitTier, error) {
+	tier, err := ParseRateLimitTier("quota=" + quota)
+	if err != nil {
+		return RateLimitTier{}, fmt.Errorf("%w %q: expected limit/window[:burst] such as 600/1m:60", ErrInvalidQuota, quota)
+	}
+	return tier, nil
+}
+
+// NewAPIKeySecret gera o valor de uma chave, o prefixo exibido nas listagens e o hash guardado no store.
+func NewAPIKeySecret() (key, prefix, hash string) {
+	key = apiKeyPrefix + randomHex(24)
+	return key, key[:len(apiKeyPrefix)+8], HashAPIKey(key)
+}
+
+// HashAPIKey calcula o hash guardado no lugar da chave. As chaves são aleatórias
+// e longas, então um SHA-256 basta (não há dicionário a resistir).
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAPIKeyID gera o identificador público de uma chave.
+func NewAPIKeyID() string {
+	return randomHex(8)
+}
+
+// HasScope informa se a chave concede o escopo.
+func HasScope(key *entity.APIKey, scope string) bool {
+	return slices.Contains(key.Scopes, scope)
+}
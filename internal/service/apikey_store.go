@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+
+	_ "modernc.org/sqlite" // Driver "sqlite", em Go puro (sem cgo)
+)
+
+// FileAPIKeyStore implementa APIKeyStore em memória, gravando todas as chaves
+// (com os hashes) em um arquivo JSON a cada alteração. Sem caminho, nada é gravado.
+type FileAPIKeyStore struct {
+	Path string
+
+	mu     sync.Mutex
+	keys   map[string]entity.APIKey
+	hashes map[string]string // hash -> ID
+}
+
+// apiKeyRecord é uma chave no arquivo de persistência.
+type apiKeyRecord struct {
+	entity.APIKey
+	Hash string `json:"hash"`
+}
+
+// NewFileAPIKeyStore cria o store e carrega o arquivo em path, se existir.
+func NewFileAPIKeyStore(path string) (*FileAPIKeyStore, error) {
+	s := &FileAPIKeyStore{Path: path, keys: map[string]entity.APIKey{}, hashes: map[string]string{}}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+	var records []apiKeyRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode API keys file %s: %w", path, err)
+	}
+	for _, rec := range records {
+		s.keys[rec.ID] = rec.APIKey
+		s.hashes[rec.Hash] = rec.ID
+	}
+	return s, nil
+}
+
+func (s *FileAPIKeyStore) CreateAPIKey(_ context.Context, key entity.APIKey, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key.Key = ""
+	s.keys[key.ID] = key
+	s.hashes[hash] = key.ID
+	return s.save()
+}
+
+func (s *FileAPIKeyStore) GetAPIKey(_ context.Context, id string) (*entity.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	return &key, nil
+}
+
+func (s *FileAPIKeyStore) FindAPIKeyByHash(_ context.Context, hash string) (*entity.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[s.hashes[hash]]
+	if !ok || key.RevokedAt != nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	return &key, nil
+}
+
+// ListAPIKeys retorna as chaves, inclusive as revogadas, em ordem de criação.
+func (s *FileAPIKeyStore) ListAPIKeys(_ context.Context) ([]entity.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]entity.APIKey, 0, len(s.keys))
+	for _, rec := range s.records() {
+		out = append(out, rec.APIKey)
+	}
+	return out, nil
+}
+
+func (s *FileAPIKeyStore) RotateAPIKey(_ context.Context, id, hash, prefix string, at time.Time) (*entity.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	for h, owner := range s.hashes {
+		if owner == id {
+			delete(s.hashes, h)
+		}
+	}
+	key.Prefix = prefix
+	key.RotatedAt = &at
+	s.keys[id] = key
+	s.hashes[hash] = id
+	return &key, s.save()
+}
+
+func (s *FileAPIKeyStore) RevokeAPIKey(_ context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return ErrAPIKeyNotFound
+	}
+	key.RevokedAt = &at
+	s.keys[id] = key
+	return s.save()
+}
+
+// records junta cada chave ao seu hash, em ordem de criação. Chamado com mu travado.
+func (s *FileAPIKeyStore) records() []apiKeyRecord {
+	out := make([]apiKeyRecord, 0, len(s.keys))
+	for hash, id := range s.hashes {
+		out = append(out, apiKeyRecord{APIKey: s.keys[id], Hash: hash})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// save grava as chaves como em FileSubscriptionStore.save. Chamado com mu travado.
+func (s *FileAPIKeyStore) save() error {
+	if s.Path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.records(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode API keys: %w", err)
+	}
+	if err := writeFileAtomic(s.Path, data); err != nil {
+		return fmt.Errorf("failed to write API keys file: %w", err)
+	}
+	return nil
+}
+
+// SQLiteAPIKeyStore implementa APIKeyStore em um banco SQLite, para quando as
+// chaves são administradas por fora da API (ex: scripts ou outras ferramentas).
+type SQLiteAPIKeyStore struct {
+	db *sql.DB
+}
+
+const sqliteAPIKeySchema = `CREATE TABLE IF NOT EXISTS api_keys (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	prefix     TEXT NOT NULL,
+	hash       TEXT NOT NULL UNIQUE,
+	scopes     TEXT NOT NULL,
+	quota      TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL,
+	rotated_at TEXT,
+	revoked_at TEXT
+)`
+
+const sqliteAPIKeyColumns = "id, name, prefix, scopes, quota, created_at, rotated_at, revoked_at"
+
+// NewSQLiteAPIKeyStore abre (ou cria) o banco em path e a tabela api_keys.
+func NewSQLiteAPIKeyStore(path string) (*SQLiteAPIKeyStore, error) {
+	if path == "" {
+		return nil, errors.New("sqlite API key store requires a path")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create API keys directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open API keys database: %w", err)
+	}
+	db.SetMaxOpenConns(1) // O SQLite serializa as escritas; uma conexão evita SQLITE_BUSY
+	if _, err := db.Exec(sqliteAPIKeySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create API keys table: %w", err)
+	}
+	return &SQLiteAPIKeyStore{db: db}, nil
+}
+
+// Close fecha o banco.
+func (s *SQLiteAPIKeyStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteAPIKeyStore) CreateAPIKey(ctx context.Context, key entity.APIKey, hash string) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO api_keys (id, name, prefix, hash, scopes, quota, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		key.ID, key.Name, key.Prefix, hash, strings.Join(key.Scopes, " "), key.Quota, formatSQLiteTime(key.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to insert API key: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteAPIKeyStore) GetAPIKey(ctx context.Context, id string) (*entity.APIKey, error) {
+	return s.queryOne(ctx, "SELECT "+sqliteAPIKeyColumns+" FROM api_keys WHERE id = ?", id)
+}
+
+func (s *SQLiteAPIKeyStore) FindAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	return s.queryOne(ctx, "SELECT "+sqliteAPIKeyColumns+" FROM api_keys WHERE hash = ? AND revoked_at IS NULL", hash)
+}
+
+func (s *SQLiteAPIKeyStore) ListAPIKeys(ctx context.Context) ([]entity.APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+sqliteAPIKeyColumns+" FROM api_keys ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+	out := []entity.APIKey{}
+	for rows.Next() {
+		key, err := scanSQLiteAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *key)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLiteAPIKeyStore) RotateAPIKey(ctx context.Context, id, hash, prefix string, at time.Time) (*entity.APIKey, error) {
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE api_keys SET hash = ?, prefix = ?, rotated_at = ? WHERE id = ? AND revoked_at IS NULL",
+		hash, prefix, formatSQLiteTime(at), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate API key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrAPIKeyNotFound
+	}
+	return s.GetAPIKey(ctx, id)
+}
+
+func (s *SQLiteAPIKeyStore) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", formatSQLiteTime(at), id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteAPIKeyStore) queryOne(ctx context.Context, query string, args ...any) (*entity.APIKey, error) {
+	key, err := scanSQLiteAPIKey(s.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+func scanSQLiteAPIKey(row interface{ Scan(...any) error }) (*entity.APIKey, error) {
+	var (
+		key                  entity.APIKey
+		scopes, createdAt    string
+		rotatedAt, revokedAt sql.NullString
+	)
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &key.Quota, &createdAt, &rotatedAt, &revokedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read API key: %w", err)
+	}
+	key.Scopes = strings.Fields(scopes)
+	key.CreatedAt = parseSQLiteTime(createdAt)
+	if rotatedAt.Valid {
+		t := parseSQLiteTime(rotatedAt.String)
+		key.RotatedAt = &t
+	}
+	if revokedAt.Valid {
+		t := parseSQLiteTime(revokedAt.String)
+		key.RevokedAt = &t
+	}
+	return &key, nil
+}
+
+// As datas são gravadas em RFC 3339 com nanossegundos, em UTC, o que mantém a
+// ordenação textual igual à cronológica.
+func formatSQLiteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z07:00")
+}
+
+func parseSQLiteTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
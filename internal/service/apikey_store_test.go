@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyStores(t *testing.T) {
+	stores := map[string]func(t *testing.T, path string) APIKeyStore{
+		"file": func(t *testing.T, path string) APIKeyStore {
+			s, err := NewAPIKeyStore("file", path+".json")
+			assert.NoError(t, err)
+			return s
+		},
+		"sqlite": func(t *testing.T, path string) APIKeyStore {
+			s, err := NewAPIKeyStore("sqlite", path+".db")
+			assert.NoError(t, err)
+			t.Cleanup(func() { s.(*SQLiteAPIKeyStore).Close() })
+			return s
+		},
+	}
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			path := t.TempDir() + "/data/api_keys"
+			store := open(t, path)
+
+			created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+			assert.NoError(t, store.CreateAPIKey(ctx, entity.APIKey{ID: "b", Name: "beta", Prefix: "wk_b", Scopes: []string{ScopeWeatherRead}, CreatedAt: created.Add(time.Minute)}, HashAPIKey("key-b")))
+			assert.NoError(t, store.CreateAPIKey(ctx, entity.APIKey{ID: "a", Name: "alpha", Prefix: "wk_a", Scopes: []string{ScopeSubscriptionsRead, ScopeWeatherRead}, Quota: "10/1s", CreatedAt: created}, HashAPIKey("key-a")))
+
+			key, err := store.FindAPIKeyByHash(ctx, HashAPIKey("key-a"))
+			if assert.NoError(t, err) {
+				assert.Equal(t, "alpha", key.Name)
+				assert.Equal(t, []string{ScopeSubscriptionsRead, ScopeWeatherRead}, key.Scopes)
+				assert.Equal(t, "10/1s", key.Quota)
+				assert.True(t, created.Equal(key.CreatedAt))
+			}
+
+			rotated, err := store.RotateAPIKey(ctx, "a", HashAPIKey("key-a2"), "wk_a2", created.Add(time.Hour))
+			if assert.NoError(t, err) {
+				assert.Equal(t, "wk_a2", rotated.Prefix)
+				assert.NotNil(t, rotated.RotatedAt)
+			}
+			_, err = store.FindAPIKeyByHash(ctx, HashAPIKey("key-a"))
+			assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+			assert.NoError(t, store.RevokeAPIKey(ctx, "b", created.Add(time.Hour)))
+			assert.ErrorIs(t, store.RevokeAPIKey(ctx, "b", created.Add(time.Hour)), ErrAPIKeyNotFound)
+			_, err = store.FindAPIKeyByHash(ctx, HashAPIKey("key-b"))
+			assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+			_, err = store.RotateAPIKey(ctx, "b", HashAPIKey("key-b2"), "wk_b2", created)
+			assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+			_, err = store.GetAPIKey(ctx, "missing")
+			assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+			// Um novo store lê o que o anterior gravou
+			if closer, ok := store.(*SQLiteAPIKeyStore); ok {
+				closer.Close()
+			}
+			reloaded := open(t, path)
+			keys, err := reloaded.ListAPIKeys(ctx)
+			assert.NoError(t, err)
+			if assert.Len(t, keys, 2) {
+				assert.Equal(t, "a", keys[0].ID, "ordered by creation")
+				assert.Equal(t, "b", keys[1].ID)
+				assert.NotNil(t, keys[1].RevokedAt)
+			}
+			_, err = reloaded.FindAPIKeyByHash(ctx, HashAPIKey("key-a2"))
+			assert.NoError(t, err)
+		})
+	}
+}
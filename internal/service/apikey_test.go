@@ -0,0 +1,27 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeScopesAndQuota(t *testing.T) {
+	scopes, err := NormalizeScopes(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ScopeWeatherRead}, scopes)
+
+	scopes, err = NormalizeScopes([]string{ScopeWeatherRead, ScopeSubscriptionsRead, ScopeWeatherRead})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ScopeSubscriptionsRead, ScopeWeatherRead}, scopes)
+
+	_, err = NormalizeScopes([]string{"weather:write"})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	tier, err := ParseQuota("600/1m:60")
+	assert.NoError(t, err)
+	assert.Equal(t, 600, tier.Limit)
+	assert.Equal(t, 60, tier.Capacity())
+	_, err = ParseQuota("600")
+	assert.ErrorIs(t, err, ErrInvalidQuota)
+}
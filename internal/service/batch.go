@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
+)
+
+// DefaultBatchConcurrency é o número de consultas simultâneas usado quando nenhum limite é informado.
+const DefaultBatchConcurrency = 8
+
+// Limites padrão do lote, usados pelas rotas HTTP e pelo gRPC quando a
+// configuração não informa valores.
+const (
+	DefaultBatchMaxSize = 100
+	DefaultBatchTimeout = 10 * time.Second
+)
+
+// BatchResult é o resultado da consulta de um CEP dentro de um lote.
+type BatchResult struct {
+	CEP     string
+	City    string
+	Weather *entity.CurrentWeather
+	Err     error
+}
+
+// BatchLookup consulta o clima de vários CEPs com concorrência limitada.
+//
+// A consulta é feita em duas etapas: primeiro os CEPs (distintos) são resolvidos
+// para cidades; depois o clima é buscado uma única vez por cidade, de modo que
+// CEPs da mesma cidade geram uma só chamada à WeatherAPI.
+type BatchLookup struct {
+	LocationService LocationFinder
+	WeatherService  WeatherFinder
+	Concurrency     int
+}
+
+// NewBatchLookup cria uma nova instância de BatchLookup.
+func NewBatchLookup(loc LocationFinder, weather WeatherFinder, concurrency int) *BatchLookup {
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	return &BatchLookup{LocationService: loc, WeatherService: weather, Concurrency: concurrency}
+}
+
+// Lookup retorna um BatchResult por CEP, na mesma ordem de ceps. Itens que não
+// puderam ser consultados antes do cancelamento de ctx recebem ctx.Err().
+func (b *BatchLookup) Lookup(ctx context.Context, ceps []string) []BatchResult {
+	// 1. Resolver cada CEP distinto para uma cidade
+	uniqueCEPs := distinct(ceps)
+	cities := make([]string, len(uniqueCEPs))
+	cepErrs := make([]error, len(uniqueCEPs))
+	b.forEach(ctx, len(uniqueCEPs), func(i int) {
+		// Cada item registra as suas latências, sem sobrescrever as dos outros
+		itemCtx := logging.WithFields(ctx, slog.String("cep", uniqueCEPs[i]))
+		cities[i], cepErrs[i] = b.LocationService.GetLocationByCEP(itemCtx, uniqueCEPs[i])
+	}, func(i int, err error) { cepErrs[i] = err })
+
+	// 2. Buscar o clima uma única vez por cidade
+	var resolved []string
+	for i, city := range cities {
+		if cepErrs[i] == nil {
+			resolved = append(resolved, city)
+		}
+	}
+	uniqueCities := distinct(resolved)
+	weathers := make([]*entity.CurrentWeather, len(uniqueCities))
+	cityErrs := make([]error, len(uniqueCities))
+	b.forEach(ctx, len(uniqueCities), func(i int) {
+		itemCtx := logging.WithFields(ctx, slog.String("city", uniqueCities[i]))
+		weathers[i], cityErrs[i] = b.WeatherService.GetWeatherByCity(itemCtx, uniqueCities[i])
+	}, func(i int, err error) { cityErrs[i] = err })
+
+	// 3. Montar o resultado na ordem original
+	cepIndex := indexOf(uniqueCEPs)
+	cityIndex := indexOf(uniqueCities)
+	results := make([]BatchResult, len(ceps))
+	for i, cep := range ceps {
+		c := cepIndex[cep]
+		results[i] = BatchResult{CEP: cep, City: cities[c], Err: cepErrs[c]}
+		if results[i].Err != nil {
+			results[i].City = ""
+			continue
+		}
+		w := cityIndex[cities[c]]
+		results[i].Weather, results[i].Err = weathers[w], cityErrs[w]
+	}
+	return results
+}
+
+// forEach executa fn para os índices [0, n) com no máximo b.Concurrency goroutines.
+// Índices que não chegaram a ser executados antes do cancelamento de ctx são
+// repassados a cancelled.
+func (b *BatchLookup) forEach(ctx context.Context, n int, fn func(i int), cancelled func(i int, err error)) {
+	sem := make(chan struct{}, b.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		acquired := false
+		select {
+		case sem <- struct{}{}:
+			acquired = true
+		case <-ctx.Done():
+		}
+		// O select escolhe ao acaso quando os dois casos estão prontos, então o
+		// cancelamento é conferido de novo depois de obter a vaga.
+		if err := ctx.Err(); err != nil {
+			if acquired {
+				<-sem
+			}
+			cancelled(i, err)
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func distinct(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func indexOf(values []string) map[string]int {
+	idx := make(map[string]int, len(values))
+	for i, v := range values {
+		idx[v] = i
+	}
+	return idx
+}
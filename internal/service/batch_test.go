@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBatchLookup_Lookup(t *testing.T) {
+	t.Run("Dedupes Cities And Keeps Order", func(t *testing.T) {
+		mockLocation := new(mocks.LocationFinder)
+		mockWeather := new(mocks.WeatherFinder)
+		lookup := NewBatchLookup(mockLocation, mockWeather, 2)
+
+		spWeather := &entity.CurrentWeather{TempC: 25}
+		rjWeather := &entity.CurrentWeather{TempC: 30}
+		mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil).Once()
+		mockLocation.On("GetLocationByCEP", mock.Anything, "01311000").Return("São Paulo", nil).Once()
+		mockLocation.On("GetLocationByCEP", mock.Anything, "20010000").Return("Rio de Janeiro", nil).Once()
+		mockLocation.On("GetLocationByCEP", mock.Anything, "123").Return("", ErrInvalidCEPFormat).Once()
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(spWeather, nil).Once()
+		mockWeather.On("GetWeatherByCity", mock.Anything, "Rio de Janeiro").Return(rjWeather, nil).Once()
+
+		results := lookup.Lookup(context.Background(), []string{"01001000", "20010000", "123", "01311000", "01001000"})
+
+		assert.Len(t, results, 5)
+		assert.Equal(t, []string{"01001000", "20010000", "123", "01311000", "01001000"},
+			[]string{results[0].CEP, results[1].CEP, results[2].CEP, results[3].CEP, results[4].CEP})
+		assert.Equal(t, spWeather, results[0].Weather)
+		assert.Equal(t, "Rio de Janeiro", results[1].City)
+		assert.Equal(t, rjWeather, results[1].Weather)
+		assert.ErrorIs(t, results[2].Err, ErrInvalidCEPFormat)
+		assert.Nil(t, results[2].Weather)
+		assert.Equal(t, spWeather, results[3].Weather)
+		assert.Equal(t, spWeather, results[4].Weather)
+		// Cada CEP distinto e cada cidade são consultados uma única vez
+		mockLocation.AssertExpectations(t)
+		mockWeather.AssertNumberOfCalls(t, "GetWeatherByCity", 2)
+	})
+
+	t.Run("Bounded Concurrency", func(t *testing.T) {
+		mockLocation := new(mocks.LocationFinder)
+		mockWeather := new(mocks.WeatherFinder)
+		lookup := NewBatchLookup(mockLocation, mockWeather, 3)
+
+		var mu sync.Mutex
+		inFlight, maxInFlight := 0, 0
+		track := func(mock.Arguments) {
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}
+		var ceps []string
+		for i := 0; i < 12; i++ {
+			cep := fmt.Sprintf("0100%04d", i)
+			ceps = append(ceps, cep)
+			mockLocation.On("GetLocationByCEP", mock.Anything, cep).Run(track).Return(cep, nil).Once()
+			mockWeather.On("GetWeatherByCity", mock.Anything, cep).Run(track).Return(&entity.CurrentWeather{}, nil).Once()
+		}
+
+		results := lookup.Lookup(context.Background(), ceps)
+
+		assert.Len(t, results, 12)
+		assert.LessOrEqual(t, maxInFlight, 3)
+		mockLocation.AssertExpectations(t)
+		mockWeather.AssertExpectations(t)
+	})
+
+	t.Run("Cancelled Context", func(t *testing.T) {
+		mockLocation := new(mocks.LocationFinder)
+		mockWeather := new(mocks.WeatherFinder)
+		lookup := NewBatchLookup(mockLocation, mockWeather, 1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Run(func(mock.Arguments) { cancel() }).
+			Return("", context.Canceled).Once()
+
+		results := lookup.Lookup(ctx, []string{"01001000", "20010000"})
+
+		assert.ErrorIs(t, results[0].Err, context.Canceled)
+		assert.ErrorIs(t, results[1].Err, context.Canceled)
+		mockLocation.AssertNotCalled(t, "GetLocationByCEP", mock.Anything, "20010000")
+		mockWeather.AssertNotCalled(t, "GetWeatherByCity", mock.Anything, mock.Anything)
+	})
+}
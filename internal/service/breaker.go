@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+)
+
+// Estados do circuit breaker.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// ErrCircuitOpen é a causa das falhas devolvidas sem chamar o provedor, com o
+// circuito aberto. Vem dentro de um UpstreamError com Kind ErrUpstreamUnavailable.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// halfOpenRetryAfter é a espera sugerida enquanto a chamada de teste do
+// circuito semiaberto está em andamento.
+const halfOpenRetryAfter = time.Second
+
+// BreakerStatus é o retrato de um CircuitBreaker.
+type BreakerStatus struct {
+	Provider    string
+	State       string // BreakerClosed, BreakerOpen ou BreakerHalfOpen
+	Failures    int    // Falhas consecutivas
+	OpenedAt    time.Time
+	LastSuccess time.Time // Última resposta do provedor, mesmo que "não encontrado"
+	LastFailure time.Time
+}
+
+// CircuitBreaker deixa de chamar um provedor depois de threshold falhas
+// seguidas (timeouts e indisponibilidade). Passado o cooldown, uma única
+// chamada de teste decide se o circuito fecha ou volta a abrir.
+type CircuitBreaker struct {
+	provider  string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu     sync.Mutex
+	status BreakerStatus
+	trial  bool // Chamada de teste do circuito semiaberto em andamento
+}
+
+// NewCircuitBreaker cria um circuito fechado para o provedor informado.
+func NewCircuitBreaker(provider string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		provider:  provider,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		status:    BreakerStatus{Provider: provider, State: BreakerClosed},
+	}
+}
+
+// Status retorna o estado atual do circuito.
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.status.State == BreakerOpen && !b.now().Before(b.status.OpenedAt.Add(b.cooldown)) {
+		s := b.status
+		s.State = BreakerHalfOpen // A próxima chamada será a de teste
+		return s
+	}
+	return b.status
+}
+
+// Reset fecha o circuito e zera as falhas.
+func (b *CircuitBreaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status.State = BreakerClosed
+	b.status.Failures = 0
+	b.trial = false
+}
+
+// Trip abre o circuito manualmente, como se o limite de falhas fosse atingido.
+func (b *CircuitBreaker) Trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.open(b.now())
+}
+
+func (b *CircuitBreaker) open(now time.Time) {
+	b.status.State = BreakerOpen
+	b.status.OpenedAt = now
+	b.trial = false
+}
+
+// allow decide se a chamada pode seguir para o provedor.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if b.status.State == BreakerOpen {
+		if wait := b.status.OpenedAt.Add(b.cooldown).Sub(now); wait > 0 {
+			return b.rejection(wait)
+		}
+		b.status.State = BreakerHalfOpen
+	}
+	if b.status.State == BreakerHalfOpen {
+		if b.trial {
+			return b.rejection(halfOpenRetryAfter)
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) rejection(retryAfter time.Duration) error {
+	return &UpstreamError{Provider: b.provider, Kind: ErrUpstreamUnavailable, RetryAfter: retryAfter, Err: ErrCircuitOpen}
+}
+
+// record registra o resultado de uma chamada liberada por allow. Respostas do
+// provedor, inclusive de erro, contam como sucesso; cancelamentos e prazos do
+// chamador, que classifyTransportError deixa sem classificar, não contam para
+// nenhum lado.
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	switch {
+	case errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrUpstreamTimeout):
+		b.trial = false
+	case errors.Is(err, ErrUpstreamTimeout), errors.Is(err, ErrUpstreamUnavailable):
+		b.status.Failures++
+		b.status.LastFailure = now
+		if b.status.State == BreakerHalfOpen || b.status.Failures >= b.threshold {
+			b.open(now)
+		}
+	default:
+		b.status.State = BreakerClosed
+		b.status.Failures = 0
+		b.trial = false
+		if !errors.Is(err, ErrUpstreamBadKey) { // Chave recusada não é sinal de saúde
+			b.status.LastSuccess = now
+		}
+	}
+}
+
+// BreakerLocationFinder decora um LocationFinder com um CircuitBreaker.
+type BreakerLocationFinder struct {
+	next    LocationFinder
+	breaker *CircuitBreaker
+}
+
+// NewBreakerLocationFinder cria o decorador.
+func NewBreakerLocationFinder(next LocationFinder, breaker *CircuitBreaker) *BreakerLocationFinder {
+	return &BreakerLocationFinder{next: next, breaker: breaker}
+}
+
+// GetLocationByCEP implementa LocationFinder. CEPs inválidos são recusados
+// antes do circuito, já que nem chegariam à ViaCEP.
+func (f *BreakerLocationFinder) GetLocationByCEP(ctx context.Context, cep string) (string, error) {
+	if err := ValidateCEP(cep); err != nil {
+		return "", err
+	}
+	if err := f.breaker.allow(); err != nil {
+		return "", err
+	}
+	city, err := f.next.GetLocationByCEP(ctx, cep)
+	f.breaker.record(err)
+	return city, err
+}
+
+// BreakerWeatherFinder decora um WeatherFinder com um CircuitBreaker.
+type BreakerWeatherFinder struct {
+	next    WeatherFinder
+	breaker *CircuitBreaker
+}
+
+// NewBreakerWeatherFinder cria o decorador.
+func NewBreakerWeatherFinder(next WeatherFinder, breaker *CircuitBreaker) *BreakerWeatherFinder {
+	return &BreakerWeatherFinder{next: next, breaker: breaker}
+}
+
+// GetWeatherByCity implementa WeatherFinder.
+func (f *BreakerWeatherFinder) GetWeatherByCity(ctx context.Context, city string) (*entity.CurrentWeather, error) {
+	if err := f.breaker.allow(); err != nil {
+		return nil, err
+	}
+	weather, err := f.next.GetWeatherByCity(ctx, city)
+	f.breaker.record(err)
+	return weather, err
+}
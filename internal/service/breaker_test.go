@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	unavailable := &UpstreamError{Provider: ProviderWeatherAPI, Kind: ErrUpstreamUnavailable, Err: errors.New("status 503")}
+
+	newBreaker := func() (*CircuitBreaker, *mocks.WeatherFinder, *time.Time) {
+		now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+		b := NewCircuitBreaker(ProviderWeatherAPI, 2, 30*time.Second)
+		b.now = func() time.Time { return now }
+		return b, new(mocks.WeatherFinder), &now
+	}
+
+	t.Run("Opens After Consecutive Failures", func(t *testing.T) {
+		b, next, now := newBreaker()
+		next.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(nil, unavailable).Twice()
+		finder := NewBreakerWeatherFinder(next, b)
+
+		for range 2 {
+			_, err := finder.GetWeatherByCity(ctx, "São Paulo")
+			assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+		}
+		assert.Equal(t, BreakerOpen, b.Status().State)
+
+		*now = now.Add(10 * time.Second)
+		_, err := finder.GetWeatherByCity(ctx, "São Paulo")
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.ErrorIs(t, err, ErrUpstreamUnavailable, "answered as 503")
+		var upErr *UpstreamError
+		if assert.ErrorAs(t, err, &upErr) {
+			assert.Equal(t, 20*time.Second, upErr.RetryAfter)
+		}
+		next.AssertNumberOfCalls(t, "GetWeatherByCity", 2)
+	})
+
+	t.Run("Half Open Trial", func(t *testing.T) {
+		b, next, now := newBreaker()
+		next.On("GetWeatherByCity", mock.Anything, "fail").Return(nil, unavailable)
+		next.On("GetWeatherByCity", mock.Anything, "ok").Return(&entity.CurrentWeather{TempC: 20}, nil)
+		finder := NewBreakerWeatherFinder(next, b)
+		b.Trip()
+
+		*now = now.Add(30 * time.Second)
+		assert.Equal(t, BreakerHalfOpen, b.Status().State)
+		_, err := finder.GetWeatherByCity(ctx, "fail")
+		assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+		assert.Equal(t, BreakerOpen, b.Status().State, "a failed trial reopens at once")
+
+		*now = now.Add(30 * time.Second)
+		_, err = finder.GetWeatherByCity(ctx, "ok")
+		assert.NoError(t, err)
+		status := b.Status()
+		assert.Equal(t, BreakerClosed, status.State)
+		assert.Zero(t, status.Failures)
+		assert.Equal(t, *now, status.LastSuccess)
+	})
+
+	t.Run("Provider Answers Are Not Failures", func(t *testing.T) {
+		b, _, _ := newBreaker()
+		next := new(mocks.LocationFinder)
+		next.On("GetLocationByCEP", mock.Anything, "99999999").Return("", ErrCEPNotFound)
+		finder := NewBreakerLocationFinder(next, b)
+
+		for range 3 {
+			_, err := finder.GetLocationByCEP(ctx, "99999999")
+			assert.ErrorIs(t, err, ErrCEPNotFound)
+		}
+		assert.Equal(t, BreakerClosed, b.Status().State)
+		assert.False(t, b.Status().LastSuccess.IsZero())
+
+		// CEPs inválidos nem passam pelo circuito
+		b.Trip()
+		_, err := finder.GetLocationByCEP(ctx, "123")
+		assert.ErrorIs(t, err, ErrInvalidCEPFormat)
+		b.Reset()
+		assert.Equal(t, BreakerClosed, b.Status().State)
+	})
+
+	t.Run("Caller Deadlines Are Not Failures", func(t *testing.T) {
+		b, next, _ := newBreaker()
+		next.On("GetWeatherByCity", mock.Anything, "slow").Return(nil, fmt.Errorf("batch: %w", context.DeadlineExceeded))
+		finder := NewBreakerWeatherFinder(next, b)
+
+		for range 3 {
+			_, err := finder.GetWeatherByCity(ctx, "slow")
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		}
+		status := b.Status()
+		assert.Equal(t, BreakerClosed, status.State)
+		assert.Zero(t, status.Failures)
+		assert.True(t, status.LastSuccess.IsZero(), "not a provider answer either")
+	})
+}
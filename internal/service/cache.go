@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Nomes dos caches dos provedores, usados nas métricas.
+const (
+	CacheLocation = "location"
+	CacheWeather  = "weather"
+)
+
+// cacheHitKey é o atributo que marca, no span da consulta, se ela foi
+// respondida pelo cache.
+const cacheHitKey = attribute.Key("cache.hit")
+
+// cacheSweepInterval é o intervalo entre as limpezas das entradas expiradas.
+const cacheSweepInterval = time.Minute
+
+// CacheStats resume o uso de um cache desde a sua criação.
+type CacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+// Cache é o controle de um cache de provedor usado pela administração e pela
+// recarga da configuração.
+type Cache interface {
+	Stats() CacheStats
+	Flush()
+	Evict(key string) bool
+	SetTTL(ttl time.Duration)
+}
+
+// ttlCache guarda valores por chave até expirarem. Só respostas de sucesso são
+// guardadas: erros sempre voltam a consultar o provedor.
+type ttlCache[V any] struct {
+	ttl          atomic.Int64
+	now          func() time.Time
+	mu           sync.Mutex
+	entries      map[string]cacheEntry[V]
+	lastSweep    time.Time
+	hits, misses atomic.Uint64
+}
+
+type cacheEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+func newTTLCache[V any](ttl time.Duration) *ttlCache[V] {
+	c := &ttlCache[V]{now: time.Now, entries: make(map[string]cacheEntry[V])}
+	c.ttl.Store(int64(ttl))
+	return c
+}
+
+func (c *ttlCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return e.value, ok
+}
+
+func (c *ttlCache[V]) set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.sweep(now)
+	c.entries[key] = cacheEntry[V]{value: value, expires: now.Add(time.Duration(c.ttl.Load()))}
+}
+
+func (c *ttlCache[V]) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < cacheSweepInterval {
+		return
+	}
+	c.lastSweep = now
+	for key, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *ttlCache[V]) stats() CacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Entries: entries}
+}
+
+func (c *ttlCache[V]) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+func (c *ttlCache[V]) evict(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	delete(c.entries, key)
+	return ok && c.now().Before(e.expires)
+}
+
+// CachedLocationFinder decora um LocationFinder guardando a cidade de cada CEP
+// por um tempo fixo, já que ela praticamente nunca muda.
+type CachedLocationFinder struct {
+	next  LocationFinder
+	cache *ttlCache[string]
+}
+
+// NewCachedLocationFinder cria o decorador com o tempo de vida ttl.
+func NewCachedLocationFinder(next LocationFinder, ttl time.Duration) *CachedLocationFinder {
+	return &CachedLocationFinder{next: next, cache: newTTLCache[string](ttl)}
+}
+
+// GetLocationByCEP implementa LocationFinder.
+func (c *CachedLocationFinder) GetLocationByCEP(ctx context.Context, cep string) (string, error) {
+	city, ok := c.cache.get(cep)
+	trace.SpanFromContext(ctx).SetAttributes(cacheHitKey.Bool(ok))
+	if ok {
+		return city, nil
+	}
+	city, err := c.next.GetLocationByCEP(ctx, cep)
+	if err != nil {
+		return "", err
+	}
+	c.cache.set(cep, city)
+	return city, nil
+}
+
+// Stats retorna os acertos, as faltas e o número de entradas do cache.
+func (c *CachedLocationFinder) Stats() CacheStats { return c.cache.stats() }
+
+// Flush descarta todas as entradas.
+func (c *CachedLocationFinder) Flush() { c.cache.flush() }
+
+// Evict descarta a cidade guardada para o CEP e informa se ela existia.
+func (c *CachedLocationFinder) Evict(cep string) bool { return c.cache.evict(cep) }
+
+// SetTTL troca o tempo de vida das próximas entradas; as guardadas mantêm o seu.
+func (c *CachedLocationFinder) SetTTL(ttl time.Duration) { c.cache.ttl.Store(int64(ttl)) }
+
+// CachedWeatherFinder decora um WeatherFinder guardando as condições de cada
+// cidade por um tempo fixo. Um TTL maior que o intervalo do WeatherPoller
+// atrasa as atualizações dos streams.
+type CachedWeatherFinder struct {
+	next  WeatherFinder
+	cache *ttlCache[entity.CurrentWeather]
+}
+
+// NewCachedWeatherFinder cria o decorador com o tempo de vida ttl.
+func NewCachedWeatherFinder(next WeatherFinder, ttl time.Duration) *CachedWeatherFinder {
+	return &CachedWeatherFinder{next: next, cache: newTTLCache[entity.CurrentWeather](ttl)}
+}
+
+// GetWeatherByCity implementa WeatherFinder. Cada chamada recebe a sua cópia do
+// resultado, que pode ser alterada sem afetar o cache.
+func (c *CachedWeatherFinder) GetWeatherByCity(ctx context.Context, city string) (*entity.CurrentWeather, error) {
+	cached, ok := c.cache.get(city)
+	trace.SpanFromContext(ctx).SetAttributes(cacheHitKey.Bool(ok))
+	if ok {
+		return &cached, nil
+	}
+	weather, err := c.next.GetWeatherByCity(ctx, city)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.set(city, *weather)
+	return weather, nil
+}
+
+// Stats retorna os acertos, as faltas e o número de entradas do cache.
+func (c *CachedWeatherFinder) Stats() CacheStats { return c.cache.stats() }
+
+// Flush descarta todas as entradas.
+func (c *CachedWeatherFinder) Flush() { c.cache.flush() }
+
+// Evict descarta as condições guardadas para a cidade e informa se elas existiam.
+func (c *CachedWeatherFinder) Evict(city string) bool { return c.cache.evict(city) }
+
+// SetTTL troca o tempo de vida das próximas entradas; as guardadas mantêm o seu.
+func (c *CachedWeatherFinder) SetTTL(ttl time.Duration) { c.cache.ttl.Store(int64(ttl)) }
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCachedFinders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Location Hits Until Expired", func(t *testing.T) {
+		next := new(mocks.LocationFinder)
+		next.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil).Twice()
+		cached := NewCachedLocationFinder(next, time.Hour)
+		now := time.Now()
+		cached.cache.now = func() time.Time { return now }
+
+		for range 3 {
+			city, err := cached.GetLocationByCEP(ctx, "01001000")
+			assert.NoError(t, err)
+			assert.Equal(t, "São Paulo", city)
+		}
+		assert.Equal(t, CacheStats{Hits: 2, Misses: 1, Entries: 1}, cached.Stats())
+
+		now = now.Add(time.Hour)
+		_, err := cached.GetLocationByCEP(ctx, "01001000")
+		assert.NoError(t, err)
+		next.AssertNumberOfCalls(t, "GetLocationByCEP", 2)
+	})
+
+	t.Run("Errors Are Not Cached", func(t *testing.T) {
+		next := new(mocks.LocationFinder)
+		next.On("GetLocationByCEP", mock.Anything, "99999999").Return("", ErrCEPNotFound)
+		cached := NewCachedLocationFinder(next, time.Hour)
+
+		for range 2 {
+			_, err := cached.GetLocationByCEP(ctx, "99999999")
+			assert.ErrorIs(t, err, ErrCEPNotFound)
+		}
+		next.AssertNumberOfCalls(t, "GetLocationByCEP", 2)
+		assert.Equal(t, CacheStats{Misses: 2}, cached.Stats())
+	})
+
+	t.Run("Weather Copies And Flush", func(t *testing.T) {
+		next := new(mocks.WeatherFinder)
+		next.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 25}, nil)
+		cached := NewCachedWeatherFinder(next, time.Hour)
+
+		first, err := cached.GetWeatherByCity(ctx, "São Paulo")
+		assert.NoError(t, err)
+		first.TempC = 99 // Alterar o resultado não afeta o cache
+		second, err := cached.GetWeatherByCity(ctx, "São Paulo")
+		assert.NoError(t, err)
+		assert.Equal(t, 25.0, second.TempC)
+		next.AssertNumberOfCalls(t, "GetWeatherByCity", 1)
+
+		cached.Flush()
+		assert.Zero(t, cached.Stats().Entries)
+		_, err = cached.GetWeatherByCity(ctx, "São Paulo")
+		assert.NoError(t, err)
+		next.AssertNumberOfCalls(t, "GetWeatherByCity", 2)
+	})
+
+	t.Run("Evict", func(t *testing.T) {
+		next := new(mocks.LocationFinder)
+		next.On("GetLocationByCEP", mock.Anything, mock.Anything).Return("São Paulo", nil)
+		cached := NewCachedLocationFinder(next, time.Hour)
+		_, _ = cached.GetLocationByCEP(ctx, "01001000")
+		_, _ = cached.GetLocationByCEP(ctx, "01310100")
+
+		assert.True(t, cached.Evict("01001000"))
+		assert.False(t, cached.Evict("01001000"), "already evicted")
+		assert.Equal(t, 1, cached.Stats().Entries)
+		_, _ = cached.GetLocationByCEP(ctx, "01001000")
+		next.AssertNumberOfCalls(t, "GetLocationByCEP", 3)
+	})
+
+	t.Run("SetTTL Applies To New Entries", func(t *testing.T) {
+		next := new(mocks.LocationFinder)
+		next.On("GetLocationByCEP", mock.Anything, mock.Anything).Return("São Paulo", nil)
+		cached := NewCachedLocationFinder(next, time.Hour)
+		now := time.Now()
+		cached.cache.now = func() time.Time { return now }
+		_, _ = cached.GetLocationByCEP(ctx, "01001000")
+
+		cached.SetTTL(time.Minute)
+		_, _ = cached.GetLocationByCEP(ctx, "01310100")
+		now = now.Add(2 * time.Minute)
+		_, _ = cached.GetLocationByCEP(ctx, "01001000") // Ainda com o TTL antigo
+		_, _ = cached.GetLocationByCEP(ctx, "01310100") // Expirou com o novo
+		next.AssertNumberOfCalls(t, "GetLocationByCEP", 3)
+	})
+}
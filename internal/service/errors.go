@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Nomes dos provedores externos, usados em UpstreamError.Provider.
+const (
+	ProviderViaCEP     = "viacep"
+	ProviderWeatherAPI = "weatherapi"
+)
+
+// Categorias de falha dos provedores externos. Use errors.Is para testá-las.
+var (
+	ErrUpstreamTimeout     = errors.New("upstream timeout")
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	ErrUpstreamRateLimited = errors.New("upstream rate limited")
+	ErrUpstreamBadKey      = errors.New("upstream rejected API key")
+	ErrLocationUnknown     = errors.New("location unknown to weather provider")
+)
+
+// UpstreamError descreve uma falha classificada de um provedor externo.
+// errors.Is funciona tanto com a categoria (Kind) quanto com a causa (Err).
+type UpstreamError struct {
+	Provider   string        // ProviderViaCEP ou ProviderWeatherAPI
+	Kind       error         // Uma das categorias ErrUpstream*/ErrLocationUnknown
+	RetryAfter time.Duration // Espera sugerida pelo provedor (header Retry-After), se informada
+	Err        error         // Causa original
+}
+
+func (e *UpstreamError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UpstreamError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// classifyTransportError classifica a falha de Client.Do. Cancelamentos e prazos
+// do próprio chamador (ctx da requisição encerrado, como o prazo de um lote) não
+// são culpa do provedor e ficam como estão; só o prazo vencido no transporte
+// (TimeoutTransport ou http.Client.Timeout) vira ErrUpstreamTimeout.
+func classifyTransportError(ctx context.Context, provider string, err error) error {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	kind := ErrUpstreamUnavailable
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		kind = ErrUpstreamTimeout
+	}
+	return &UpstreamError{Provider: provider, Kind: kind, Err: err}
+}
+
+// classifyStatus classifica uma resposta HTTP de erro comum a qualquer provedor.
+// Retorna nil para status que não indicam falha do provedor (ex: 400, 404).
+func classifyStatus(provider string, resp *http.Response, err error) *UpstreamError {
+	var kind error
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		kind = ErrUpstreamRateLimited
+	case http.StatusUnauthorized, http.StatusForbidden:
+		kind = ErrUpstreamBadKey
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		kind = ErrUpstreamTimeout
+	default:
+		if resp.StatusCode < 500 {
+			return nil
+		}
+		kind = ErrUpstreamUnavailable
+	}
+	return &UpstreamError{
+		Provider:   provider,
+		Kind:       kind,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Err:        err,
+	}
+}
+
+// parseRetryAfter interpreta o header Retry-After (segundos ou HTTP-date).
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now).Round(time.Second)
+	}
+	return 0
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUpstreamErrorClassification(t *testing.T) {
+	newResponse := func(status int, body string, header http.Header) *http.Response {
+		if header == nil {
+			header = make(http.Header)
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewBufferString(body)), Header: header}
+	}
+
+	weatherTests := []struct {
+		name       string
+		resp       *http.Response
+		err        error
+		kind       error
+		retryAfter time.Duration
+	}{
+		{"Location Unknown (1006)", newResponse(http.StatusBadRequest, `{"error": {"code": 1006, "message": "No matching location found."}}`, nil), nil, ErrLocationUnknown, 0},
+		{"Invalid Key (2006)", newResponse(http.StatusUnauthorized, `{"error": {"code": 2006, "message": "API key is invalid."}}`, nil), nil, ErrUpstreamBadKey, 0},
+		{"Quota Exceeded (2007)", newResponse(http.StatusForbidden, `{"error": {"code": 2007, "message": "API key has exceeded calls per month quota."}}`, nil), nil, ErrUpstreamRateLimited, 0},
+		{"Too Many Requests", newResponse(http.StatusTooManyRequests, ``, http.Header{"Retry-After": {"120"}}), nil, ErrUpstreamRateLimited, 2 * time.Minute},
+		{"Service Unavailable", newResponse(http.StatusServiceUnavailable, `oops`, http.Header{"Retry-After": {"5"}}), nil, ErrUpstreamUnavailable, 5 * time.Second},
+		{"Gateway Timeout", newResponse(http.StatusGatewayTimeout, ``, nil), nil, ErrUpstreamTimeout, 0},
+		{"Deadline Exceeded", nil, context.DeadlineExceeded, ErrUpstreamTimeout, 0},
+		{"Connection Refused", nil, errors.New("connection refused"), ErrUpstreamUnavailable, 0},
+	}
+
+	for _, tt := range weatherTests {
+		t.Run("WeatherAPI "+tt.name, func(t *testing.T) {
+			mockTripper := new(MockRoundTripper)
+			weatherService := NewWeatherAPIService("test-api-key", &http.Client{Transport: mockTripper})
+			mockTripper.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(tt.resp, tt.err).Once()
+
+			weather, err := weatherService.GetWeatherByCity(context.Background(), "São Paulo")
+
+			assert.Nil(t, weather)
+			assert.ErrorIs(t, err, tt.kind)
+			assert.ErrorIs(t, err, ErrWeatherAPIFailure)
+			var upErr *UpstreamError
+			if assert.ErrorAs(t, err, &upErr) {
+				assert.Equal(t, ProviderWeatherAPI, upErr.Provider)
+				assert.Equal(t, tt.retryAfter, upErr.RetryAfter)
+			}
+		})
+	}
+
+	t.Run("WeatherAPI Missing Key", func(t *testing.T) {
+		_, err := NewWeatherAPIService("", nil).GetWeatherByCity(context.Background(), "São Paulo")
+		assert.ErrorIs(t, err, ErrUpstreamBadKey)
+	})
+
+	t.Run("WeatherAPI Unclassified Bad Request", func(t *testing.T) {
+		mockTripper := new(MockRoundTripper)
+		weatherService := NewWeatherAPIService("test-api-key", &http.Client{Transport: mockTripper})
+		mockTripper.On("RoundTrip", mock.AnythingOfType("*http.Request")).
+			Return(newResponse(http.StatusBadRequest, `{"error": {"code": 9999, "message": "Internal application error."}}`, nil), nil).Once()
+
+		_, err := weatherService.GetWeatherByCity(context.Background(), "São Paulo")
+
+		var upErr *UpstreamError
+		assert.False(t, errors.As(err, &upErr))
+		assert.ErrorIs(t, err, ErrWeatherAPIFailure)
+	})
+
+	t.Run("ViaCEP Unavailable", func(t *testing.T) {
+		mockTripper := new(MockRoundTripper)
+		viaCEPService := NewViaCEPService(&http.Client{Transport: mockTripper})
+		mockTripper.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(newResponse(http.StatusBadGateway, `bad gateway`, nil), nil).Once()
+
+		_, err := viaCEPService.GetLocationByCEP(context.Background(), "01001000")
+
+		assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+		assert.Contains(t, err.Error(), "ViaCEP request failed with status 502")
+	})
+
+	t.Run("ViaCEP Timeout", func(t *testing.T) {
+		mockTripper := new(MockRoundTripper)
+		viaCEPService := NewViaCEPService(&http.Client{Transport: mockTripper})
+		mockTripper.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(nil, context.DeadlineExceeded).Once()
+
+		_, err := viaCEPService.GetLocationByCEP(context.Background(), "01001000")
+
+		assert.ErrorIs(t, err, ErrUpstreamTimeout)
+	})
+
+	t.Run("Caller Cancellation Is Not Classified", func(t *testing.T) {
+		err := classifyTransportError(context.Background(), ProviderViaCEP, context.Canceled)
+		assert.Equal(t, context.Canceled, err)
+	})
+
+	t.Run("Caller Deadline Is Not Classified", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		mockTripper := new(MockRoundTripper)
+		viaCEPService := NewViaCEPService(&http.Client{Transport: mockTripper})
+		mockTripper.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(nil, context.DeadlineExceeded).Once()
+
+		_, err := viaCEPService.GetLocationByCEP(ctx, "01001000")
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.NotErrorIs(t, err, ErrUpstreamTimeout)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter("", now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+	assert.Zero(t, parseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+}
@@ -1,19 +1,23 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"regexp"
+	"time"
 
 	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
 )
 
 // LocationFinder define a interface para buscar localização por CEP.
 type LocationFinder interface {
-	GetLocationByCEP(cep string) (string, error)
+	GetLocationByCEP(ctx context.Context, cep string) (string, error)
 }
 
 // ViaCEPService implementa LocationFinder usando a API ViaCEP.
@@ -34,24 +38,36 @@ var (
 	ErrCEPNotFound      = errors.New("can not find zipcode")
 )
 
+var cepPattern = regexp.MustCompile(`^\d{8}$`)
+
+// ValidateCEP retorna ErrInvalidCEPFormat se cep não tiver 8 dígitos numéricos.
+func ValidateCEP(cep string) error {
+	if !cepPattern.MatchString(cep) {
+		return ErrInvalidCEPFormat
+	}
+	return nil
+}
+
 // GetLocationByCEP busca a cidade correspondente a um CEP usando a API ViaCEP.
-func (s *ViaCEPService) GetLocationByCEP(cep string) (string, error) {
+func (s *ViaCEPService) GetLocationByCEP(ctx context.Context, cep string) (string, error) {
 	// 1. Validar formato do CEP (8 dígitos numéricos)
-	match, _ := regexp.MatchString(`^\d{8}$`, cep)
-	if !match {
-		return "", ErrInvalidCEPFormat
+	if err := ValidateCEP(cep); err != nil {
+		return "", err
 	}
 
 	// 2. Montar URL e fazer requisição
 	url := fmt.Sprintf("https://viacep.com.br/ws/%s/json/", cep)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create ViaCEP request: %w", err)
 	}
 
+	logging.Add(ctx, slog.String("provider", ProviderViaCEP))
+	start := time.Now()
 	resp, err := s.Client.Do(req)
+	logging.Add(ctx, logging.Latency(ProviderViaCEP, time.Since(start)))
 	if err != nil {
-		return "", fmt.Errorf("failed to execute ViaCEP request: %w", err)
+		return "", classifyTransportError(ctx, ProviderViaCEP, fmt.Errorf("failed to execute ViaCEP request: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -62,7 +78,11 @@ func (s *ViaCEPService) GetLocationByCEP(cep string) (string, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ViaCEP request failed with status %d: %s", resp.StatusCode, string(body))
+		err := fmt.Errorf("ViaCEP request failed with status %d: %s", resp.StatusCode, string(body))
+		if upErr := classifyStatus(ProviderViaCEP, resp, err); upErr != nil {
+			return "", upErr
+		}
+		return "", err
 	}
 
 	var viaCEPResp entity.ViaCEPResponse
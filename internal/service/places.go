@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidState       = errors.New("invalid state")
+	ErrInvalidCoordinates = errors.New("invalid coordinates")
+	ErrInvalidCityName    = errors.New("invalid city name")
+)
+
+// maxCityNameLength limita o nome aceito em /weather/city/{name}.
+const maxCityNameLength = 100
+
+// brazilianStates associa cada UF ao nome do estado, usado para desambiguar
+// cidades homônimas na consulta à WeatherAPI.
+var brazilianStates = map[string]string{
+	"AC": "Acre", "AL": "Alagoas", "AP": "Amapá", "AM": "Amazonas", "BA": "Bahia",
+	"CE": "Ceará", "DF": "Distrito Federal", "ES": "Espírito Santo", "GO": "Goiás",
+	"MA": "Maranhão", "MT": "Mato Grosso", "MS": "Mato Grosso do Sul", "MG": "Minas Gerais",
+	"PA": "Pará", "PB": "Paraíba", "PR": "Paraná", "PE": "Pernambuco", "PI": "Piauí",
+	"RJ": "Rio de Janeiro", "RN": "Rio Grande do Norte", "RS": "Rio Grande do Sul",
+	"RO": "Rondônia", "RR": "Roraima", "SC": "Santa Catarina", "SP": "São Paulo",
+	"SE": "Sergipe", "TO": "Tocantins",
+}
+
+// StateName retorna o nome do estado da UF informada (maiúsculas ou minúsculas).
+func StateName(uf string) (string, bool) {
+	name, ok := brazilianStates[strings.ToUpper(uf)]
+	return name, ok
+}
+
+// CityQuery monta a consulta da WeatherAPI para uma cidade brasileira, com o
+// estado quando a UF é informada (ex: "Campinas, São Paulo, Brazil").
+func CityQuery(name, uf string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxCityNameLength || strings.ContainsAny(name, ",;\r\n") {
+		return "", fmt.Errorf("%w %q", ErrInvalidCityName, name)
+	}
+	if uf == "" {
+		return name + ", Brazil", nil
+	}
+	state, ok := StateName(uf)
+	if !ok {
+		return "", fmt.Errorf("%w %q: expected a Brazilian UF such as SP or RJ", ErrInvalidState, uf)
+	}
+	return name + ", " + state + ", Brazil", nil
+}
+
+// CoordinatesQuery valida latitude e longitude em graus decimais e monta a
+// consulta "lat,lon" da WeatherAPI.
+func CoordinatesQuery(lat, lon string) (string, error) {
+	latitude, errLat := strconv.ParseFloat(lat, 64)
+	longitude, errLon := strconv.ParseFloat(lon, 64)
+	if errLat != nil || errLon != nil || math.IsNaN(latitude) || math.IsNaN(longitude) ||
+		latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return "", fmt.Errorf("%w: lat must be in [-90, 90] and lon in [-180, 180], got lat=%q lon=%q", ErrInvalidCoordinates, lat, lon)
+	}
+	return strconv.FormatFloat(latitude, 'f', -1, 64) + "," + strconv.FormatFloat(longitude, 'f', -1, 64), nil
+}
+
+// CountryCode converte o nome de país da WeatherAPI no código ISO usado nas
+// respostas. Países sem código conhecido mantêm o nome.
+func CountryCode(name string) string {
+	switch name {
+	case "Brazil", "Brasil", "":
+		return "BR"
+	default:
+		return name
+	}
+}
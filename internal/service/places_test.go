@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaceQueries(t *testing.T) {
+	q, err := CityQuery(" São Paulo ", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "São Paulo, Brazil", q)
+	q, err = CityQuery("Campinas", "sp")
+	assert.NoError(t, err)
+	assert.Equal(t, "Campinas, São Paulo, Brazil", q)
+	_, err = CityQuery("Campinas", "XX")
+	assert.ErrorIs(t, err, ErrInvalidState)
+	_, err = CityQuery("Campinas, SP", "")
+	assert.ErrorIs(t, err, ErrInvalidCityName)
+
+	q, err = CoordinatesQuery("-23.550", "-46.633")
+	assert.NoError(t, err)
+	assert.Equal(t, "-23.55,-46.633", q)
+	for _, c := range [][2]string{{"", "0"}, {"91", "0"}, {"0", "-180.5"}, {"NaN", "0"}, {"1e3", "0"}} {
+		_, err := CoordinatesQuery(c[0], c[1])
+		assert.ErrorIs(t, err, ErrInvalidCoordinates, c)
+	}
+
+	assert.Equal(t, "BR", CountryCode("Brazil"))
+	assert.Equal(t, "Portugal", CountryCode("Portugal"))
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+)
+
+// Padrões do poller, usados quando a configuração não informa valores.
+const (
+	DefaultPollInterval   = 30 * time.Second
+	DefaultMaxSubscribers = 1000
+)
+
+// ErrTooManySubscribers indica que o limite de assinantes do poller foi atingido.
+var ErrTooManySubscribers = errors.New("too many stream subscribers")
+
+// Reading é uma leitura publicada pelo poller para os assinantes de uma cidade.
+// ID cresce a cada mudança da observação e serve como ID do evento (Last-Event-ID).
+type Reading struct {
+	ID      int64
+	City    string
+	Weather *entity.CurrentWeather
+	At      time.Time // Momento em que a mudança foi detectada
+	Err     error     // Falha da última consulta; Weather fica com a última leitura válida
+}
+
+// WeatherPoller consulta o clima de cada cidade periodicamente, uma única vez
+// por cidade, e publica as mudanças para todos os seus assinantes. A consulta
+// de uma cidade começa com o primeiro assinante e para com o último.
+type WeatherPoller struct {
+	Finder         WeatherFinder
+	Interval       time.Duration
+	MaxSubscribers int
+	Logger         *slog.Logger
+
+	mu          sync.Mutex
+	feeds       map[string]*cityFeed
+	subscribers int
+	now         func() time.Time
+	done        chan struct{}
+	closed      bool
+}
+
+// cityFeed é o estado de uma cidade com ao menos um assinante.
+type cityFeed struct {
+	subs   map[*Subscription]struct{}
+	last   *Reading // Última leitura válida
+	err    error    // Falha da última consulta; nil quando ela deu certo
+	cancel context.CancelFunc
+}
+
+// Subscription recebe as leituras de uma cidade. C guarda apenas a leitura mais
+// recente: um assinante lento perde as intermediárias, nunca a última.
+type Subscription struct {
+	C    <-chan Reading
+	Done <-chan struct{} // Fechado quando o poller é encerrado
+
+	c      chan Reading
+	city   string
+	poller *WeatherPoller
+	once   sync.Once
+}
+
+// NewWeatherPoller cria uma nova instância de WeatherPoller.
+func NewWeatherPoller(finder WeatherFinder, interval time.Duration, maxSubscribers int) *WeatherPoller {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	if maxSubscribers <= 0 {
+		maxSubscribers = DefaultMaxSubscribers
+	}
+	return &WeatherPoller{
+		Finder:         finder,
+		Interval:       interval,
+		MaxSubscribers: maxSubscribers,
+		Logger:         slog.Default(),
+		feeds:          map[string]*cityFeed{},
+		now:            time.Now,
+		done:           make(chan struct{}),
+	}
+}
+
+// Subscribe assina as leituras de city. Se a cidade já tem leitura, ela é
+// entregue imediatamente, com o Err da última consulta se ela falhou, para que
+// o assinante saiba que a leitura está desatualizada. Retorna ErrTooManySubscribers acima do limite. Com o
+// poller encerrado, a assinatura já nasce com Done fechado.
+func (p *WeatherPoller) Subscribe(city string) (*Subscription, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.subscribers >= p.MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+	c := make(chan Reading, 1)
+	sub := &Subscription{C: c, Done: p.done, c: c, city: city, poller: p}
+	if p.closed {
+		return sub, nil
+	}
+
+	feed, ok := p.feeds[city]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		feed = &cityFeed{subs: map[*Subscription]struct{}{}, cancel: cancel}
+		p.feeds[city] = feed
+		go p.poll(ctx, city)
+	}
+	feed.subs[sub] = struct{}{}
+	p.subscribers++
+	if reading, ok := feed.current(city, p.now()); ok {
+		sub.deliver(reading)
+	}
+	return sub, nil
+}
+
+// Close cancela a assinatura. Pode ser chamado mais de uma vez.
+func (s *Subscription) Close() {
+	s.once.Do(func() { s.poller.unsubscribe(s) })
+}
+
+// Close para todas as consultas e fecha o Done das assinaturas, para que os
+// streams terminem no desligamento do servidor. Pode ser chamado mais de uma vez.
+func (p *WeatherPoller) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.done)
+	for city, feed := range p.feeds {
+		feed.cancel()
+		delete(p.feeds, city)
+	}
+	p.subscribers = 0
+}
+
+// Subscribers retorna o total de assinantes ativos.
+func (p *WeatherPoller) Subscribers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.subscribers
+}
+
+func (p *WeatherPoller) unsubscribe(sub *Subscription) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	feed := p.feeds[sub.city]
+	if feed == nil {
+		return
+	}
+	if _, ok := feed.subs[sub]; !ok {
+		return
+	}
+	delete(feed.subs, sub)
+	p.subscribers--
+	if len(feed.subs) == 0 {
+		feed.cancel()
+		delete(p.feeds, sub.city)
+	}
+}
+
+// poll consulta a cidade a cada Interval até o contexto ser cancelado.
+func (p *WeatherPoller) poll(ctx context.Context, city string) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, p.Interval)
+		weather, err := p.Finder.GetWeatherByCity(fetchCtx, city)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			p.Logger.Warn("error polling weather", "city", city, "error", err)
+		}
+		p.publish(ctx, city, weather, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publish entrega a leitura quando a observação mudou ou a consulta falhou.
+func (p *WeatherPoller) publish(ctx context.Context, city string, weather *entity.CurrentWeather, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	feed := p.feeds[city]
+	if feed == nil || ctx.Err() != nil {
+		return // A cidade perdeu os assinantes durante a consulta
+	}
+
+	var reading Reading
+	switch {
+	case err != nil:
+		feed.err = err
+		reading, _ = feed.current(city, p.now())
+	case feed.last != nil && *feed.last.Weather == *weather:
+		if feed.err == nil {
+			return // Observação inalterada
+		}
+		// Recuperação sem mudança: reenvia a última leitura (mesmo ID) para limpar o erro
+		feed.err = nil
+		reading = *feed.last
+	default:
+		feed.err = nil
+		id := p.now().UnixMilli()
+		if feed.last != nil && id <= feed.last.ID {
+			id = feed.last.ID + 1
+		}
+		reading = Reading{ID: id, City: city, Weather: weather, At: p.now()}
+		feed.last = &reading
+	}
+
+	for sub := range feed.subs {
+		sub.deliver(reading)
+	}
+}
+
+// current retorna o estado da cidade como uma leitura: a última válida, com o
+// erro da última consulta se ela falhou, ou só o erro se nenhuma deu certo.
+// ok é false antes da primeira consulta.
+func (f *cityFeed) current(city string, now time.Time) (Reading, bool) {
+	switch {
+	case f.last != nil:
+		reading := *f.last
+		reading.Err = f.err
+		return reading, true
+	case f.err != nil:
+		return Reading{City: city, At: now, Err: f.err}, true
+	default:
+		return Reading{}, false
+	}
+}
+
+// deliver substitui a leitura pendente, se houver, pela nova.
+func (s *Subscription) deliver(r Reading) {
+	select {
+	case <-s.c:
+	default:
+	}
+	s.c <- r
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// receive espera a próxima leitura da assinatura.
+func receive(t *testing.T, sub *Subscription) Reading {
+	t.Helper()
+	select {
+	case r := <-sub.C:
+		return r
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for reading")
+		return Reading{}
+	}
+}
+
+func TestWeatherPoller(t *testing.T) {
+	sp := &entity.CurrentWeather{TempC: 25, Humidity: 60, WindKph: 10}
+	warmer := &entity.CurrentWeather{TempC: 26, Humidity: 58, WindKph: 10}
+
+	t.Run("Shares One Poll Per City", func(t *testing.T) {
+		mockWeather := new(mocks.WeatherFinder)
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(sp, nil)
+		poller := NewWeatherPoller(mockWeather, time.Hour, 10)
+
+		first, err := poller.Subscribe("São Paulo")
+		assert.NoError(t, err)
+		second, err := poller.Subscribe("São Paulo")
+		assert.NoError(t, err)
+		r1, r2 := receive(t, first), receive(t, second)
+		assert.Equal(t, sp, r1.Weather)
+		assert.Equal(t, r1.ID, r2.ID)
+
+		// Quem chega depois recebe a última leitura sem nova consulta
+		late, err := poller.Subscribe("São Paulo")
+		assert.NoError(t, err)
+		assert.Equal(t, r1.ID, receive(t, late).ID)
+		mockWeather.AssertNumberOfCalls(t, "GetWeatherByCity", 1)
+		assert.Equal(t, 3, poller.Subscribers())
+
+		first.Close()
+		first.Close() // Idempotente
+		second.Close()
+		late.Close()
+		assert.Zero(t, poller.Subscribers())
+		assert.Empty(t, poller.feeds)
+	})
+
+	t.Run("Publishes Only Changes", func(t *testing.T) {
+		mockWeather := new(mocks.WeatherFinder)
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(sp, nil).Twice()
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(warmer, nil)
+		poller := NewWeatherPoller(mockWeather, 10*time.Millisecond, 10)
+
+		sub, err := poller.Subscribe("São Paulo")
+		assert.NoError(t, err)
+		defer sub.Close()
+
+		first := receive(t, sub)
+		second := receive(t, sub)
+		assert.Equal(t, sp, first.Weather)
+		assert.Equal(t, warmer, second.Weather)
+		assert.Greater(t, second.ID, first.ID)
+	})
+
+	t.Run("Reports Errors And Recovery", func(t *testing.T) {
+		failure := &UpstreamError{Provider: ProviderWeatherAPI, Kind: ErrUpstreamUnavailable, Err: errors.New("down")}
+		mockWeather := new(mocks.WeatherFinder)
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(sp, nil).Once()
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(nil, failure).Once()
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(sp, nil)
+		poller := NewWeatherPoller(mockWeather, 10*time.Millisecond, 10)
+
+		sub, err := poller.Subscribe("São Paulo")
+		assert.NoError(t, err)
+		defer sub.Close()
+
+		ok := receive(t, sub)
+		failed := receive(t, sub)
+		recovered := receive(t, sub)
+		assert.NoError(t, ok.Err)
+		assert.ErrorIs(t, failed.Err, ErrUpstreamUnavailable)
+		assert.Equal(t, sp, failed.Weather, "keeps the last good reading")
+		assert.NoError(t, recovered.Err)
+		assert.Equal(t, ok.ID, recovered.ID, "unchanged observation keeps its ID")
+	})
+
+	t.Run("Late Subscriber Sees The Failure", func(t *testing.T) {
+		failure := &UpstreamError{Provider: ProviderWeatherAPI, Kind: ErrUpstreamUnavailable, Err: errors.New("down")}
+		mockWeather := new(mocks.WeatherFinder)
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(sp, nil)
+		poller := NewWeatherPoller(mockWeather, time.Hour, 10)
+
+		first, err := poller.Subscribe("São Paulo")
+		assert.NoError(t, err)
+		defer first.Close()
+		ok := receive(t, first)
+		poller.publish(context.Background(), "São Paulo", nil, failure)
+		receive(t, first)
+
+		// Quem chega durante a falha recebe a última leitura junto com o erro
+		late, err := poller.Subscribe("São Paulo")
+		assert.NoError(t, err)
+		defer late.Close()
+		stale := receive(t, late)
+		assert.ErrorIs(t, stale.Err, ErrUpstreamUnavailable)
+		assert.Equal(t, sp, stale.Weather)
+		assert.Equal(t, ok.ID, stale.ID)
+
+		poller.publish(context.Background(), "São Paulo", sp, nil)
+		assert.NoError(t, receive(t, late).Err)
+	})
+
+	t.Run("Subscriber Limit", func(t *testing.T) {
+		mockWeather := new(mocks.WeatherFinder)
+		mockWeather.On("GetWeatherByCity", mock.Anything, mock.Anything).Return(sp, nil)
+		poller := NewWeatherPoller(mockWeather, time.Hour, 1)
+
+		sub, err := poller.Subscribe("São Paulo")
+		assert.NoError(t, err)
+		_, err = poller.Subscribe("Rio de Janeiro")
+		assert.ErrorIs(t, err, ErrTooManySubscribers)
+
+		sub.Close()
+		other, err := poller.Subscribe("Rio de Janeiro")
+		assert.NoError(t, err)
+		other.Close()
+	})
+
+	t.Run("Close Ends Subscriptions", func(t *testing.T) {
+		mockWeather := new(mocks.WeatherFinder)
+		mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(sp, nil)
+		poller := NewWeatherPoller(mockWeather, time.Hour, 10)
+
+		sub, err := poller.Subscribe("São Paulo")
+		assert.NoError(t, err)
+		receive(t, sub)
+
+		poller.Close()
+		poller.Close() // Idempotente
+		<-sub.Done
+		sub.Close()
+		assert.Zero(t, poller.Subscribers())
+		assert.Empty(t, poller.feeds)
+
+		late, err := poller.Subscribe("São Paulo")
+		assert.NoError(t, err)
+		<-late.Done
+		assert.Empty(t, poller.feeds, "no poll after close")
+	})
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnonymousTier é o nome do tier aplicado aos clientes sem chave de API,
+// identificados pelo IP.
+const AnonymousTier = "anonymous"
+
+var ErrInvalidRateLimitTier = errors.New("invalid rate limit tier")
+
+// RateLimitTier é um plano de limite: Limit requisições por Window, com
+// rajadas de até Burst requisições.
+type RateLimitTier struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+	Burst  int // Capacidade do balde; zero usa Limit
+}
+
+// Capacity é o número máximo de tokens acumulados no balde.
+func (t RateLimitTier) Capacity() int {
+	if t.Burst > 0 {
+		return t.Burst
+	}
+	return t.Limit
+}
+
+// interval é o tempo para repor um token.
+func (t RateLimitTier) interval() time.Duration {
+	return t.Window / time.Duration(t.Limit)
+}
+
+// ParseRateLimitTier interpreta um tier no formato "nome=limite/janela[:rajada]"
+// (ex: "anonymous=60/1m:20").
+func ParseRateLimitTier(spec string) (RateLimitTier, error) {
+	name, rest, ok := strings.Cut(strings.TrimSpace(spec), "=")
+	limit, rest, okRate := strings.Cut(rest, "/")
+	window, burst, hasBurst := strings.Cut(rest, ":")
+	if !ok || !okRate || name == "" {
+		return RateLimitTier{}, fmt.Errorf("%w %q: expected name=limit/window[:burst]", ErrInvalidRateLimitTier, spec)
+	}
+	tier := RateLimitTier{Name: name}
+	var err error
+	if tier.Limit, err = strconv.Atoi(limit); err != nil || tier.Limit <= 0 {
+		return RateLimitTier{}, fmt.Errorf("%w %q: limit must be a positive integer", ErrInvalidRateLimitTier, spec)
+	}
+	if tier.Window, err = time.ParseDuration(window); err != nil || tier.Window <= 0 {
+		return RateLimitTier{}, fmt.Errorf("%w %q: window must be a positive duration such as 1m", ErrInvalidRateLimitTier, spec)
+	}
+	if hasBurst {
+		if tier.Burst, err = strconv.Atoi(burst); err != nil || tier.Burst <= 0 {
+			return RateLimitTier{}, fmt.Errorf("%w %q: burst must be a positive integer", ErrInvalidRateLimitTier, spec)
+		}
+	}
+	return tier, nil
+}
+
+// RateLimitResult é o estado do balde depois de uma tentativa de consumo.
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int           // Tokens inteiros restantes
+	Reset      time.Duration // Tempo até o balde voltar a ficar cheio
+	RetryAfter time.Duration // Tempo até o próximo token, quando Allowed é false
+}
+
+// RateLimitStore guarda os baldes de tokens. A implementação em memória serve a
+// uma instância; várias instâncias precisam de um store compartilhado.
+type RateLimitStore interface {
+	// Take consome um token do balde key, criado cheio com a capacidade do tier.
+	Take(ctx context.Context, key string, tier RateLimitTier) (RateLimitResult, error)
+}
+
+// rateLimitSweepInterval é o intervalo entre as limpezas dos baldes ociosos.
+const rateLimitSweepInterval = time.Minute
+
+// MemoryRateLimitStore implementa RateLimitStore em memória. Baldes que voltaram
+// a ficar cheios são descartados periodicamente, pois equivalem a um balde novo.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	at     time.Time // Momento em que tokens foi calculado
+	full   time.Time // Momento em que o balde volta a ficar cheio
+}
+
+// NewMemoryRateLimitStore cria um store em memória vazio.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+// Take implementa RateLimitStore.
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, tier RateLimitTier) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	capacity := float64(tier.Capacity())
+	interval := tier.interval()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: capacity, at: now}
+		s.buckets[key] = b
+	}
+	// Repõe os tokens acumulados desde a última requisição
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.at))/float64(interval))
+	b.at = now
+
+	res := RateLimitResult{Allowed: b.tokens >= 1}
+	if res.Allowed {
+		b.tokens--
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = time.Duration((capacity - b.tokens) * float64(interval))
+	b.full = now.Add(res.Reset)
+	return res, nil
+}
+
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimitTier(t *testing.T) {
+	tier, err := ParseRateLimitTier("anonymous=60/1m:20")
+	assert.NoError(t, err)
+	assert.Equal(t, RateLimitTier{Name: "anonymous", Limit: 60, Window: time.Minute, Burst: 20}, tier)
+	assert.Equal(t, 20, tier.Capacity())
+
+	tier, err = ParseRateLimitTier("partner=1000/1h")
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, tier.Capacity(), "burst defaults to the limit")
+
+	for _, spec := range []string{"", "anonymous", "anonymous=60", "=60/1m", "a=0/1m", "a=60/soon", "a=60/1m:-1"} {
+		_, err := ParseRateLimitTier(spec)
+		assert.ErrorIs(t, err, ErrInvalidRateLimitTier, spec)
+	}
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+	tier := RateLimitTier{Name: "test", Limit: 60, Window: time.Minute, Burst: 3}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		res, err := store.Take(ctx, "ip:192.0.2.1", tier)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+	res, _ := store.Take(ctx, "ip:192.0.2.1", tier)
+	assert.False(t, res.Allowed, "burst exhausted")
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	other, _ := store.Take(ctx, "ip:192.0.2.2", tier)
+	assert.True(t, other.Allowed, "buckets are per key")
+
+	now = now.Add(1500 * time.Millisecond)
+	res, _ = store.Take(ctx, "ip:192.0.2.1", tier)
+	assert.True(t, res.Allowed, "one token refilled")
+	assert.Equal(t, 0, res.Remaining)
+
+	now = now.Add(time.Hour)
+	res, _ = store.Take(ctx, "ip:192.0.2.1", tier)
+	assert.Equal(t, 2, res.Remaining, "refill is capped at the burst")
+	assert.Len(t, store.buckets, 1, "idle full buckets are swept")
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+)
+
+// RoundingMode define a regra de desempate usada ao arredondar temperaturas.
+type RoundingMode string
+
+const (
+	// RoundHalfEven arredonda empates para o dígito par mais próximo (arredondamento bancário).
+	RoundHalfEven RoundingMode = "half-even"
+	// RoundHalfUp arredonda empates para longe do zero (2.25 -> 2.3, -2.25 -> -2.3).
+	RoundHalfUp RoundingMode = "half-up"
+)
+
+// MaxPrecision é o maior número de casas decimais aceito em uma política de arredondamento.
+const MaxPrecision = 6
+
+var (
+	ErrInvalidPrecision    = errors.New("invalid precision")
+	ErrInvalidRoundingMode = errors.New("invalid rounding mode")
+)
+
+// RoundingPolicy define quantas casas decimais cada unidade recebe e como desempatar.
+// O valor zero (Mode vazio) desativa o arredondamento.
+type RoundingPolicy struct {
+	PrecisionC int
+	PrecisionF int
+	PrecisionK int
+	Mode       RoundingMode
+}
+
+// DefaultRoundingPolicy retorna a política padrão: uma casa para °C e °F, duas para K.
+func DefaultRoundingPolicy() RoundingPolicy {
+	return RoundingPolicy{PrecisionC: 1, PrecisionF: 1, PrecisionK: 2, Mode: RoundHalfEven}
+}
+
+// NewRoundingPolicy valida as precisões e o modo informados e monta uma RoundingPolicy.
+func NewRoundingPolicy(precisionC, precisionF, precisionK int, mode string) (RoundingPolicy, error) {
+	for _, digits := range []int{precisionC, precisionF, precisionK} {
+		if err := validatePrecision(digits); err != nil {
+			return RoundingPolicy{}, err
+		}
+	}
+	m, err := ParseRoundingMode(mode)
+	if err != nil {
+		return RoundingPolicy{}, err
+	}
+	return RoundingPolicy{PrecisionC: precisionC, PrecisionF: precisionF, PrecisionK: precisionK, Mode: m}, nil
+}
+
+// ParseRoundingMode converte o nome de um modo ("half-even", "half-up") em RoundingMode.
+func ParseRoundingMode(mode string) (RoundingMode, error) {
+	switch m := RoundingMode(strings.ToLower(strings.TrimSpace(mode))); m {
+	case RoundHalfEven, RoundHalfUp:
+		return m, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidRoundingMode, mode)
+	}
+}
+
+// ParsePrecision converte o parâmetro ?precision= em número de casas decimais.
+func ParsePrecision(raw string) (int, error) {
+	digits, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidPrecision, raw)
+	}
+	if err := validatePrecision(digits); err != nil {
+		return 0, err
+	}
+	return digits, nil
+}
+
+func validatePrecision(digits int) error {
+	if digits < 0 || digits > MaxPrecision {
+		return fmt.Errorf("%w: %d (must be between 0 and %d)", ErrInvalidPrecision, digits, MaxPrecision)
+	}
+	return nil
+}
+
+// WithPrecision retorna uma cópia da política usando a mesma precisão para todas as unidades.
+func (p RoundingPolicy) WithPrecision(digits int) RoundingPolicy {
+	if p.Mode == "" {
+		p.Mode = RoundHalfEven
+	}
+	p.PrecisionC, p.PrecisionF, p.PrecisionK = digits, digits, digits
+	return p
+}
+
+// Apply arredonda as temperaturas de out conforme a política.
+func (p RoundingPolicy) Apply(out *entity.WeatherOutput) {
+	if out == nil || p.Mode == "" {
+		return
+	}
+	out.TempC = Round(out.TempC, p.PrecisionC, p.Mode)
+	out.TempF = Round(out.TempF, p.PrecisionF, p.Mode)
+	out.TempK = Round(out.TempK, p.PrecisionK, p.Mode)
+}
+
+// ApplyTemperature arredonda as três escalas de t conforme a política.
+func (p RoundingPolicy) ApplyTemperature(t *entity.Temperature) {
+	if t == nil || p.Mode == "" {
+		return
+	}
+	t.C = Round(t.C, p.PrecisionC, p.Mode)
+	t.F = Round(t.F, p.PrecisionF, p.Mode)
+	t.K = Round(t.K, p.PrecisionK, p.Mode)
+}
+
+// Round arredonda value para digits casas decimais usando o modo informado.
+//
+// O arredondamento é feito sobre a menor representação decimal do float64
+// (a mesma que o encoding/json emite), de modo que 2.675 com duas casas e
+// half-up resulta em 2.68, e não em 2.67 como aconteceria com math.Round
+// sobre o binário. O resultado é o float64 mais próximo do decimal
+// arredondado, o que garante uma serialização JSON curta e estável.
+func Round(value float64, digits int, mode RoundingMode) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return value
+	}
+
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(value, 'g', -1, 64))
+	if !ok {
+		return value
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	r.Mul(r, new(big.Rat).SetInt(scale))
+
+	// q é a parte inteira (truncada em direção ao zero) e rem o que sobrou.
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+
+	cmp := twice.Cmp(r.Denom())
+	roundAway := cmp > 0
+	if cmp == 0 {
+		switch mode {
+		case RoundHalfUp:
+			roundAway = true
+		default: // RoundHalfEven
+			roundAway = new(big.Int).Abs(q).Bit(0) == 1
+		}
+	}
+	if roundAway {
+		if r.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+
+	result, _ := new(big.Rat).SetFrac(q, scale).Float64()
+	return result
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		digits   int
+		mode     RoundingMode
+		expected float64
+	}{
+		{"Half Even Tie Down", 2.25, 1, RoundHalfEven, 2.2},
+		{"Half Even Tie Up", 2.35, 1, RoundHalfEven, 2.4},
+		{"Half Up Tie", 2.25, 1, RoundHalfUp, 2.3},
+		{"Half Up Negative Tie", -2.25, 1, RoundHalfUp, -2.3},
+		{"Half Even Negative Tie", -2.25, 1, RoundHalfEven, -2.2},
+		{"Decimal Tie Not Binary", 2.675, 2, RoundHalfUp, 2.68},
+		{"Float Noise", 59.900000000000006, 2, RoundHalfEven, 59.9},
+		{"Zero Digits", 298.5, 0, RoundHalfEven, 298},
+		{"Not A Tie", 298.150001, 2, RoundHalfEven, 298.15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Round(tt.value, tt.digits, tt.mode))
+		})
+	}
+}
+
+func TestNewRoundingPolicy(t *testing.T) {
+	policy, err := NewRoundingPolicy(1, 1, 2, "HALF-UP")
+	assert.NoError(t, err)
+	assert.Equal(t, RoundHalfUp, policy.Mode)
+
+	_, err = NewRoundingPolicy(1, 1, MaxPrecision+1, "half-even")
+	assert.ErrorIs(t, err, ErrInvalidPrecision)
+
+	_, err = NewRoundingPolicy(1, 1, 2, "ceiling")
+	assert.ErrorIs(t, err, ErrInvalidRoundingMode)
+
+	_, err = ParsePrecision("abc")
+	assert.ErrorIs(t, err, ErrInvalidPrecision)
+}
@@ -2,13 +2,16 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"net/http"
 	"testing"
+	"time"
 
 	// Ajuste o import path se necessário
 	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -48,11 +51,20 @@ func TestViaCEPService_GetLocationByCEP(t *testing.T) {
 		}
 		mockTripper.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(mockResponse, nil).Once()
 
-		city, err := viaCEPService.GetLocationByCEP(cep)
+		ctx := logging.WithFields(context.Background())
+		city, err := viaCEPService.GetLocationByCEP(ctx, cep)
 
 		assert.NoError(t, err)
 		assert.Equal(t, expectedCity, city)
 		mockTripper.AssertExpectations(t)
+
+		// A latência e o provedor ficam nos campos de log da requisição
+		keys := map[string]bool{}
+		for _, a := range logging.Attrs(ctx) {
+			keys[a.Key] = true
+		}
+		assert.True(t, keys["viacep_ms"])
+		assert.True(t, keys["provider"])
 	})
 
 	t.Run("Invalid CEP Format", func(t *testing.T) {
@@ -63,7 +75,7 @@ func TestViaCEPService_GetLocationByCEP(t *testing.T) {
 		// --- Fim da criação ---
 
 		cep := "12345" // Formato inválido
-		city, err := viaCEPService.GetLocationByCEP(cep)
+		city, err := viaCEPService.GetLocationByCEP(context.Background(), cep)
 
 		assert.ErrorIs(t, err, ErrInvalidCEPFormat)
 		assert.Empty(t, city)
@@ -86,7 +98,7 @@ func TestViaCEPService_GetLocationByCEP(t *testing.T) {
 		}
 		mockTripper.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(mockResponse, nil).Once()
 
-		city, err := viaCEPService.GetLocationByCEP(cep)
+		city, err := viaCEPService.GetLocationByCEP(context.Background(), cep)
 
 		assert.ErrorIs(t, err, ErrCEPNotFound)
 		assert.Empty(t, city)
@@ -103,7 +115,7 @@ func TestViaCEPService_GetLocationByCEP(t *testing.T) {
 		cep := "01001000"
 		mockTripper.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(nil, errors.New("network error")).Once()
 
-		city, err := viaCEPService.GetLocationByCEP(cep)
+		city, err := viaCEPService.GetLocationByCEP(context.Background(), cep)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to execute ViaCEP request")
@@ -126,7 +138,7 @@ func TestViaCEPService_GetLocationByCEP(t *testing.T) {
 		}
 		mockTripper.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(mockResponse, nil).Once()
 
-		city, err := viaCEPService.GetLocationByCEP(cep)
+		city, err := viaCEPService.GetLocationByCEP(context.Background(), cep)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "ViaCEP request failed with status 500")
@@ -150,7 +162,7 @@ func TestWeatherAPIService_GetWeatherByCity(t *testing.T) {
 		expectedTempC := 25.5
 		mockResponse := &http.Response{
 			StatusCode: http.StatusOK,
-			Body:       io.NopCloser(bytes.NewBufferString(`{"current": {"temp_c": 25.5}}`)),
+			Body:       io.NopCloser(bytes.NewBufferString(`{"location": {"name": "Sao Paulo", "region": "Sao Paulo", "country": "Brazil"}, "current": {"last_updated_epoch": 1792324800, "temp_c": 25.5, "humidity": 60, "wind_kph": 11.2}}`)),
 			Header:     make(http.Header),
 		}
 		// Verifica se a URL contém a cidade encodada e a API key
@@ -161,10 +173,15 @@ func TestWeatherAPIService_GetWeatherByCity(t *testing.T) {
 				req.URL.Query().Get("q") == city // QueryEscape é testado implicitamente
 		})).Return(mockResponse, nil).Once()
 
-		tempC, err := weatherService.GetWeatherByCity(city)
+		weather, err := weatherService.GetWeatherByCity(context.Background(), city)
 
 		assert.NoError(t, err)
-		assert.Equal(t, expectedTempC, tempC)
+		assert.Equal(t, expectedTempC, weather.TempC)
+		assert.Equal(t, 60.0, weather.Humidity)
+		assert.Equal(t, 11.2, weather.WindKph)
+		assert.Equal(t, "Sao Paulo", weather.Place)
+		assert.Equal(t, "Brazil", weather.Country)
+		assert.Equal(t, time.Unix(1792324800, 0).UTC(), weather.ObservedAt)
 		mockTripper.AssertExpectations(t)
 	})
 
@@ -178,11 +195,11 @@ func TestWeatherAPIService_GetWeatherByCity(t *testing.T) {
 		city := "London"
 		mockTripper.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(nil, errors.New("network error")).Once()
 
-		tempC, err := weatherService.GetWeatherByCity(city)
+		weather, err := weatherService.GetWeatherByCity(context.Background(), city)
 
 		assert.ErrorIs(t, err, ErrWeatherAPIFailure)
 		assert.Contains(t, err.Error(), "network error")
-		assert.Zero(t, tempC)
+		assert.Nil(t, weather)
 		mockTripper.AssertExpectations(t)
 	})
 
@@ -201,11 +218,11 @@ func TestWeatherAPIService_GetWeatherByCity(t *testing.T) {
 		}
 		mockTripper.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(mockResponse, nil).Once()
 
-		tempC, err := weatherService.GetWeatherByCity(city)
+		weather, err := weatherService.GetWeatherByCity(context.Background(), city)
 
 		assert.ErrorIs(t, err, ErrWeatherAPIFailure)
 		assert.Contains(t, err.Error(), "status 400 - No matching location found.")
-		assert.Zero(t, tempC)
+		assert.Nil(t, weather)
 		mockTripper.AssertExpectations(t)
 	})
 
@@ -220,14 +237,32 @@ func TestWeatherAPIService_GetWeatherByCity(t *testing.T) {
 
 		city := "São Paulo"
 
-		tempC, err := weatherServiceNoKey.GetWeatherByCity(city)
+		weather, err := weatherServiceNoKey.GetWeatherByCity(context.Background(), city)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "WeatherAPI key is missing")
-		assert.Zero(t, tempC)
+		assert.Nil(t, weather)
 		// Verifica que nenhuma chamada HTTP foi feita - AssertNotCalled agora funciona
 		mockTripper.AssertNotCalled(t, "RoundTrip", mock.Anything)
 	})
+
+	t.Run("Rotated API Key", func(t *testing.T) {
+		mockTripper := new(MockRoundTripper)
+		weatherService := NewWeatherAPIService(apiKey, &http.Client{Transport: mockTripper})
+		weatherService.SetAPIKey("rotated-key")
+
+		mockTripper.On("RoundTrip", mock.MatchedBy(func(req *http.Request) bool {
+			return req.URL.Query().Get("key") == "rotated-key"
+		})).Return(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString(`{"current": {"temp_c": 20}}`)),
+			Header:     make(http.Header),
+		}, nil).Once()
+
+		_, err := weatherService.GetWeatherByCity(context.Background(), "São Paulo")
+		assert.NoError(t, err)
+		mockTripper.AssertExpectations(t)
+	})
 }
 
 // TestStandardTemperatureConverter_ConvertTemperatures não usa mocks que precisam ser resetados,
@@ -256,3 +291,60 @@ func TestStandardTemperatureConverter_ConvertTemperatures(t *testing.T) {
 		})
 	}
 }
+
+func TestStandardTemperatureConverter_RoundingPolicy(t *testing.T) {
+	t.Run("Default Policy Removes Float Noise", func(t *testing.T) {
+		converter := NewStandardTemperatureConverter()
+
+		result := converter.ConvertTemperatures(15.5)
+
+		// 15.5*1.8+32 = 59.900000000000006 sem arredondamento
+		assert.Equal(t, 59.9, result.TempF)
+		assert.Equal(t, 288.65, result.TempK)
+	})
+
+	t.Run("Zero Policy Keeps Raw Values", func(t *testing.T) {
+		converter := &StandardTemperatureConverter{}
+
+		result := converter.ConvertTemperatures(15.5)
+
+		assert.Equal(t, 59.900000000000006, result.TempF)
+	})
+
+	t.Run("WithPrecision Overrides All Units", func(t *testing.T) {
+		converter := NewStandardTemperatureConverter().WithPrecision(0)
+
+		result := converter.ConvertTemperatures(15.5)
+
+		assert.Equal(t, 16.0, result.TempC) // half-even: 15.5 -> 16
+		assert.Equal(t, 60.0, result.TempF)
+		assert.Equal(t, 289.0, result.TempK)
+	})
+}
+
+func TestStandardTemperatureConverter_ThermalIndices(t *testing.T) {
+	converter := NewStandardTemperatureConverter()
+
+	t.Run("Hot And Humid", func(t *testing.T) {
+		indices := converter.ThermalIndices(&entity.CurrentWeather{TempC: 32.2, Humidity: 50, WindKph: 10})
+
+		assert.NotNil(t, indices)
+		assert.InDelta(t, 95, indices.HeatIndex.F, 1.0)
+		assert.Equal(t, indices.HeatIndex, indices.ApparentTemperature)
+		assert.Equal(t, 32.2, indices.WindChill.C) // fora da faixa do wind chill
+		assert.InDelta(t, indices.DewPoint.C+273.15, indices.DewPoint.K, 0.06)
+	})
+
+	t.Run("Cold And Windy", func(t *testing.T) {
+		indices := converter.ThermalIndices(&entity.CurrentWeather{TempC: -20, Humidity: 70, WindKph: 30})
+
+		assert.NotNil(t, indices)
+		assert.InDelta(t, -33, indices.WindChill.C, 0.6)
+		assert.Equal(t, indices.WindChill, indices.ApparentTemperature)
+	})
+
+	t.Run("Missing Humidity", func(t *testing.T) {
+		assert.Nil(t, converter.ThermalIndices(&entity.CurrentWeather{TempC: 20}))
+		assert.Nil(t, converter.ThermalIndices(nil))
+	})
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+)
+
+// DefaultSubscriptionCheckInterval é o intervalo padrão entre as verificações das assinaturas.
+const DefaultSubscriptionCheckInterval = 5 * time.Minute
+
+// EventThresholdCrossed é o tipo do aviso enviado quando a condição passa a ser verdadeira.
+const EventThresholdCrossed = "threshold.crossed"
+
+var (
+	ErrInvalidCondition     = errors.New("invalid condition")
+	ErrInvalidCallbackURL   = errors.New("invalid callback URL")
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+)
+
+// SubscriptionStore define a persistência das assinaturas e dos avisos não entregues.
+type SubscriptionStore interface {
+	CreateSubscription(ctx context.Context, sub entity.Subscription) error
+	GetSubscription(ctx context.Context, id string) (*entity.Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]entity.Subscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	// UpdateSubscription substitui a assinatura de mesmo ID.
+	UpdateSubscription(ctx context.Context, sub entity.Subscription) error
+	// UpdateSubscriptionState registra o resultado de uma verificação.
+	UpdateSubscriptionState(ctx context.Context, id string, matching bool, checkedAt time.Time) error
+	AddDeadLetter(ctx context.Context, dl entity.DeadLetter) error
+	ListDeadLetters(ctx context.Context) ([]entity.DeadLetter, error)
+}
+
+// Condition é um limite de temperatura, como "temp_C > 35".
+type Condition struct {
+	Field     string // temp_C, temp_F ou temp_K
+	Operator  string // >, >=, < ou <=
+	Threshold float64
+}
+
+var conditionPattern = regexp.MustCompile(`^\s*(?:(temp_[CFKcfk])\s*)?(>=|<=|>|<)\s*(-?\d+(?:\.\d+)?)\s*$`)
+
+// ParseCondition interpreta uma condição no formato "[campo] operador valor".
+// Sem campo, a condição vale para temp_C (ex: "< 5").
+func ParseCondition(raw string) (Condition, error) {
+	m := conditionPattern.FindStringSubmatch(raw)
+	if m == nil {
+		return Condition{}, fmt.Errorf("%w %q: expected e.g. \"temp_C > 35\" or \"< 5\"", ErrInvalidCondition, raw)
+	}
+	field := "temp_C"
+	if m[1] != "" {
+		field = "temp_" + strings.ToUpper(m[1][len("temp_"):])
+	}
+	threshold, _ := strconv.ParseFloat(m[3], 64)
+	return Condition{Field: field, Operator: m[2], Threshold: threshold}, nil
+}
+
+// String retorna a forma normalizada da condição, usada na persistência.
+func (c Condition) String() string {
+	return fmt.Sprintf("%s %s %s", c.Field, c.Operator, strconv.FormatFloat(c.Threshold, 'f', -1, 64))
+}
+
+// Value extrai de t o valor comparado pela condição.
+func (c Condition) Value(t entity.Temperature) float64 {
+	switch c.Field {
+	case "temp_F":
+		return t.F
+	case "temp_K":
+		return t.K
+	default:
+		return t.C
+	}
+}
+
+// Holds informa se value satisfaz a condição.
+func (c Condition) Holds(value float64) bool {
+	switch c.Operator {
+	case ">":
+		return value > c.Threshold
+	case ">=":
+		return value >= c.Threshold
+	case "<":
+		return value < c.Threshold
+	default:
+		return value <= c.Threshold
+	}
+}
+
+// ValidateCallbackURL exige uma URL absoluta http ou https que aponte para um
+// endereço público: IPs fora das faixas de uso especial (loopback, privados,
+// CGNAT, NAT64, metadados etc.) e nomes que não sejam como localhost. Nomes que
+// resolvem para outros endereços são barrados na conexão, pelo transporte de
+// NewWebhookTransport.
+func ValidateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%w %q: expected an absolute http(s) URL", ErrInvalidCallbackURL, raw)
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !publicAddr(addr) {
+			return fmt.Errorf("%w %q: only public unicast addresses are allowed", ErrInvalidCallbackURL, raw)
+		}
+	} else if host == "localhost" || strings.HasSuffix(host, ".localhost") || host == "metadata.google.internal" {
+		return fmt.Errorf("%w %q: internal host names are not allowed", ErrInvalidCallbackURL, raw)
+	}
+	return nil
+}
+
+// NewSubscriptionID gera um identificador aleatório para assinaturas e avisos.
+func NewSubscriptionID() string {
+	return randomHex(16)
+}
+
+// NewSubscriptionSecret gera o segredo usado para assinar os avisos.
+func NewSubscriptionSecret() string {
+	return "whsec_" + randomHex(32)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SubscriptionScheduler verifica periodicamente as assinaturas e avisa os
+// callbacks cuja condição passou de falsa para verdadeira. Uma assinatura nova
+// começa como falsa, então é avisada já na primeira verificação se a condição valer.
+type SubscriptionScheduler struct {
+	Store     SubscriptionStore
+	Lookup    *BatchLookup
+	Converter TemperatureConverter
+	Notifier  *WebhookNotifier
+	Interval  time.Duration
+	// Concurrency limita as entregas simultâneas; os demais avisos aguardam uma vaga
+	Concurrency int
+	Logger      *slog.Logger
+
+	now func() time.Time
+}
+
+// NewSubscriptionScheduler cria uma nova instância de SubscriptionScheduler.
+func NewSubscriptionScheduler(store SubscriptionStore, lookup *BatchLookup, conv TemperatureConverter, notifier *WebhookNotifier, interval time.Duration) *SubscriptionScheduler {
+	if interval <= 0 {
+		interval = DefaultSubscriptionCheckInterval
+	}
+	return &SubscriptionScheduler{
+		Store: store, Lookup: lookup, Converter: conv, Notifier: notifier, Interval: interval,
+		Concurrency: DefaultWebhookConcurrency, Logger: slog.Default(), now: time.Now,
+	}
+}
+
+// Run executa Check a cada Interval até ctx ser cancelado.
+func (s *SubscriptionScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Check(ctx); err != nil && ctx.Err() == nil {
+			s.Logger.Error("error checking subscriptions", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check avalia todas as assinaturas uma vez e aguarda a entrega dos avisos
+// gerados, no máximo Concurrency de cada vez. CEPs e cidades repetidos são
+// consultados uma única vez.
+func (s *SubscriptionScheduler) Check(ctx context.Context) error {
+	subs, err := s.Store.ListSubscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	ceps := make([]string, len(subs))
+	for i, sub := range subs {
+		ceps[i] = sub.CEP
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, s.Interval)
+	results := s.Lookup.Lookup(lookupCtx, ceps)
+	cancel()
+
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultWebhookConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, sub := range subs {
+		res := results[i]
+		if res.Err != nil {
+			s.Logger.WarnContext(ctx, "error checking subscription", "subscription_id", sub.ID, "cep", sub.CEP, "error", res.Err)
+			continue
+		}
+		cond, err := ParseCondition(sub.Condition)
+		if err != nil {
+			s.Logger.WarnContext(ctx, "skipping subscription", "subscription_id", sub.ID, "error", err)
+			continue
+		}
+
+		output := s.Converter.ConvertTemperatures(res.Weather.TempC)
+		temperature := entity.Temperature{C: output.TempC, F: output.TempF, K: output.TempK}
+		value := cond.Value(temperature)
+		matching := cond.Holds(value)
+		now := s.now()
+		if err := s.Store.UpdateSubscriptionState(ctx, sub.ID, matching, now); err != nil {
+			continue // Removida durante a verificação
+		}
+		if !matching || sub.Matching {
+			continue
+		}
+
+		event := entity.SubscriptionEvent{
+			ID:             NewSubscriptionID(),
+			Type:           EventThresholdCrossed,
+			SubscriptionID: sub.ID,
+			CEP:            sub.CEP,
+			City:           res.City,
+			Condition:      sub.Condition,
+			Value:          value,
+			Temperature:    temperature,
+			OccurredAt:     now,
+		}
+		sem <- struct{}{} // As entregas respeitam ctx, então as vagas se liberam no desligamento
+		wg.Add(1)
+		go func(sub entity.Subscription) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.Notifier.Deliver(ctx, sub, event)
+		}(sub)
+	}
+	wg.Wait()
+	return nil
+}
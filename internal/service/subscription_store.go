@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+)
+
+// MaxDeadLetters limita a lista de avisos não entregues; os mais antigos são descartados.
+const MaxDeadLetters = 1000
+
+// FileSubscriptionStore implementa SubscriptionStore em memória, gravando o
+// estado completo em um arquivo JSON a cada alteração. Sem caminho, nada é gravado.
+type FileSubscriptionStore struct {
+	Path string
+
+	mu          sync.Mutex
+	subs        map[string]entity.Subscription
+	deadLetters []entity.DeadLetter
+}
+
+// subscriptionFile é o conteúdo do arquivo de persistência.
+type subscriptionFile struct {
+	Subscriptions []entity.Subscription `json:"subscriptions"`
+	DeadLetters   []entity.DeadLetter   `json:"dead_letters"`
+}
+
+// NewFileSubscriptionStore cria o store e carrega o arquivo em path, se existir.
+func NewFileSubscriptionStore(path string) (*FileSubscriptionStore, error) {
+	s := &FileSubscriptionStore{Path: path, subs: map[string]entity.Subscription{}}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscriptions file: %w", err)
+	}
+	var f subscriptionFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to decode subscriptions file %s: %w", path, err)
+	}
+	for _, sub := range f.Subscriptions {
+		s.subs[sub.ID] = sub
+	}
+	s.deadLetters = f.DeadLetters
+	return s, nil
+}
+
+func (s *FileSubscriptionStore) CreateSubscription(_ context.Context, sub entity.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[sub.ID] = sub
+	return s.save()
+}
+
+func (s *FileSubscriptionStore) GetSubscription(_ context.Context, id string) (*entity.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.subs[id]
+	if !ok {
+		return nil, ErrSubscriptionNotFound
+	}
+	return &sub, nil
+}
+
+// ListSubscriptions retorna as assinaturas em ordem de criação.
+func (s *FileSubscriptionStore) ListSubscriptions(_ context.Context) ([]entity.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sorted(), nil
+}
+
+func (s *FileSubscriptionStore) DeleteSubscription(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[id]; !ok {
+		return ErrSubscriptionNotFound
+	}
+	delete(s.subs, id)
+	return s.save()
+}
+
+func (s *FileSubscriptionStore) UpdateSubscription(_ context.Context, sub entity.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[sub.ID]; !ok {
+		return ErrSubscriptionNotFound
+	}
+	s.subs[sub.ID] = sub
+	return s.save()
+}
+
+func (s *FileSubscriptionStore) UpdateSubscriptionState(_ context.Context, id string, matching bool, checkedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.subs[id]
+	if !ok {
+		return ErrSubscriptionNotFound
+	}
+	changed := sub.Matching != matching
+	sub.Matching = matching
+	sub.LastCheckedAt = &checkedAt
+	s.subs[id] = sub
+	if !changed {
+		return nil // Só a data da verificação mudou; evita regravar o arquivo a cada ciclo
+	}
+	return s.save()
+}
+
+func (s *FileSubscriptionStore) AddDeadLetter(_ context.Context, dl entity.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters = append(s.deadLetters, dl)
+	if len(s.deadLetters) > MaxDeadLetters {
+		s.deadLetters = s.deadLetters[len(s.deadLetters)-MaxDeadLetters:]
+	}
+	return s.save()
+}
+
+// ListDeadLetters retorna os avisos não entregues, do mais antigo ao mais recente.
+func (s *FileSubscriptionStore) ListDeadLetters(_ context.Context) ([]entity.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]entity.DeadLetter{}, s.deadLetters...), nil
+}
+
+func (s *FileSubscriptionStore) sorted() []entity.Subscription {
+	out := make([]entity.Subscription, 0, len(s.subs))
+	for _, sub := range s.subs {
+		out = append(out, sub)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// save grava o estado em um arquivo temporário e o renomeia, para que uma
+// falha no meio da escrita não corrompa o arquivo anterior. Chamado com mu travado.
+func (s *FileSubscriptionStore) save() error {
+	if s.Path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(subscriptionFile{Subscriptions: s.sorted(), DeadLetters: s.deadLetters}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode subscriptions: %w", err)
+	}
+	if err := writeFileAtomic(s.Path, data); err != nil {
+		return fmt.Errorf("failed to write subscriptions file: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic grava data em um arquivo temporário (permissão 0600) no mesmo
+// diretório e o renomeia para path, criando o diretório se preciso.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
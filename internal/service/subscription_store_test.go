@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSubscriptionStore(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/data/subscriptions.json"
+
+	store, err := NewFileSubscriptionStore(path)
+	assert.NoError(t, err)
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.NoError(t, store.CreateSubscription(ctx, entity.Subscription{ID: "b", CEP: "01001000", Condition: "temp_C > 35", CreatedAt: created.Add(time.Minute)}))
+	assert.NoError(t, store.CreateSubscription(ctx, entity.Subscription{ID: "a", CEP: "20040002", Condition: "temp_C < 5", CreatedAt: created}))
+	assert.NoError(t, store.UpdateSubscriptionState(ctx, "a", true, created.Add(time.Hour)))
+	assert.NoError(t, store.AddDeadLetter(ctx, entity.DeadLetter{Event: entity.SubscriptionEvent{ID: "evt"}, Attempts: 5}))
+	assert.ErrorIs(t, store.DeleteSubscription(ctx, "missing"), ErrSubscriptionNotFound)
+
+	// Um novo store lê o que o anterior gravou
+	reloaded, err := NewFileSubscriptionStore(path)
+	assert.NoError(t, err)
+	subs, err := reloaded.ListSubscriptions(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, subs, 2) {
+		assert.Equal(t, "a", subs[0].ID, "ordered by creation")
+		assert.True(t, subs[0].Matching)
+		assert.Equal(t, "b", subs[1].ID)
+	}
+	dls, err := reloaded.ListDeadLetters(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, dls, 1)
+
+	assert.NoError(t, reloaded.DeleteSubscription(ctx, "a"))
+	_, err = reloaded.GetSubscription(ctx, "a")
+	assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+}
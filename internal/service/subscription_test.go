@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		raw       string
+		want      string
+		wantError bool
+	}{
+		{"temp_C > 35", "temp_C > 35", false},
+		{"< 5", "temp_C < 5", false},
+		{"temp_f>=95.5", "temp_F >= 95.5", false},
+		{"  temp_K <= -0.5 ", "temp_K <= -0.5", false},
+		{"temp_C = 35", "", true},
+		{"humidity > 80", "", true},
+		{"hot", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		cond, err := ParseCondition(tt.raw)
+		if tt.wantError {
+			assert.ErrorIs(t, err, ErrInvalidCondition, tt.raw)
+			continue
+		}
+		assert.NoError(t, err, tt.raw)
+		assert.Equal(t, tt.want, cond.String(), tt.raw)
+	}
+
+	cond, _ := ParseCondition("temp_F > 95")
+	temp := entity.Temperature{C: 36, F: 96.8, K: 309.15}
+	assert.Equal(t, 96.8, cond.Value(temp))
+	assert.True(t, cond.Holds(cond.Value(temp)))
+	assert.False(t, cond.Holds(95))
+}
+
+func TestValidateCallbackURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		allowed bool
+	}{
+		{"https://example.com/hook", true},
+		{"http://93.184.215.14:8080/hook", true},
+		{"http://[2606:2800:21f:cb07:6820:80da:af6b:8b2c]/hook", true},
+		{"ftp://example.com", false},
+		{"/hook", false},
+		{"http://127.0.0.1:9090/admin/breakers/weatherapi/open", false},
+		{"http://localhost/hook", false},
+		{"http://api.localhost./hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://metadata.google.internal/computeMetadata/v1/", false},
+		{"http://0.0.0.0:8080/", false},
+		{"http://[::1]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+		{"http://[fd00:ec2::254]/", false},
+		{"http://100.64.0.1/hook", false},         // CGNAT
+		{"http://100.100.100.200/latest/", false}, // Metadados da Alibaba Cloud
+		{"http://198.18.0.1/hook", false},         // Testes de desempenho
+		{"http://203.0.113.7/hook", false},        // Documentação
+		{"http://[64:ff9b::a00:1]/hook", false},   // NAT64 para 10.0.0.1
+		{"http://[2002:a00:1::]/hook", false},     // 6to4
+		{"http://224.0.0.1/hook", false},
+	}
+	for _, tt := range tests {
+		err := ValidateCallbackURL(tt.raw)
+		if tt.allowed {
+			assert.NoError(t, err, tt.raw)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidCallbackURL, tt.raw)
+		}
+	}
+
+	// Nomes que resolvem para a rede interna são barrados na conexão
+	assert.ErrorIs(t, guardDial("tcp4", "127.0.0.1:80", nil), ErrBlockedDestination)
+	assert.ErrorIs(t, guardDial("tcp6", "[fe80::1]:80", nil), ErrBlockedDestination)
+	assert.ErrorIs(t, guardDial("tcp4", "100.64.0.1:80", nil), ErrBlockedDestination)
+	assert.ErrorIs(t, guardDial("tcp4", "198.19.255.1:80", nil), ErrBlockedDestination)
+	assert.ErrorIs(t, guardDial("tcp6", "[64:ff9b::7f00:1]:80", nil), ErrBlockedDestination)
+	assert.NoError(t, guardDial("tcp4", "93.184.215.14:443", nil))
+}
+
+func TestSubscriptionScheduler_Check(t *testing.T) {
+	ctx := context.Background()
+	rcv := newCallbackReceiver(t, "whsec_test", http.StatusOK)
+	store, _ := NewFileSubscriptionStore("")
+	store.CreateSubscription(ctx, entity.Subscription{ID: "hot", CEP: "01001000", Condition: "temp_C > 35", CallbackURL: rcv.URL, Secret: "whsec_test"})
+	store.CreateSubscription(ctx, entity.Subscription{ID: "cold", CEP: "01311000", Condition: "temp_C < 5", CallbackURL: rcv.URL, Secret: "whsec_test"})
+
+	mockLocation := new(mocks.LocationFinder)
+	mockWeather := new(mocks.WeatherFinder)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "01311000").Return("São Paulo", nil)
+	mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 30}, nil).Once()
+	mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 36}, nil).Twice()
+	mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 34}, nil).Once()
+	mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 37}, nil).Once()
+
+	notifier := NewWebhookNotifier(rcv.Client(), store, 1, time.Millisecond)
+	scheduler := NewSubscriptionScheduler(store, NewBatchLookup(mockLocation, mockWeather, 2), NewStandardTemperatureConverter(), notifier, time.Minute)
+
+	// 30 °C: nenhuma condição vale
+	assert.NoError(t, scheduler.Check(ctx))
+	assert.Empty(t, rcv.received())
+
+	// 36 °C: "hot" cruza o limite e é avisada uma única vez enquanto a condição se mantém
+	assert.NoError(t, scheduler.Check(ctx))
+	assert.NoError(t, scheduler.Check(ctx))
+	events := rcv.received()
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "hot", events[0].SubscriptionID)
+		assert.Equal(t, EventThresholdCrossed, events[0].Type)
+		assert.Equal(t, "São Paulo", events[0].City)
+		assert.Equal(t, 36.0, events[0].Value)
+		assert.Equal(t, 96.8, events[0].Temperature.F)
+	}
+	hot, _ := store.GetSubscription(ctx, "hot")
+	assert.True(t, hot.Matching)
+	assert.NotNil(t, hot.LastCheckedAt)
+
+	// 34 °C e depois 37 °C: volta abaixo do limite e cruza de novo
+	assert.NoError(t, scheduler.Check(ctx))
+	assert.NoError(t, scheduler.Check(ctx))
+	assert.Len(t, rcv.received(), 2)
+
+	// Uma consulta por cidade em cada verificação, mesmo com dois CEPs
+	mockWeather.AssertNumberOfCalls(t, "GetWeatherByCity", 5)
+}
+
+func TestSubscriptionScheduler_Concurrency(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	inFlight, peak, delivered := 0, 0, 0
+	rcv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		delivered++
+		mu.Unlock()
+	}))
+	t.Cleanup(rcv.Close)
+
+	store, _ := NewFileSubscriptionStore("")
+	for i := range 6 {
+		store.CreateSubscription(ctx, entity.Subscription{ID: strconv.Itoa(i), CEP: "01001000", Condition: "temp_C > 35", CallbackURL: rcv.URL, Secret: "whsec_test"})
+	}
+	mockLocation := new(mocks.LocationFinder)
+	mockWeather := new(mocks.WeatherFinder)
+	mockLocation.On("GetLocationByCEP", mock.Anything, "01001000").Return("São Paulo", nil)
+	mockWeather.On("GetWeatherByCity", mock.Anything, "São Paulo").Return(&entity.CurrentWeather{TempC: 36}, nil)
+
+	notifier := NewWebhookNotifier(rcv.Client(), store, 1, time.Millisecond)
+	scheduler := NewSubscriptionScheduler(store, NewBatchLookup(mockLocation, mockWeather, 2), NewStandardTemperatureConverter(), notifier, time.Minute)
+	scheduler.Concurrency = 2
+
+	assert.NoError(t, scheduler.Check(ctx))
+	assert.Equal(t, 6, delivered, "every crossing is delivered")
+	assert.LessOrEqual(t, peak, 2, "deliveries are bounded by Concurrency")
+}
@@ -0,0 +1,75 @@
+package service
+
+import "math"
+
+// Limites de validade das fórmulas, conforme NOAA/NWS e Environment Canada.
+const (
+	windChillMaxTempC  = 10.0 // Wind chill só é definido para temperaturas até 10 °C
+	windChillMinWindKh = 4.8  // ...e ventos acima de 4,8 km/h
+	heatIndexMinTempF  = 80.0 // Limite de 80 °F (26,7 °C) que separa Steadman de Rothfusz
+)
+
+func celsiusToFahrenheit(tempC float64) float64 { return tempC*1.8 + 32 }
+func fahrenheitToCelsius(tempF float64) float64 { return (tempF - 32) / 1.8 }
+
+// HeatIndexC calcula o índice de calor (°C) pelo algoritmo da NOAA: a fórmula
+// simplificada de Steadman e, quando o resultado passa de 80 °F, a regressão de
+// Rothfusz com os ajustes para umidade muito baixa ou muito alta.
+func HeatIndexC(tempC, humidity float64) float64 {
+	t := celsiusToFahrenheit(tempC)
+	rh := humidity
+
+	hi := 0.5 * (t + 61.0 + (t-68.0)*1.2 + rh*0.094)
+	if (hi+t)/2 < heatIndexMinTempF {
+		return fahrenheitToCelsius(hi)
+	}
+
+	hi = -42.379 + 2.04901523*t + 10.14333127*rh -
+		0.22475541*t*rh - 0.00683783*t*t - 0.05481717*rh*rh +
+		0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+
+	switch {
+	case rh < 13 && t >= 80 && t <= 112:
+		hi -= ((13 - rh) / 4) * math.Sqrt((17-math.Abs(t-95))/17)
+	case rh > 85 && t >= 80 && t <= 87:
+		hi += ((rh - 85) / 10) * ((87 - t) / 5)
+	}
+	return fahrenheitToCelsius(hi)
+}
+
+// WindChillC calcula a sensação térmica pelo vento (°C) com a fórmula de 2001
+// da Environment Canada/NWS. Fora da faixa de validade retorna a própria temperatura.
+func WindChillC(tempC, windKph float64) float64 {
+	if tempC > windChillMaxTempC || windKph <= windChillMinWindKh {
+		return tempC
+	}
+	v := math.Pow(windKph, 0.16)
+	return 13.12 + 0.6215*tempC - 11.37*v + 0.3965*tempC*v
+}
+
+// DewPointC calcula o ponto de orvalho (°C) pela aproximação de Magnus
+// (coeficientes de Alduchov e Eskridge).
+func DewPointC(tempC, humidity float64) float64 {
+	const a, b = 17.625, 243.04
+	gamma := math.Log(humidity/100) + a*tempC/(b+tempC)
+	return b * gamma / (a - gamma)
+}
+
+// HumidexC calcula o humidex da Environment Canada a partir da temperatura e do ponto de orvalho.
+func HumidexC(tempC, dewPointC float64) float64 {
+	e := 6.11 * math.Exp(5417.7530*(1/273.16-1/(273.15+dewPointC)))
+	return tempC + 0.5555*(e-10)
+}
+
+// ApparentTemperatureC combina os índices como o NWS faz para a "sensação térmica":
+// wind chill no frio com vento, heat index no calor e a temperatura do ar no restante.
+func ApparentTemperatureC(tempC, humidity, windKph float64) float64 {
+	switch {
+	case tempC <= windChillMaxTempC && windKph > windChillMinWindKh:
+		return WindChillC(tempC, windKph)
+	case celsiusToFahrenheit(tempC) >= heatIndexMinTempF:
+		return HeatIndexC(tempC, humidity)
+	default:
+		return tempC
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Os testes de índices térmicos comparam as fórmulas com as tabelas publicadas
+// pela NOAA/NWS (em °F e mph) e pela Environment Canada (em °C e km/h). As
+// tabelas trazem valores inteiros, daí a tolerância de meio grau (mais o erro de
+// arredondamento das próprias regressões).
+func TestHeatIndexC_NOAATable(t *testing.T) {
+	tests := []struct {
+		tempF, humidity, expectedF float64
+	}{
+		{80, 40, 80},
+		{90, 50, 95},
+		{100, 40, 109},
+		{86, 90, 105},
+		{96, 10, 91}, // ajuste de umidade baixa
+	}
+
+	for _, tt := range tests {
+		got := celsiusToFahrenheit(HeatIndexC(fahrenheitToCelsius(tt.tempF), tt.humidity))
+		assert.InDelta(t, tt.expectedF, got, 1.0, "T=%.0f°F RH=%.0f%%", tt.tempF, tt.humidity)
+	}
+}
+
+func TestHeatIndexC_Steadman(t *testing.T) {
+	// Abaixo de 80 °F na média de Steadman o índice é a fórmula simplificada.
+	assert.InDelta(t, 19.4, HeatIndexC(20, 50), 0.1)
+
+	// A decisão é pela média de Steadman, não pela temperatura do ar: 26,6 °C
+	// com umidade alta já passa para Rothfusz, sem salto em 26,7 °C.
+	assert.InDelta(t, 29.6, HeatIndexC(26.6, 90), 0.2)
+	assert.InDelta(t, HeatIndexC(26.6, 90), HeatIndexC(26.7, 90), 0.8)
+}
+
+func TestWindChillC_ReferenceTables(t *testing.T) {
+	const kphPerMph = 1.609344
+
+	t.Run("NWS Table", func(t *testing.T) {
+		tests := []struct {
+			tempF, windMph, expectedF float64
+		}{
+			{40, 5, 36},
+			{30, 10, 21},
+			{0, 15, -19},
+			{-10, 30, -39},
+		}
+		for _, tt := range tests {
+			got := celsiusToFahrenheit(WindChillC(fahrenheitToCelsius(tt.tempF), tt.windMph*kphPerMph))
+			assert.InDelta(t, tt.expectedF, got, 1.0, "T=%.0f°F V=%.0fmph", tt.tempF, tt.windMph)
+		}
+	})
+
+	t.Run("Environment Canada Table", func(t *testing.T) {
+		tests := []struct {
+			tempC, windKph, expectedC float64
+		}{
+			{-10, 20, -18},
+			{-20, 30, -33},
+			{-30, 50, -49},
+		}
+		for _, tt := range tests {
+			assert.InDelta(t, tt.expectedC, WindChillC(tt.tempC, tt.windKph), 0.6, "T=%.0f°C V=%.0fkm/h", tt.tempC, tt.windKph)
+		}
+	})
+
+	t.Run("Outside Validity Range", func(t *testing.T) {
+		assert.Equal(t, 15.0, WindChillC(15, 30))
+		assert.Equal(t, -5.0, WindChillC(-5, 3))
+	})
+}
+
+func TestDewPointAndHumidexC(t *testing.T) {
+	assert.InDelta(t, 16.7, DewPointC(25, 60), 0.1)
+	assert.InDelta(t, 18.4, DewPointC(30, 50), 0.1)
+	assert.InDelta(t, 0.0, DewPointC(0, 100), 0.001)
+
+	// Tabela de humidex da Environment Canada (temperatura x ponto de orvalho)
+	assert.InDelta(t, 38, HumidexC(30, 20), 0.6)
+	assert.InDelta(t, 47, HumidexC(35, 25), 0.6)
+	assert.InDelta(t, 24, HumidexC(25, 5), 0.6)
+}
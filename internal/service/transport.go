@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// TimeoutTransport limita cada chamada, leitura do corpo incluída, como o
+// http.Client.Timeout, mas com um prazo que pode ser trocado com o cliente em
+// uso. Cada chamada fica com o prazo vigente quando começou.
+type TimeoutTransport struct {
+	next    http.RoundTripper
+	timeout atomic.Int64
+}
+
+// NewTimeoutTransport cria o transporte sobre next (nil usa o
+// http.DefaultTransport). Um prazo zero não limita as chamadas.
+func NewTimeoutTransport(next http.RoundTripper, timeout time.Duration) *TimeoutTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &TimeoutTransport{next: next}
+	t.SetTimeout(timeout)
+	return t
+}
+
+// SetTimeout troca o prazo das próximas chamadas.
+func (t *TimeoutTransport) SetTimeout(timeout time.Duration) {
+	t.timeout.Store(int64(timeout))
+}
+
+// Timeout retorna o prazo vigente.
+func (t *TimeoutTransport) Timeout() time.Duration {
+	return time.Duration(t.timeout.Load())
+}
+
+// RoundTrip implementa http.RoundTripper. O prazo vence com
+// context.DeadlineExceeded, classificado como ErrUpstreamTimeout.
+func (t *TimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout := t.Timeout()
+	if timeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel} // O prazo vale até o corpo ser fechado
+	return resp, nil
+}
+
+// CloseIdleConnections repassa ao transporte de baixo, para que
+// http.Client.CloseIdleConnections continue funcionando.
+func (t *TimeoutTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
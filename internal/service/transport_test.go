@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutTransport(t *testing.T) {
+	delay := make(chan time.Duration, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		select {
+		case <-time.After(<-delay): // O corpo termina depois do atraso
+		case <-r.Context().Done():
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	transport := NewTimeoutTransport(nil, 50*time.Millisecond)
+	client := &http.Client{Transport: transport}
+	get := func() error {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, err = io.ReadAll(resp.Body)
+		return err
+	}
+
+	delay <- 10 * time.Millisecond
+	assert.NoError(t, get())
+
+	delay <- time.Second
+	err := get()
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "the deadline covers the body")
+	assert.ErrorIs(t, classifyTransportError(context.Background(), ProviderViaCEP, err), ErrUpstreamTimeout)
+
+	transport.SetTimeout(2 * time.Second)
+	delay <- 100 * time.Millisecond
+	assert.NoError(t, get())
+	assert.Equal(t, 2*time.Second, transport.Timeout())
+}
@@ -1,30 +1,43 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"net/url"
+	neturl "net/url"
+	"sync/atomic"
+	"time"
 
 	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/logging"
 )
 
 // WeatherFinder define a interface para buscar o clima por cidade.
 type WeatherFinder interface {
-	GetWeatherByCity(city string) (float64, error)
+	GetWeatherByCity(ctx context.Context, city string) (*entity.CurrentWeather, error)
 }
 
 // TemperatureConverter define a interface para converter temperaturas.
 type TemperatureConverter interface {
 	ConvertTemperatures(tempC float64) *entity.WeatherOutput
+	ThermalIndices(weather *entity.CurrentWeather) *entity.ThermalIndices
+}
+
+// PrecisionConverter é implementado por conversores que aceitam sobrescrever
+// a precisão por requisição (parâmetro ?precision=).
+type PrecisionConverter interface {
+	TemperatureConverter
+	WithPrecision(digits int) TemperatureConverter
 }
 
 // WeatherAPIService implementa WeatherFinder usando a WeatherAPI.
 type WeatherAPIService struct {
-	APIKey string
 	Client *http.Client
+	apiKey atomic.Pointer[string]
 }
 
 // NewWeatherAPIService cria uma nova instância de WeatherAPIService.
@@ -32,74 +45,175 @@ func NewWeatherAPIService(apiKey string, client *http.Client) *WeatherAPIService
 	if client == nil {
 		client = http.DefaultClient
 	}
-	return &WeatherAPIService{APIKey: apiKey, Client: client}
+	s := &WeatherAPIService{Client: client}
+	s.SetAPIKey(apiKey)
+	return s
+}
+
+// SetAPIKey troca a chave usada pelas próximas chamadas, para rotacioná-la sem
+// reiniciar. As chamadas em andamento terminam com a chave anterior.
+func (s *WeatherAPIService) SetAPIKey(key string) {
+	s.apiKey.Store(&key)
 }
 
 var ErrWeatherAPIFailure = errors.New("failed to get weather data")
 
-// GetWeatherByCity busca a temperatura atual (Celsius) para uma cidade usando a WeatherAPI.
-func (s *WeatherAPIService) GetWeatherByCity(city string) (float64, error) {
-	if s.APIKey == "" {
-		return 0, errors.New("WeatherAPI key is missing")
+// Códigos de erro da WeatherAPI (https://www.weatherapi.com/docs/#intro-error-codes).
+const (
+	weatherAPICodeKeyNotProvided = 1002
+	weatherAPICodeNoLocation     = 1006
+	weatherAPICodeKeyInvalid     = 2006
+	weatherAPICodeQuotaExceeded  = 2007
+	weatherAPICodeKeyDisabled    = 2008
+	weatherAPICodeNoAccess       = 2009
+)
+
+// GetWeatherByCity busca as condições atuais (temperatura em Celsius, umidade e vento)
+// para uma cidade usando a WeatherAPI. city é repassado no parâmetro q, que também
+// aceita coordenadas ("lat,lon") e endereços IP; o local resolvido vem no resultado.
+func (s *WeatherAPIService) GetWeatherByCity(ctx context.Context, city string) (*entity.CurrentWeather, error) {
+	logging.Add(ctx, slog.String("provider", ProviderWeatherAPI))
+	var apiKey string
+	if k := s.apiKey.Load(); k != nil {
+		apiKey = *k
+	}
+	if apiKey == "" {
+		return nil, &UpstreamError{Provider: ProviderWeatherAPI, Kind: ErrUpstreamBadKey, Err: errors.New("WeatherAPI key is missing")}
 	}
 
 	// URL Encode a cidade para evitar problemas com espaços ou caracteres especiais
-	encodedCity := url.QueryEscape(city)
-	url := fmt.Sprintf("http://api.weatherapi.com/v1/current.json?key=%s&q=%s&aqi=no", s.APIKey, encodedCity)
+	encodedCity := neturl.QueryEscape(city)
+	url := fmt.Sprintf("http://api.weatherapi.com/v1/current.json?key=%s&q=%s&aqi=no", apiKey, encodedCity)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create WeatherAPI request: %w", err)
+		return nil, fmt.Errorf("failed to create WeatherAPI request: %w", err)
 	}
 
+	start := time.Now()
 	resp, err := s.Client.Do(req)
+	logging.Add(ctx, logging.Latency(ProviderWeatherAPI, time.Since(start)))
 	if err != nil {
-		return 0, fmt.Errorf("%w: %v", ErrWeatherAPIFailure, err)
+		// O *url.Error de Client.Do traz a URL completa; sem a query, a chave não
+		// vaza para os logs e traces
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+		}
+		return nil, classifyTransportError(ctx, ProviderWeatherAPI, fmt.Errorf("%w: %w", ErrWeatherAPIFailure, err))
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read WeatherAPI response body: %w", err)
+		return nil, fmt.Errorf("failed to read WeatherAPI response body: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		// Tenta decodificar uma possível mensagem de erro da API
-		var errorResp map[string]interface{}
-		json.Unmarshal(body, &errorResp)
-		errMsg := fmt.Sprintf("status %d", resp.StatusCode)
-		if errorData, ok := errorResp["error"].(map[string]interface{}); ok {
-			if msg, ok := errorData["message"].(string); ok {
-				errMsg = fmt.Sprintf("status %d - %s", resp.StatusCode, msg)
-			}
-		}
-		return 0, fmt.Errorf("%w: request failed with %s", ErrWeatherAPIFailure, errMsg)
+		return nil, weatherAPIError(resp, body)
 	}
 
 	var weatherResp entity.WeatherAPIResponse
 	err = json.Unmarshal(body, &weatherResp)
 	if err != nil {
-		return 0, fmt.Errorf("failed to decode WeatherAPI response: %w", err)
+		return nil, fmt.Errorf("failed to decode WeatherAPI response: %w", err)
 	}
 
-	return weatherResp.Current.TempC, nil
+	weather := &entity.CurrentWeather{
+		TempC:    weatherResp.Current.TempC,
+		Humidity: weatherResp.Current.Humidity,
+		WindKph:  weatherResp.Current.WindKph,
+		Place:    weatherResp.Location.Name,
+		Region:   weatherResp.Location.Region,
+		Country:  weatherResp.Location.Country,
+	}
+	if weatherResp.Current.LastUpdatedEpoch > 0 {
+		weather.ObservedAt = time.Unix(weatherResp.Current.LastUpdatedEpoch, 0).UTC()
+	}
+	return weather, nil
+}
+
+// weatherAPIError classifica uma resposta de erro da WeatherAPI pelo código
+// interno do corpo ({"error": {"code": 1006, "message": "..."}}) e, na falta
+// dele, pelo status HTTP.
+func weatherAPIError(resp *http.Response, body []byte) error {
+	// Tenta decodificar uma possível mensagem de erro da API
+	var errorResp entity.WeatherAPIErrorResponse
+	json.Unmarshal(body, &errorResp)
+	errMsg := fmt.Sprintf("status %d", resp.StatusCode)
+	if errorResp.Error.Message != "" {
+		errMsg = fmt.Sprintf("status %d - %s", resp.StatusCode, errorResp.Error.Message)
+	}
+	err := fmt.Errorf("%w: request failed with %s", ErrWeatherAPIFailure, errMsg)
+
+	switch errorResp.Error.Code {
+	case weatherAPICodeNoLocation:
+		return &UpstreamError{Provider: ProviderWeatherAPI, Kind: ErrLocationUnknown, Err: err}
+	case weatherAPICodeQuotaExceeded:
+		return &UpstreamError{Provider: ProviderWeatherAPI, Kind: ErrUpstreamRateLimited, Err: err}
+	case weatherAPICodeKeyNotProvided, weatherAPICodeKeyInvalid, weatherAPICodeKeyDisabled, weatherAPICodeNoAccess:
+		return &UpstreamError{Provider: ProviderWeatherAPI, Kind: ErrUpstreamBadKey, Err: err}
+	}
+	if upErr := classifyStatus(ProviderWeatherAPI, resp, err); upErr != nil {
+		return upErr
+	}
+	return err
 }
 
 // StandardTemperatureConverter implementa TemperatureConverter.
-type StandardTemperatureConverter struct{}
+type StandardTemperatureConverter struct {
+	Policy RoundingPolicy
+}
 
-// NewStandardTemperatureConverter cria uma nova instância de StandardTemperatureConverter.
+// NewStandardTemperatureConverter cria uma nova instância de StandardTemperatureConverter
+// usando a política de arredondamento padrão.
 func NewStandardTemperatureConverter() *StandardTemperatureConverter {
-	return &StandardTemperatureConverter{}
+	return NewStandardTemperatureConverterWithPolicy(DefaultRoundingPolicy())
 }
 
-// ConvertTemperatures converte Celsius para Fahrenheit e Kelvin.
+// NewStandardTemperatureConverterWithPolicy cria um StandardTemperatureConverter com a política informada.
+func NewStandardTemperatureConverterWithPolicy(policy RoundingPolicy) *StandardTemperatureConverter {
+	return &StandardTemperatureConverter{Policy: policy}
+}
+
+// ConvertTemperatures converte Celsius para Fahrenheit e Kelvin e aplica a política de arredondamento.
 func (c *StandardTemperatureConverter) ConvertTemperatures(tempC float64) *entity.WeatherOutput {
 	tempF := tempC*1.8 + 32
 	tempK := tempC + 273.15 // Usando 273.15 para Kelvin, mais preciso que 273
-	return &entity.WeatherOutput{
+	output := &entity.WeatherOutput{
 		TempC: tempC,
 		TempF: tempF,
 		TempK: tempK,
 	}
+	c.Policy.Apply(output)
+	return output
+}
+
+// ThermalIndices calcula heat index, wind chill, ponto de orvalho, humidex e sensação
+// térmica nas três escalas. Retorna nil se a umidade não estiver disponível.
+func (c *StandardTemperatureConverter) ThermalIndices(weather *entity.CurrentWeather) *entity.ThermalIndices {
+	if weather == nil || weather.Humidity <= 0 || weather.Humidity > 100 {
+		return nil
+	}
+	dewPoint := DewPointC(weather.TempC, weather.Humidity)
+	return &entity.ThermalIndices{
+		HeatIndex:           c.temperature(HeatIndexC(weather.TempC, weather.Humidity)),
+		WindChill:           c.temperature(WindChillC(weather.TempC, weather.WindKph)),
+		DewPoint:            c.temperature(dewPoint),
+		Humidex:             c.temperature(HumidexC(weather.TempC, dewPoint)),
+		ApparentTemperature: c.temperature(ApparentTemperatureC(weather.TempC, weather.Humidity, weather.WindKph)),
+	}
+}
+
+// temperature expressa uma temperatura em Celsius nas três escalas, já arredondada.
+func (c *StandardTemperatureConverter) temperature(tempC float64) entity.Temperature {
+	t := entity.Temperature{C: tempC, F: tempC*1.8 + 32, K: tempC + 273.15}
+	c.Policy.ApplyTemperature(&t)
+	return t
+}
+
+// WithPrecision retorna um conversor que usa digits casas decimais para todas as unidades,
+// mantendo o modo de arredondamento configurado.
+func (c *StandardTemperatureConverter) WithPrecision(digits int) TemperatureConverter {
+	return &StandardTemperatureConverter{Policy: c.Policy.WithPrecision(digits)}
 }
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+)
+
+// Padrões da entrega dos avisos, usados quando a configuração não informa valores.
+const (
+	DefaultWebhookMaxAttempts = 5
+	DefaultWebhookBackoff     = time.Second
+	DefaultWebhookTimeout     = 10 * time.Second
+	DefaultWebhookConcurrency = 10
+)
+
+// Headers enviados em cada aviso.
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature" // t=<unix>,v1=<hex do HMAC-SHA256>
+	WebhookIDHeader        = "X-Webhook-ID"        // ID do aviso, igual em todas as tentativas
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrBlockedDestination indica um callback fora dos endereços públicos
+	// (loopback, rede privada, CGNAT, NAT64, metadados da nuvem etc.), recusado contra SSRF.
+	ErrBlockedDestination = errors.New("callback destination not allowed")
+)
+
+// metadataAddrs são os serviços de metadados das nuvens dentro de faixas
+// públicas (ex: Alibaba Cloud).
+var metadataAddrs = []netip.Addr{
+	netip.MustParseAddr("100.100.100.200"),
+}
+
+// specialPrefixes são as faixas de uso especial da IANA que IsGlobalUnicast
+// aceita, mas que não são destinos públicos: redes privadas, CGNAT, testes de
+// desempenho, documentação e os prefixos de tradução (NAT64, 6to4, Teredo),
+// que levariam a um endereço IPv4 interno.
+var specialPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+}
+
+// publicAddr informa se os avisos podem ser entregues em addr: só endereços
+// unicast globais fora das faixas de uso especial.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || slices.Contains(metadataAddrs, addr) {
+		return false
+	}
+	for _, p := range specialPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// guardDial recusa conexões a endereços internos. Roda depois da resolução de
+// nomes, a cada conexão, então um DNS que muda de resposta não escapa da regra.
+func guardDial(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil || !publicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrBlockedDestination, address)
+	}
+	return nil
+}
+
+// NewWebhookTransport cria o transporte dos avisos: sem proxy e com conexões
+// apenas a endereços públicos.
+func NewWebhookTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil // O proxy estaria na rede interna e esconderia o destino real
+	t.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: guardDial}).DialContext
+	return t
+}
+
+// RefuseRedirects é o CheckRedirect do cliente dos avisos: a resposta 3xx é
+// tratada como falha em vez de seguida, já que o novo destino não foi validado.
+func RefuseRedirects(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// WebhookNotifier entrega os avisos aos callbacks com novas tentativas em
+// backoff exponencial. Avisos não entregues vão para a lista de dead letters.
+type WebhookNotifier struct {
+	Client      *http.Client
+	Store       SubscriptionStore
+	MaxAttempts int
+	Backoff     time.Duration // Espera antes da segunda tentativa; dobra a cada nova falha
+	Logger      *slog.Logger
+
+	now func() time.Time
+}
+
+// NewWebhookNotifier cria uma nova instância de WebhookNotifier.
+func NewWebhookNotifier(client *http.Client, store SubscriptionStore, maxAttempts int, backoff time.Duration) *WebhookNotifier {
+	if client == nil {
+		client = &http.Client{Transport: NewWebhookTransport(), CheckRedirect: RefuseRedirects, Timeout: DefaultWebhookTimeout}
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultWebhookMaxAttempts
+	}
+	if backoff <= 0 {
+		backoff = DefaultWebhookBackoff
+	}
+	return &WebhookNotifier{Client: client, Store: store, MaxAttempts: maxAttempts, Backoff: backoff, Logger: slog.Default(), now: time.Now}
+}
+
+// Deliver envia event ao callback de sub. Respostas 2xx confirmam a entrega;
+// falhas de rede, 429 e 5xx são repetidas; os demais 4xx encerram as tentativas.
+func (n *WebhookNotifier) Deliver(ctx context.Context, sub entity.Subscription, event entity.SubscriptionEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	wait := n.Backoff
+	attempts := 0
+	for {
+		attempts++
+		retry, err := n.send(ctx, sub, event.ID, body)
+		if err == nil {
+			return nil
+		}
+		n.Logger.WarnContext(ctx, "webhook delivery failed", "event_id", event.ID, "subscription_id", sub.ID, "attempt", attempts, "max_attempts", n.MaxAttempts, "error", err)
+		if !retry || attempts >= n.MaxAttempts || !sleep(ctx, wait) {
+			n.deadLetter(sub, event, attempts, err)
+			return err
+		}
+		wait *= 2
+	}
+}
+
+// send faz uma tentativa e informa se a falha pode ser repetida.
+func (n *WebhookNotifier) send(ctx context.Context, sub entity.Subscription, eventID string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", sub.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "fc-lab02-webhook")
+	req.Header.Set(WebhookIDHeader, eventID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(sub.Secret, n.now(), body))
+
+	resp, err := n.Client.Do(req)
+	if errors.Is(err, ErrBlockedDestination) {
+		return false, err
+	}
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // Permite reaproveitar a conexão
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	}
+}
+
+func (n *WebhookNotifier) deadLetter(sub entity.Subscription, event entity.SubscriptionEvent, attempts int, err error) {
+	dl := entity.DeadLetter{
+		Event:       event,
+		APIKeyID:    sub.APIKeyID,
+		CallbackURL: sub.CallbackURL,
+		Attempts:    attempts,
+		LastError:   err.Error(),
+		FailedAt:    n.now(),
+	}
+	// O contexto da verificação pode já ter sido cancelado; o registro não deve se perder
+	if err := n.Store.AddDeadLetter(context.Background(), dl); err != nil {
+		n.Logger.Error("error recording dead letter", "event_id", event.ID, "subscription_id", sub.ID, "error", err)
+	}
+}
+
+// sleep espera d ou até ctx ser cancelado; retorna false no cancelamento.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// SignWebhook monta o header X-Webhook-Signature: o HMAC-SHA256 com o segredo
+// da assinatura sobre "<timestamp>.<corpo>".
+func SignWebhook(secret string, at time.Time, body []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + ts + ",v1=" + webhookMAC(secret, ts, body)
+}
+
+// VerifyWebhook confere o header X-Webhook-Signature de um aviso recebido.
+// Assinaturas com mais de tolerance em relação a now são rejeitadas (replay).
+func VerifyWebhook(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sig = value
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(sig), []byte(webhookMAC(secret, ts, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func webhookMAC(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	now := time.Unix(1792281600, 0)
+	body := []byte(`{"id":"evt"}`)
+	header := SignWebhook("whsec_test", now, body)
+	assert.Regexp(t, `^t=1792281600,v1=[0-9a-f]{64}$`, header)
+
+	assert.NoError(t, VerifyWebhook("whsec_test", header, body, now.Add(time.Minute), 5*time.Minute))
+	assert.ErrorIs(t, VerifyWebhook("other", header, body, now, 5*time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyWebhook("whsec_test", header, []byte(`{"id":"forged"}`), now, 5*time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyWebhook("whsec_test", header, body, now.Add(time.Hour), 5*time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyWebhook("whsec_test", "garbage", body, now, 5*time.Minute), ErrInvalidSignature)
+}
+
+// callbackReceiver é um receptor local de avisos que responde com os status
+// informados, em ordem (o último se repete), e guarda os avisos recebidos.
+type callbackReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	events   []entity.SubscriptionEvent
+	verified []error
+}
+
+func newCallbackReceiver(t *testing.T, secret string, statuses ...int) *callbackReceiver {
+	t.Helper()
+	rcv := &callbackReceiver{statuses: statuses}
+	rcv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event entity.SubscriptionEvent
+		json.Unmarshal(body, &event)
+
+		rcv.mu.Lock()
+		defer rcv.mu.Unlock()
+		rcv.events = append(rcv.events, event)
+		rcv.verified = append(rcv.verified, VerifyWebhook(secret, r.Header.Get(WebhookSignatureHeader), body, time.Now(), time.Minute))
+		status := rcv.statuses[0]
+		if len(rcv.statuses) > 1 {
+			rcv.statuses = rcv.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rcv.Close)
+	return rcv
+}
+
+func (rcv *callbackReceiver) received() []entity.SubscriptionEvent {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return append([]entity.SubscriptionEvent{}, rcv.events...)
+}
+
+func TestWebhookNotifier_Deliver(t *testing.T) {
+	sub := entity.Subscription{ID: "sub", APIKeyID: "k1", Secret: "whsec_test"}
+	event := entity.SubscriptionEvent{ID: "evt", Type: EventThresholdCrossed, SubscriptionID: "sub"}
+
+	t.Run("Retries Until Delivered", func(t *testing.T) {
+		rcv := newCallbackReceiver(t, sub.Secret, http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusNoContent)
+		store, _ := NewFileSubscriptionStore("")
+		notifier := NewWebhookNotifier(rcv.Client(), store, 5, time.Millisecond)
+		sub := sub
+		sub.CallbackURL = rcv.URL
+
+		assert.NoError(t, notifier.Deliver(context.Background(), sub, event))
+		events := rcv.received()
+		assert.Len(t, events, 3)
+		for i, err := range rcv.verified {
+			assert.NoError(t, err, "attempt %d signature", i+1)
+			assert.Equal(t, "evt", events[i].ID, "same event ID on every attempt")
+		}
+		dls, _ := store.ListDeadLetters(context.Background())
+		assert.Empty(t, dls)
+	})
+
+	t.Run("Dead Letter After Max Attempts", func(t *testing.T) {
+		rcv := newCallbackReceiver(t, sub.Secret, http.StatusBadGateway)
+		store, _ := NewFileSubscriptionStore("")
+		notifier := NewWebhookNotifier(rcv.Client(), store, 3, time.Millisecond)
+		sub := sub
+		sub.CallbackURL = rcv.URL
+
+		assert.Error(t, notifier.Deliver(context.Background(), sub, event))
+		assert.Len(t, rcv.received(), 3)
+		dls, _ := store.ListDeadLetters(context.Background())
+		if assert.Len(t, dls, 1) {
+			assert.Equal(t, "evt", dls[0].Event.ID)
+			assert.Equal(t, "k1", dls[0].APIKeyID, "dead letters keep the subscription owner")
+			assert.Equal(t, 3, dls[0].Attempts)
+			assert.Equal(t, rcv.URL, dls[0].CallbackURL)
+			assert.Contains(t, dls[0].LastError, "502")
+		}
+	})
+
+	t.Run("Internal Destinations Are Refused", func(t *testing.T) {
+		rcv := newCallbackReceiver(t, sub.Secret, http.StatusNoContent)
+		store, _ := NewFileSubscriptionStore("")
+		notifier := NewWebhookNotifier(nil, store, 5, time.Millisecond) // Cliente padrão, com o transporte protegido
+		sub := sub
+		sub.CallbackURL = rcv.URL // 127.0.0.1
+
+		err := notifier.Deliver(context.Background(), sub, event)
+		assert.ErrorIs(t, err, ErrBlockedDestination)
+		assert.Empty(t, rcv.received())
+		dls, _ := store.ListDeadLetters(context.Background())
+		if assert.Len(t, dls, 1) {
+			assert.Equal(t, 1, dls[0].Attempts, "not retried")
+		}
+	})
+
+	t.Run("Redirects Are Not Followed", func(t *testing.T) {
+		target := newCallbackReceiver(t, sub.Secret, http.StatusNoContent)
+		rcv := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+		defer rcv.Close()
+		store, _ := NewFileSubscriptionStore("")
+		client := rcv.Client()
+		client.CheckRedirect = RefuseRedirects
+		notifier := NewWebhookNotifier(client, store, 5, time.Millisecond)
+		sub := sub
+		sub.CallbackURL = rcv.URL
+
+		assert.ErrorContains(t, notifier.Deliver(context.Background(), sub, event), "status 307")
+		assert.Empty(t, target.received())
+	})
+
+	t.Run("Client Errors Are Not Retried", func(t *testing.T) {
+		rcv := newCallbackReceiver(t, sub.Secret, http.StatusGone)
+		store, _ := NewFileSubscriptionStore("")
+		notifier := NewWebhookNotifier(rcv.Client(), store, 5, time.Millisecond)
+		sub := sub
+		sub.CallbackURL = rcv.URL
+
+		assert.Error(t, notifier.Deliver(context.Background(), sub, event))
+		assert.Len(t, rcv.received(), 1)
+		dls, _ := store.ListDeadLetters(context.Background())
+		assert.Len(t, dls, 1)
+	})
+}
@@ -0,0 +1,176 @@
+// Package tlsconfig carrega o certificado do servidor e, com mTLS, as CAs dos
+// clientes, e os relê quando os arquivos mudam, sem reiniciar o processo. As
+// conexões novas usam os arquivos novos; as abertas seguem com os antigos.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultReloadInterval é o intervalo padrão entre as verificações dos arquivos.
+const DefaultReloadInterval = 10 * time.Second
+
+// fileStamp identifica uma versão de um arquivo.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reloader guarda o certificado e as CAs dos clientes carregados por último.
+// Uma leitura que falha (ex: arquivo trocado pela metade) mantém os anteriores.
+type Reloader struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string // Vazio desliga o mTLS
+	Logger       *slog.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	stamps    map[string]fileStamp
+}
+
+// New carrega os arquivos pela primeira vez. certFile e keyFile são
+// obrigatórios; clientCAFile, se informado, passa a exigir certificados de
+// cliente assinados por uma das CAs dele.
+func New(certFile, keyFile, clientCAFile string) (*Reloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	r := &Reloader{CertFile: certFile, KeyFile: keyFile, ClientCAFile: clientCAFile, Logger: slog.Default()}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload relê os arquivos e, se todos forem válidos, passa a usá-los.
+func (r *Reloader) Reload() error {
+	stamps, err := r.stat()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+	if err != nil {
+		return fmt.Errorf("could not load TLS certificate: %w", err)
+	}
+	var clientCAs *x509.CertPool
+	if r.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("could not read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA file %s", r.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert, r.clientCAs, r.stamps = &cert, clientCAs, stamps
+	return nil
+}
+
+// Watch verifica os arquivos a cada interval, até ctx ser cancelado, e os
+// relê quando algum muda. A verificação é pelo stat dos arquivos, o que também
+// pega a troca do link simbólico feita pelos secrets do Kubernetes.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !r.changed() {
+			continue
+		}
+		// Falhas não atualizam os stamps, então a leitura é repetida no próximo ciclo
+		if err := r.Reload(); err != nil {
+			r.Logger.Error("could not reload TLS files, keeping the previous ones", "error", err)
+			continue
+		}
+		r.Logger.Info("reloaded TLS files", "cert_file", r.CertFile, "not_after", r.Certificate().Leaf.NotAfter)
+	}
+}
+
+// Certificate retorna o certificado do servidor em uso.
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+// ServerConfig monta a configuração TLS do servidor (TLS 1.2 ou superior).
+// Com mTLS, o handshake confere o certificado de cliente, se houver, e recusa
+// os inválidos. Quem exige o certificado são as rotas, para que as sondas do
+// Kubernetes, que não o apresentam, continuem funcionando.
+func (r *Reloader) ServerConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.Certificate(), nil
+		},
+	}
+	if r.ClientCAFile == "" {
+		return cfg
+	}
+
+	// As CAs são lidas a cada conexão. A configuração devolvida substitui a
+	// original, então já anuncia o HTTP/2 (também exigido pelo gRPC)
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	cfg.NextProtos = []string{"h2", "http/1.1"}
+	base := cfg.Clone()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := base.Clone()
+		r.mu.RLock()
+		c.ClientCAs = r.clientCAs
+		r.mu.RUnlock()
+		return c, nil
+	}
+	return cfg
+}
+
+func (r *Reloader) stat() (map[string]fileStamp, error) {
+	stamps := map[string]fileStamp{}
+	for _, name := range []string{r.CertFile, r.KeyFile, r.ClientCAFile} {
+		if name == "" {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, fmt.Errorf("could not read TLS file: %w", err)
+		}
+		stamps[name] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+// changed informa se algum arquivo mudou desde a última leitura bem-sucedida.
+func (r *Reloader) changed() bool {
+	stamps, err := r.stat()
+	if err != nil {
+		return true // Reload registra o erro
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for name, s := range stamps {
+		if r.stamps[name] != s {
+			return true
+		}
+	}
+	return false
+}
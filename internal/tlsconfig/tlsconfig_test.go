@@ -0,0 +1,224 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA emite certificados para os testes.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue retorna o certificado e a chave, em PEM, de um servidor em 127.0.0.1
+// ou de um cliente.
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "billing"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime)) // Garante um stamp novo mesmo com o mesmo tamanho
+}
+
+// serve atende HTTPS em uma porta livre com a configuração do Reloader.
+func serve(t *testing.T, r *Reloader) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", r.ServerConfig())
+	require.NoError(t, err)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			w.Header().Set("X-Client-Cert", r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+	})}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return "https://" + ln.Addr().String()
+}
+
+// client cria um cliente que confia em ca, sem reaproveitar conexões. O
+// certificado, se houver, é enviado mesmo que o servidor não confie no emissor.
+func client(ca *testCA, cert *tls.Certificate) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	cfg := &tls.Config{RootCAs: roots}
+	if cert != nil {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return cert, nil }
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, DisableKeepAlives: true}}
+}
+
+func servedSerial(t *testing.T, c *http.Client, url string) int64 {
+	t.Helper()
+	resp, err := c.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "server-ca")
+	certPEM, keyPEM := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeFile(t, certFile, certPEM, time.Now())
+	writeFile(t, keyFile, keyPEM, time.Now())
+
+	_, err := New(certFile, "", "")
+	assert.EqualError(t, err, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+
+	_, err = New(certFile, filepath.Join(dir, "missing.key"), "")
+	assert.ErrorContains(t, err, "could not read TLS file")
+
+	_, err = New(certFile, certFile, "")
+	assert.ErrorContains(t, err, "could not load TLS certificate")
+
+	_, err = New(certFile, keyFile, keyFile)
+	assert.ErrorContains(t, err, "no certificates found in client CA file")
+
+	r, err := New(certFile, keyFile, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), r.Certificate().Leaf.SerialNumber.Int64())
+}
+
+func TestReloader_Watch(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "server-ca")
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	certPEM, keyPEM := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	start := time.Now().Add(-time.Minute)
+	writeFile(t, certFile, certPEM, start)
+	writeFile(t, keyFile, keyPEM, start)
+
+	r, err := New(certFile, keyFile, "")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	url := serve(t, r)
+	c := client(ca, nil)
+	assert.Equal(t, int64(2), servedSerial(t, c, url))
+
+	t.Run("Invalid Files Keep The Previous Certificate", func(t *testing.T) {
+		writeFile(t, certFile, []byte("not a certificate"), start.Add(time.Second))
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, int64(2), servedSerial(t, c, url))
+	})
+
+	t.Run("New Connections Get The New Certificate", func(t *testing.T) {
+		certPEM, keyPEM := ca.issue(t, 3, x509.ExtKeyUsageServerAuth)
+		writeFile(t, keyFile, keyPEM, start.Add(2*time.Second))
+		writeFile(t, certFile, certPEM, start.Add(2*time.Second))
+		assert.Eventually(t, func() bool { return servedSerial(t, c, url) == 3 }, 2*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestReloader_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCA, clientCA, otherCA := newTestCA(t, "server-ca"), newTestCA(t, "client-ca"), newTestCA(t, "other-ca")
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	certPEM, keyPEM := serverCA.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM, time.Now())
+	writeFile(t, keyFile, keyPEM, time.Now())
+	writeFile(t, caFile, clientCA.pem, time.Now())
+
+	r, err := New(certFile, keyFile, caFile)
+	require.NoError(t, err)
+	url := serve(t, r)
+
+	clientCert := func(ca *testCA) *tls.Certificate {
+		certPEM, keyPEM := ca.issue(t, 10, x509.ExtKeyUsageClientAuth)
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		require.NoError(t, err)
+		return &cert
+	}
+
+	t.Run("Trusted Client", func(t *testing.T) {
+		c := client(serverCA, clientCert(clientCA))
+		c.Transport.(*http.Transport).ForceAttemptHTTP2 = true
+		resp, err := c.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "HTTP/2.0", resp.Proto, "HTTP/2 still negotiated")
+		assert.NotEmpty(t, resp.Header.Get("X-Client-Cert"))
+	})
+
+	t.Run("Missing Client Certificate Is Left To The Routes", func(t *testing.T) {
+		resp, err := client(serverCA, nil).Get(url)
+		require.NoError(t, err, "probes connect without a certificate")
+		defer resp.Body.Close()
+		assert.Empty(t, resp.Header.Get("X-Client-Cert"))
+	})
+
+	t.Run("Untrusted Client Certificate", func(t *testing.T) {
+		_, err := client(serverCA, clientCert(otherCA)).Get(url)
+		assert.Error(t, err)
+	})
+
+	t.Run("Client CAs Reload", func(t *testing.T) {
+		writeFile(t, caFile, otherCA.pem, time.Now().Add(time.Minute))
+		require.NoError(t, r.Reload())
+		_, err := client(serverCA, clientCert(otherCA)).Get(url)
+		assert.NoError(t, err)
+		_, err = client(serverCA, clientCert(clientCA)).Get(url)
+		assert.Error(t, err)
+	})
+}
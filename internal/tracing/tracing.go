@@ -0,0 +1,203 @@
+// Package tracing gera os spans do OpenTelemetry: um por requisição HTTP, um
+// por consulta de CEP ou clima e um por chamada HTTP aos provedores. O contexto
+// W3C (traceparent) recebido é continuado e repassado aos provedores.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/MchlAlex/fc-lab02/internal/entity"
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifica os spans criados por esta aplicação.
+const instrumentationName = "github.com/MchlAlex/fc-lab02"
+
+// Atributos próprios da aplicação; os de HTTP seguem as convenções semânticas.
+const (
+	AttrCEP  = attribute.Key("weather.cep")
+	AttrCity = attribute.Key("weather.city")
+)
+
+// propagator lê e escreve os headers traceparent, tracestate e baggage.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// New cria o TracerProvider da aplicação. Com endpoint vazio nenhum span é
+// exportado, mas os IDs continuam sendo gerados e propagados. endpoint é a URL
+// base do coletor OTLP/HTTP (ex: http://otel-collector:4318); o caminho
+// /v1/traces é acrescentado quando ausente.
+func New(ctx context.Context, endpoint, serviceName string) (*sdktrace.TracerProvider, error) {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	}
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid OTLP endpoint %q: expected an URL such as http://localhost:4318", endpoint)
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/v1/traces"
+		}
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(u.String()))
+		if err != nil {
+			return nil, fmt.Errorf("could not create OTLP exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	return sdktrace.NewTracerProvider(opts...), nil
+}
+
+// Or retorna tp ou, se for nil, o TracerProvider global (no-op por padrão).
+func Or(tp trace.TracerProvider) trace.TracerProvider {
+	if tp == nil {
+		return otel.GetTracerProvider()
+	}
+	return tp
+}
+
+// Shutdown exporta os spans pendentes e encerra tp, se ele tiver um exportador.
+func Shutdown(ctx context.Context, tp trace.TracerProvider) error {
+	if s, ok := tp.(interface{ Shutdown(context.Context) error }); ok {
+		return s.Shutdown(ctx)
+	}
+	return nil
+}
+
+// Middleware abre o span de cada requisição, filho do traceparent recebido. O
+// nome do span usa o padrão da rota do chi (ex: GET /v1/weather/{cep}), só
+// conhecido depois do roteamento.
+func Middleware(tp trace.TracerProvider) func(http.Handler) http.Handler {
+	tracer := Or(tp).Tracer(instrumentationName)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			))
+			defer span.End()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetName(r.Method + " " + rctx.RoutePattern())
+				span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
+
+// Transport decora um http.RoundTripper com um span de cliente por chamada e
+// repassa o contexto do trace ao provedor. Só o host e o caminho da URL são
+// registrados: a query da WeatherAPI leva a chave de API.
+func Transport(tp trace.TracerProvider, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{tracer: Or(tp).Tracer(instrumentationName), base: base}
+}
+
+type transport struct {
+	tracer trace.Tracer
+	base   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(req.Context(), req.Method+" "+req.URL.Host, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.ServerAddress(req.URL.Hostname()),
+		semconv.URLPath(req.URL.Path),
+	))
+	defer span.End()
+
+	req = req.Clone(ctx)
+	propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		// Transportes podem devolver um *url.Error com a URL completa
+		recorded := err
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			recorded = urlErr.Err
+		}
+		span.RecordError(recorded)
+		span.SetStatus(codes.Error, recorded.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, strconv.Itoa(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// TraceLocation decora um LocationFinder com um span por consulta, com o CEP e
+// a cidade encontrada. Os caches abaixo dele marcam o atributo cache.hit.
+func TraceLocation(tp trace.TracerProvider, next service.LocationFinder) service.LocationFinder {
+	return &tracedLocationFinder{tracer: Or(tp).Tracer(instrumentationName), next: next}
+}
+
+// TraceWeather decora um WeatherFinder com um span por consulta, com a cidade.
+func TraceWeather(tp trace.TracerProvider, next service.WeatherFinder) service.WeatherFinder {
+	return &tracedWeatherFinder{tracer: Or(tp).Tracer(instrumentationName), next: next}
+}
+
+type tracedLocationFinder struct {
+	tracer trace.Tracer
+	next   service.LocationFinder
+}
+
+func (f *tracedLocationFinder) GetLocationByCEP(ctx context.Context, cep string) (string, error) {
+	ctx, span := f.tracer.Start(ctx, "GetLocationByCEP", trace.WithAttributes(AttrCEP.String(cep)))
+	defer span.End()
+	city, err := f.next.GetLocationByCEP(ctx, cep)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return "", err
+	}
+	span.SetAttributes(AttrCity.String(city))
+	return city, nil
+}
+
+type tracedWeatherFinder struct {
+	tracer trace.Tracer
+	next   service.WeatherFinder
+}
+
+func (f *tracedWeatherFinder) GetWeatherByCity(ctx context.Context, city string) (*entity.CurrentWeather, error) {
+	ctx, span := f.tracer.Start(ctx, "GetWeatherByCity", trace.WithAttributes(AttrCity.String(city)))
+	defer span.End()
+	weather, err := f.next.GetWeatherByCity(ctx, city)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return weather, err
+}
@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MchlAlex/fc-lab02/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const incomingTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func newTestProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+// spanNamed retorna o span exportado com o nome informado.
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("span %q not exported; got %d spans", name, len(spans))
+	return tracetest.SpanStub{}
+}
+
+func attrs(s tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value, len(s.Attributes))
+	for _, kv := range s.Attributes {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestTracing(t *testing.T) {
+	tp, exporter := newTestProvider()
+
+	// A ViaCEP falsa registra o traceparent recebido
+	var upstreamTraceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get("traceparent")
+		_, _ = w.Write([]byte(`{"localidade": "São Paulo"}`))
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: Transport(tp, nil)}
+	lookup := service.NewCachedLocationFinder(locationFunc(func(ctx context.Context, cep string) (string, error) {
+		req, _ := http.NewRequestWithContext(ctx, "GET", upstream.URL+"/ws/"+cep+"/json/?key=secret", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+		return "São Paulo", nil
+	}), time.Hour)
+	location := TraceLocation(tp, lookup)
+
+	r := chi.NewRouter()
+	r.Use(Middleware(tp))
+	r.Get("/v1/weather/{cep}", func(w http.ResponseWriter, r *http.Request) {
+		_, err := location.GetLocationByCEP(r.Context(), chi.URLParam(r, "cep"))
+		require.NoError(t, err)
+	})
+
+	t.Run("Request And Upstream Spans", func(t *testing.T) {
+		exporter.Reset()
+		req := httptest.NewRequest("GET", "/v1/weather/01001000", nil)
+		req.Header.Set("traceparent", incomingTraceparent)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 3)
+		server := spanNamed(t, spans, "GET /v1/weather/{cep}")
+		lookupSpan := spanNamed(t, spans, "GetLocationByCEP")
+		clientSpan := spanNamed(t, spans, "GET "+upstream.Listener.Addr().String())
+
+		// O span da requisição continua o trace recebido
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+		assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+		assert.Equal(t, "/v1/weather/{cep}", attrs(server)["http.route"].AsString())
+		assert.Equal(t, int64(http.StatusOK), attrs(server)["http.response.status_code"].AsInt64())
+
+		assert.Equal(t, server.SpanContext.SpanID(), lookupSpan.Parent.SpanID())
+		assert.Equal(t, "01001000", attrs(lookupSpan)[AttrCEP].AsString())
+		assert.Equal(t, "São Paulo", attrs(lookupSpan)[AttrCity].AsString())
+		assert.False(t, attrs(lookupSpan)["cache.hit"].AsBool())
+
+		assert.Equal(t, lookupSpan.SpanContext.SpanID(), clientSpan.Parent.SpanID())
+		assert.Equal(t, trace.SpanKindClient, clientSpan.SpanKind)
+		assert.Equal(t, int64(http.StatusOK), attrs(clientSpan)["http.response.status_code"].AsInt64())
+		assert.Equal(t, "/ws/01001000/json/", attrs(clientSpan)["url.path"].AsString(), "query with the API key is not recorded")
+		for _, kv := range clientSpan.Attributes {
+			assert.NotContains(t, kv.Value.Emit(), "secret")
+		}
+
+		// O provedor recebe o contexto do span de cliente
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+clientSpan.SpanContext.SpanID().String()+"-01", upstreamTraceparent)
+	})
+
+	t.Run("Cache Hit", func(t *testing.T) {
+		exporter.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/weather/01001000", nil))
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2, "no upstream call")
+		lookupSpan := spanNamed(t, spans, "GetLocationByCEP")
+		assert.True(t, attrs(lookupSpan)["cache.hit"].AsBool())
+		assert.False(t, spanNamed(t, spans, "GET /v1/weather/{cep}").Parent.IsValid(), "new trace without traceparent")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		exporter.Reset()
+		failing := TraceLocation(tp, locationFunc(func(context.Context, string) (string, error) {
+			return "", service.ErrCEPNotFound
+		}))
+		_, err := failing.GetLocationByCEP(context.Background(), "99999999")
+		assert.ErrorIs(t, err, service.ErrCEPNotFound)
+
+		span := spanNamed(t, exporter.GetSpans(), "GetLocationByCEP")
+		assert.Equal(t, codes.Error, span.Status.Code)
+		assert.Len(t, span.Events, 1, "error recorded as event")
+	})
+}
+
+func TestNew(t *testing.T) {
+	tp, err := New(context.Background(), "", "weather-api")
+	require.NoError(t, err)
+	assert.NoError(t, Shutdown(context.Background(), tp))
+
+	_, err = New(context.Background(), "localhost:4318", "weather-api")
+	assert.ErrorContains(t, err, "invalid OTLP endpoint")
+
+	tp, err = New(context.Background(), "http://localhost:4318", "weather-api")
+	require.NoError(t, err)
+	assert.NoError(t, Shutdown(context.Background(), tp), "nothing to export")
+}
+
+type locationFunc func(ctx context.Context, cep string) (string, error)
+
+func (f locationFunc) GetLocationByCEP(ctx context.Context, cep string) (string, error) {
+	return f(ctx, cep)
+}